@@ -0,0 +1,146 @@
+package gonotes
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Hooks used by the atomic write helpers. Tests replace them to simulate
+// full disks and failing renames.
+var (
+	writeFileData = func(f *os.File, data []byte) error {
+		_, err := f.Write(data)
+		return err
+	}
+	syncFile   = func(f *os.File) error { return f.Sync() }
+	renameFile = os.Rename
+	linkFile   = os.Link
+)
+
+// writeFileAtomic replaces path with data so that readers, and the file
+// after a crash, see either the old or the new content but never a partial
+// write. The data is written to a temporary file in the same directory,
+// synced, and renamed over path. An existing file keeps its mode; a new
+// file gets perm.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	if info, err := os.Stat(path); err == nil {
+		perm = info.Mode().Perm()
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	tmp, err := writeTempFile(path, data, perm)
+	if err != nil {
+		return err
+	}
+	if err := renameFile(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// writeNewFile writes data to path like writeFileAtomic, but fails with an
+// error wrapping os.ErrExist instead of replacing an existing file. The
+// synced temporary file is hard-linked to path, which fails if path
+// exists, so path never appears without its full content. On filesystems
+// without hard links, path is created exclusively and written in place.
+func writeNewFile(path string, data []byte, perm os.FileMode) error {
+	tmp, err := writeTempFile(path, data, perm)
+	if err != nil {
+		return err
+	}
+	err = linkFile(tmp, path)
+	_ = os.Remove(tmp)
+	if err != nil && !errors.Is(err, os.ErrExist) {
+		err = writeExclusive(path, data, perm)
+	}
+	if err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// writeExclusive creates path, failing if it exists, and writes and syncs
+// data to it. On error path is removed again.
+func writeExclusive(path string, data []byte, perm os.FileMode) (err error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			_ = os.Remove(path)
+		}
+	}()
+
+	if err := writeFileData(f, data); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := syncFile(f); err != nil {
+		return fmt.Errorf("sync %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+// renameNoReplace renames from to to like os.Rename, but fails with an
+// error wrapping os.ErrExist instead of replacing an existing file. On
+// filesystems without hard links the check and the rename are separate
+// steps.
+func renameNoReplace(from, to string) error {
+	err := linkFile(from, to)
+	if err != nil && !errors.Is(err, os.ErrExist) {
+		if _, err := os.Lstat(to); err == nil {
+			return &os.LinkError{Op: "rename", Old: from, New: to, Err: os.ErrExist}
+		}
+		return renameFile(from, to)
+	}
+	if err != nil {
+		return err
+	}
+	return os.Remove(from)
+}
+
+// writeTempFile writes data to a new, synced temporary file next to path
+// and returns its name. On error nothing is left behind.
+func writeTempFile(path string, data []byte, perm os.FileMode) (name string, err error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			_ = os.Remove(f.Name())
+		}
+	}()
+
+	if err := writeFileData(f, data); err != nil {
+		return "", fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := f.Chmod(perm); err != nil {
+		return "", err
+	}
+	if err := syncFile(f); err != nil {
+		return "", fmt.Errorf("sync %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// syncDir flushes directory metadata so a completed rename survives a
+// crash. Platforms that cannot sync directories are ignored.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	_ = d.Sync()
+	return nil
+}
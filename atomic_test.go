@@ -0,0 +1,254 @@
+package gonotes
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// failHook replaces *hook with failing for the duration of the test.
+func failHook[T any](t *testing.T, hook *T, failing T) {
+	t.Helper()
+	orig := *hook
+	*hook = failing
+	t.Cleanup(func() { *hook = orig })
+}
+
+func assertDirEntries(t *testing.T, dir string, want ...string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Name())
+	}
+	if len(got) != len(want) {
+		t.Fatalf("dir entries = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("dir entries = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestWriteFileAtomicPreservesMode(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "note.md")
+	if err := os.WriteFile(path, []byte("old"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeFileAtomic(path, []byte("new"), 0o644); err != nil {
+		t.Fatalf("writeFileAtomic() err = %q", err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "new" {
+		t.Errorf("content = %q, want %q", got, "new")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v, want %v", info.Mode().Perm(), os.FileMode(0o600))
+	}
+	assertDirEntries(t, dir, "note.md")
+}
+
+func TestWriteFileAtomicFailures(t *testing.T) {
+	errDiskFull := syscall.ENOSPC
+
+	tests := []struct {
+		name   string
+		inject func(t *testing.T)
+	}{
+		{
+			name: "write fails",
+			inject: func(t *testing.T) {
+				failHook(t, &writeFileData, func(f *os.File, data []byte) error {
+					// Leave a partial write behind, as a full disk would.
+					_, _ = f.Write(data[:len(data)/2])
+					return errDiskFull
+				})
+			},
+		},
+		{
+			name: "sync fails",
+			inject: func(t *testing.T) {
+				failHook(t, &syncFile, func(*os.File) error { return errDiskFull })
+			},
+		},
+		{
+			name: "rename fails",
+			inject: func(t *testing.T) {
+				failHook(t, &renameFile, func(string, string) error { return errDiskFull })
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "note.md")
+			if err := os.WriteFile(path, []byte("original content"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			tt.inject(t)
+
+			err := writeFileAtomic(path, []byte("replacement content"), 0o644)
+			if !errors.Is(err, errDiskFull) {
+				t.Fatalf("writeFileAtomic() err = %v, want %v", err, errDiskFull)
+			}
+
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != "original content" {
+				t.Errorf("content = %q, want original content", got)
+			}
+			assertDirEntries(t, dir, "note.md")
+		})
+	}
+}
+
+func TestWriteNewFileFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		inject func(t *testing.T)
+	}{
+		{
+			name: "sync fails",
+			inject: func(t *testing.T) {
+				failHook(t, &syncFile, func(*os.File) error { return syscall.ENOSPC })
+			},
+		},
+		{
+			name: "write without hard links fails",
+			inject: func(t *testing.T) {
+				failHook(t, &linkFile, func(string, string) error { return syscall.EPERM })
+				calls := 0
+				failHook(t, &syncFile, func(f *os.File) error {
+					// The temporary file syncs; the file written in its
+					// place does not.
+					if calls++; calls > 1 {
+						return syscall.ENOSPC
+					}
+					return f.Sync()
+				})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "20260328-1-note.md")
+
+			tt.inject(t)
+
+			if err := writeNewFile(path, []byte("content"), 0o644); !errors.Is(err, syscall.ENOSPC) {
+				t.Fatalf("writeNewFile() err = %v, want %v", err, syscall.ENOSPC)
+			}
+			assertDirEntries(t, dir)
+		})
+	}
+}
+
+func TestWriteNewFileWithoutHardLinks(t *testing.T) {
+	failHook(t, &linkFile, func(string, string) error { return syscall.EPERM })
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "20260328-1-note.md")
+	if err := writeNewFile(path, []byte("content"), 0o600); err != nil {
+		t.Fatalf("writeNewFile() err = %q", err)
+	}
+	if got, err := os.ReadFile(path); err != nil || string(got) != "content" {
+		t.Errorf("content = %q, %v; want %q", got, err, "content")
+	}
+	if err := writeNewFile(path, []byte("new"), 0o600); !errors.Is(err, os.ErrExist) {
+		t.Errorf("writeNewFile() on existing file err = %v, want os.ErrExist", err)
+	}
+
+	to := filepath.Join(dir, "20260328-2-note.md")
+	if err := renameNoReplace(path, to); err != nil {
+		t.Fatalf("renameNoReplace() err = %q", err)
+	}
+	writeTestNote(t, dir, "other.md", "other")
+	if err := renameNoReplace(filepath.Join(dir, "other.md"), to); !errors.Is(err, os.ErrExist) {
+		t.Errorf("renameNoReplace() onto existing file err = %v, want os.ErrExist", err)
+	}
+	assertDirEntries(t, dir, "20260328-2-note.md", "other.md")
+}
+
+func TestWriteNewFileExisting(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "20260328-1-note.md")
+	if err := os.WriteFile(path, []byte("original"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeNewFile(path, []byte("new"), 0o600); !errors.Is(err, os.ErrExist) {
+		t.Fatalf("writeNewFile() err = %v, want os.ErrExist", err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "original" {
+		t.Errorf("content = %q, want original", got)
+	}
+	assertDirEntries(t, dir, "20260328-1-note.md")
+
+	// A new file gets perm and the full content.
+	path = filepath.Join(dir, "20260328-2-note.md")
+	if err := writeNewFile(path, []byte("new"), 0o600); err != nil {
+		t.Fatalf("writeNewFile() err = %q", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 || info.Size() != 3 {
+		t.Errorf("new file mode %v size %d, want %v size 3", info.Mode().Perm(), info.Size(), os.FileMode(0o600))
+	}
+	assertDirEntries(t, dir, "20260328-1-note.md", "20260328-2-note.md")
+}
+
+func TestExecuteReverseRebuildWriteFailureKeepsNote(t *testing.T) {
+	baseDir := t.TempDir()
+	idDir := filepath.Join(baseDir, "notes", "by", "id")
+	content := "---\ntitle: Hello\ntags: old\n---\n\nBody."
+	writeTestNote(t, idDir, "20260328-1-hello.md", content)
+
+	failHook(t, &writeFileData, func(*os.File, []byte) error { return syscall.ENOSPC })
+
+	changes := []TagChange{{
+		ID:      "20260328-1",
+		Path:    filepath.Join(idDir, "20260328-1-hello.md"),
+		OldTags: []string{"old"},
+		NewTags: []string{"new"},
+	}}
+	if err := ExecuteReverseRebuild(baseDir, nil, changes); err == nil {
+		t.Fatal("ExecuteReverseRebuild() err = <nil>, want error")
+	}
+
+	got, err := os.ReadFile(filepath.Join(idDir, "20260328-1-hello.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != content {
+		t.Errorf("note content = %q, want unchanged %q", got, content)
+	}
+	assertDirEntries(t, idDir, "20260328-1-hello.md")
+}
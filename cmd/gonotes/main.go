@@ -2,43 +2,97 @@ package main
 
 import (
 	"bufio"
+	"context"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"os/exec"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/marcelbeumer/gonotes"
 )
 
-const usage = `Usage: gonotes <command> [flags]
+const usage = `Usage: gonotes [-C <dir>] <command> [flags]
 
 Commands:
   new        Create a new note
   folder     Create a new folder for file storage
   rebuild    Scan notes, report issues, rename files, rebuild symlinks
              Use -r for reverse rebuild: sync tags from filesystem into notes
+  search     Full-text search over titles, tags, frontmatter and bodies
+  backlinks  List notes that link to a given note
+  list       List notes matching a query
+  export     Export the vault as a static HTML site
+  graph      Print the note link graph as DOT, GraphML or JSON
+  import     Import notes from another tool (obsidian, enex)
+  edit       Open a note in $EDITOR and update its filename and symlinks
+  watch      Keep filenames and symlinks in sync as notes change
+
+The vault root is found by walking up from the current directory (or the
+directory given with -C or $GONOTES_DIR) to the first directory holding
+.gonotes.yaml, .gonotes/ or notes/by/id/. Without one, the starting
+directory is used. Settings are read from .gonotes.yaml in the vault root
+when present.
+
+Global flags:
+  -C <dir>   run as if gonotes was started in <dir>
 `
 
+// startDir is where vault root discovery begins: the -C flag, else
+// $GONOTES_DIR, else the working directory.
+var startDir string
+
 func main() {
-	if len(os.Args) < 2 {
+	global := flag.NewFlagSet("gonotes", flag.ContinueOnError)
+	global.StringVar(&startDir, "C", os.Getenv("GONOTES_DIR"), "run as if started in `dir`")
+	global.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	if err := global.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		}
+		os.Exit(1)
+	}
+
+	args := global.Args()
+	if len(args) < 1 {
 		fmt.Fprint(os.Stderr, usage)
 		os.Exit(1)
 	}
 
 	var err error
-	switch os.Args[1] {
+	switch args[0] {
 	case "new":
-		err = runNew(os.Args[2:])
+		err = runNew(args[1:])
 	case "folder":
-		err = runFolder(os.Args[2:])
+		err = runFolder(args[1:])
 	case "rebuild":
-		err = runRebuild(os.Args[2:])
+		err = runRebuild(args[1:])
+	case "search":
+		err = runSearch(args[1:])
+	case "backlinks":
+		err = runBacklinks(args[1:])
+	case "list":
+		err = runList(args[1:])
+	case "export":
+		err = runExport(args[1:])
+	case "graph":
+		err = runGraph(args[1:])
+	case "import":
+		err = runImport(args[1:])
+	case "edit":
+		err = runEdit(args[1:])
+	case "watch":
+		err = runWatch(args[1:])
 	default:
-		fmt.Fprintf(os.Stderr, "unknown command: %s\n", os.Args[1])
+		fmt.Fprintf(os.Stderr, "unknown command: %s\n", args[0])
 		fmt.Fprint(os.Stderr, usage)
 		os.Exit(1)
 	}
@@ -139,18 +193,18 @@ Flags:
 		opts.Title = *title
 	}
 
-	baseDir, err := os.Getwd()
+	baseDir, cfg, err := openVault()
 	if err != nil {
-		return fmt.Errorf("get working directory: %w", err)
+		return err
 	}
 
-	note, plan, err := gonotes.CreateNote(baseDir, r, opts, *dryRun)
+	note, plan, err := gonotes.CreateNote(baseDir, cfg, r, opts, *dryRun)
 	if err != nil {
 		return err
 	}
 
 	filename := gonotes.NoteFilename(note.ID, note.Slug)
-	writePath := filepath.Join("notes", "by", "id", filename)
+	writePath := filepath.Join(cfg.IDDir, filename)
 
 	if *dryRun {
 		if _, err := fmt.Fprint(os.Stdout, note.Markdown()); err != nil {
@@ -184,12 +238,12 @@ Flags:
 		return err
 	}
 
-	baseDir, err := os.Getwd()
+	baseDir, cfg, err := openVault()
 	if err != nil {
-		return fmt.Errorf("get working directory: %w", err)
+		return err
 	}
 
-	path, err := gonotes.CreateFolder(baseDir, *title, time.Now)
+	path, err := gonotes.CreateFolder(baseDir, cfg, *title, time.Now)
 	if err != nil {
 		return err
 	}
@@ -202,16 +256,27 @@ func runRebuild(args []string) error {
 	fs := flag.NewFlagSet("rebuild", flag.ContinueOnError)
 	reverse := fs.Bool("r", false, "reverse rebuild: sync tags from filesystem into note files")
 	confirm := fs.Bool("y", false, "skip confirmation prompts")
+	jsonOut := fs.Bool("json", false, "print the report as JSON on stdout; changes are only applied with -y")
+	idLinks := fs.Bool("id-links", false, "rewrite links that name a note by title or alias to ID links")
 
 	fs.Usage = func() {
-		fmt.Fprintf(os.Stderr, `Usage: gonotes rebuild [-y] [-r]
+		fmt.Fprintf(os.Stderr, `Usage: gonotes rebuild [-y] [-r] [-json] [-id-links]
 
 Scan notes/by/id/, report broken links and filename mismatches,
-rename files, and rebuild symlink structures.
+rename files, and rebuild symlink structures. Renames and link rewrites
+are recorded in .gonotes/journal first; if a rebuild is interrupted, the
+next run offers to resume or roll it back (-y resumes).
+
+Links like [[My Note Title]] that name a note by title or alias are
+reported, and rewritten to [[id|My Note Title]] when confirmed. With -y
+or -json they are only rewritten with -id-links.
 
 With -r, scan tags from the symlink structure and update
 note frontmatter to match, replacing the normal rebuild flow.
 
+With -json, nothing is asked: the report is printed as JSON on stdout
+and changes are applied only when -y is also given.
+
 Flags:
 `)
 		fs.PrintDefaults()
@@ -221,50 +286,200 @@ Flags:
 		return err
 	}
 
-	baseDir, err := os.Getwd()
+	baseDir, cfg, err := openVault()
 	if err != nil {
-		return fmt.Errorf("get working directory: %w", err)
+		return err
 	}
 
+	// rebuild.assume_yes only answers prompts; -json never prompts and
+	// applies changes with an explicit -y alone, so it stays read-only for
+	// scripts.
+	yes := *confirm || cfg.Rebuild.AssumeYes
+
 	if *reverse {
-		return runReverseRebuild(baseDir, *confirm)
+		if *jsonOut {
+			return runReverseRebuildJSON(baseDir, cfg, *confirm)
+		}
+		return runReverseRebuild(baseDir, cfg, yes)
+	}
+	if *jsonOut {
+		return runRebuildJSON(baseDir, cfg, *confirm, *idLinks)
 	}
 
-	idDir := filepath.Join(baseDir, "notes", "by", "id")
+	if err := resolveJournal(baseDir, cfg, yes); err != nil {
+		return err
+	}
 
-	report, err := gonotes.ScanNotes(baseDir)
+	report, err := gonotes.ScanNotes(baseDir, cfg)
 	if err != nil {
 		return err
 	}
 
 	fmt.Fprint(os.Stderr, report.String())
 
+	var rewrites []gonotes.LinkRewrite
+	if len(report.LinkRewrites) > 0 {
+		if !yes && !promptYN("Rewrite stale links?") {
+			fmt.Fprintln(os.Stderr, "Skipping link rewrites.")
+		} else {
+			rewrites = report.LinkRewrites
+		}
+	}
+
+	if len(report.TitleLinks) > 0 {
+		switch {
+		case *idLinks:
+			rewrites = append(rewrites, report.TitleLinks...)
+		case yes:
+			// Title links resolve as they are; -y alone keeps them.
+		case promptYN("Rewrite title links to ID links?"):
+			rewrites = append(rewrites, report.TitleLinks...)
+		default:
+			fmt.Fprintln(os.Stderr, "Keeping title links.")
+		}
+	}
+
+	var renames []gonotes.Rename
 	if len(report.Renames) > 0 {
-		if !*confirm && !promptYN("Perform renames?") {
+		if !yes && !promptYN("Perform renames?") {
 			fmt.Fprintln(os.Stderr, "Skipping renames.")
 		} else {
-			if err := gonotes.ExecuteRenames(idDir, report.Renames); err != nil {
-				return err
-			}
-			fmt.Fprintf(os.Stderr, "Renamed %d file(s).\n", len(report.Renames))
+			renames = report.Renames
 		}
 	}
 
-	if !*confirm && !promptYN("Rebuild symlinks?") {
+	touched, err := applyRebuild(baseDir, cfg, rewrites, renames)
+	if err != nil {
+		return err
+	}
+	if len(touched) > 0 {
+		fmt.Fprintf(os.Stderr, "Rewrote links in %d note(s):\n", len(touched))
+		for _, name := range touched {
+			fmt.Fprintf(os.Stderr, "  %s\n", name)
+		}
+	}
+	if len(renames) > 0 {
+		fmt.Fprintf(os.Stderr, "Renamed %d file(s).\n", len(renames))
+	}
+
+	if !yes && !promptYN("Rebuild symlinks?") {
 		fmt.Fprintln(os.Stderr, "Skipping symlink rebuild.")
 		return nil
 	}
 
-	if err := gonotes.RebuildSymlinks(baseDir); err != nil {
+	changes, err := gonotes.SyncSymlinks(baseDir, cfg)
+	if err != nil {
 		return err
 	}
-	fmt.Fprintln(os.Stderr, "Symlinks rebuilt.")
+	fmt.Fprintf(os.Stderr, "Symlinks rebuilt (%d added, %d retargeted, %d removed).\n",
+		len(changes.Added), len(changes.Retargeted), len(changes.Removed))
 
 	return nil
 }
 
-func runReverseRebuild(baseDir string, confirm bool) error {
-	report, err := gonotes.ReverseRebuild(baseDir)
+// applyRebuild performs the link rewrites and renames through the rebuild
+// journal, rolling back on failure, and returns the rewritten files.
+func applyRebuild(baseDir string, cfg *gonotes.Config, rewrites []gonotes.LinkRewrite, renames []gonotes.Rename) ([]string, error) {
+	if len(rewrites) == 0 && len(renames) == 0 {
+		return nil, nil
+	}
+
+	journal, err := gonotes.BeginRebuild(baseDir, cfg, rewrites, renames)
+	if err != nil {
+		return nil, err
+	}
+	if err := journal.Apply(); err != nil {
+		fmt.Fprintf(os.Stderr, "Rebuild failed, rolling back: %v\n", err)
+		if rbErr := journal.Rollback(); rbErr != nil {
+			return nil, fmt.Errorf("%w (rollback failed: %v; journal kept at %s)", err, rbErr, gonotes.JournalPath(baseDir))
+		}
+		return nil, err
+	}
+	return journal.Rewritten(), nil
+}
+
+// rebuildJSON is the output of rebuild -json. Rewritten and Symlinks are
+// only set when the changes were applied.
+type rebuildJSON struct {
+	Report    *gonotes.RebuildReport  `json:"report"`
+	Applied   bool                    `json:"applied"`
+	Rewritten []string                `json:"rewritten"`
+	Symlinks  *gonotes.SymlinkChanges `json:"symlinks"`
+}
+
+// runRebuildJSON is rebuild -json: it prints the report as JSON and, with
+// apply, performs every change without asking. Title links are only
+// rewritten with idLinks.
+func runRebuildJSON(baseDir string, cfg *gonotes.Config, apply, idLinks bool) error {
+	if apply {
+		if err := resolveJournal(baseDir, cfg, true); err != nil {
+			return err
+		}
+	} else if journal, err := gonotes.OpenJournal(baseDir, cfg); err != nil {
+		return err
+	} else if journal != nil {
+		return fmt.Errorf("unfinished rebuild journal at %s (rerun with -y to resume it)", gonotes.JournalPath(baseDir))
+	}
+
+	report, err := gonotes.ScanNotes(baseDir, cfg)
+	if err != nil {
+		return err
+	}
+
+	out := rebuildJSON{Report: report, Rewritten: []string{}}
+	if apply {
+		rewrites := report.LinkRewrites
+		if idLinks {
+			rewrites = append(rewrites, report.TitleLinks...)
+		}
+		touched, err := applyRebuild(baseDir, cfg, rewrites, report.Renames)
+		if err != nil {
+			return err
+		}
+		if touched != nil {
+			out.Rewritten = touched
+		}
+		out.Symlinks, err = gonotes.SyncSymlinks(baseDir, cfg)
+		if err != nil {
+			return err
+		}
+		out.Applied = true
+	}
+
+	return writeJSON(os.Stdout, out)
+}
+
+// resolveJournal reports an unfinished rebuild left behind by a crash or
+// failure and resumes or rolls it back before a new rebuild starts.
+func resolveJournal(baseDir string, cfg *gonotes.Config, confirm bool) error {
+	journal, err := gonotes.OpenJournal(baseDir, cfg)
+	if err != nil || journal == nil {
+		return err
+	}
+
+	fmt.Fprint(os.Stderr, journal.String())
+
+	if confirm || promptYN("Resume unfinished rebuild?") {
+		if err := journal.Apply(); err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "Resumed unfinished rebuild.")
+		return nil
+	}
+
+	if promptYN("Roll back unfinished rebuild?") {
+		if err := journal.Rollback(); err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "Rolled back unfinished rebuild.")
+		return nil
+	}
+
+	return fmt.Errorf("unfinished rebuild journal at %s", gonotes.JournalPath(baseDir))
+}
+
+func runReverseRebuild(baseDir string, cfg *gonotes.Config, confirm bool) error {
+	report, err := gonotes.ReverseRebuild(baseDir, cfg)
 	if err != nil {
 		return err
 	}
@@ -280,7 +495,7 @@ func runReverseRebuild(baseDir string, confirm bool) error {
 		return nil
 	}
 
-	if err := gonotes.ExecuteReverseRebuild(baseDir, report.Changes); err != nil {
+	if err := gonotes.ExecuteReverseRebuild(baseDir, cfg, report.Changes); err != nil {
 		return err
 	}
 	fmt.Fprintf(os.Stderr, "Updated %d note(s).\n", len(report.Changes))
@@ -288,6 +503,705 @@ func runReverseRebuild(baseDir string, confirm bool) error {
 	return nil
 }
 
+// reverseRebuildJSON is the output of rebuild -r -json.
+type reverseRebuildJSON struct {
+	Report  *gonotes.ReverseRebuildReport `json:"report"`
+	Applied bool                          `json:"applied"`
+}
+
+// runReverseRebuildJSON is rebuild -r -json: it prints the report as JSON
+// and, with apply, updates the notes without asking.
+func runReverseRebuildJSON(baseDir string, cfg *gonotes.Config, apply bool) error {
+	report, err := gonotes.ReverseRebuild(baseDir, cfg)
+	if err != nil {
+		return err
+	}
+
+	out := reverseRebuildJSON{Report: report}
+	if apply && len(report.Changes) > 0 {
+		if err := gonotes.ExecuteReverseRebuild(baseDir, cfg, report.Changes); err != nil {
+			return err
+		}
+	}
+	out.Applied = apply
+
+	return writeJSON(os.Stdout, out)
+}
+
+func runSearch(args []string) error {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	limit := fs.Int("l", 10, "maximum number of results (0 for all)")
+	jsonOut := fs.Bool("json", false, "print results as JSON")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: gonotes search [flags] <query>
+
+Search note titles, tags, frontmatter values and bodies, ranked by
+relevance. The index is kept in .gonotes/index and updated
+incrementally from file modification times.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	query := strings.Join(fs.Args(), " ")
+	if strings.TrimSpace(query) == "" {
+		fs.Usage()
+		return fmt.Errorf("missing search query")
+	}
+
+	baseDir, cfg, err := openVault()
+	if err != nil {
+		return err
+	}
+
+	hlStart, hlEnd := "", ""
+	if isTerminal(os.Stdout) && !*jsonOut {
+		hlStart, hlEnd = "\x1b[1m", "\x1b[0m"
+	}
+
+	results, upd, err := gonotes.SearchNotes(baseDir, cfg, query, *limit, hlStart, hlEnd)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range upd.Errors {
+		fmt.Fprintf(os.Stderr, "warning: %s: %s\n", e.Filename, e.Message)
+	}
+
+	if *jsonOut {
+		if results == nil {
+			// Encode no matches as [] rather than null.
+			results = []gonotes.SearchResult{}
+		}
+		return writeJSON(os.Stdout, results)
+	}
+
+	for _, r := range results {
+		fmt.Fprintf(os.Stdout, "%s  %s\n", r.ID, r.Title)
+		if r.Snippet != "" {
+			fmt.Fprintf(os.Stdout, "    %s\n", r.Snippet)
+		}
+	}
+
+	return nil
+}
+
+func runBacklinks(args []string) error {
+	fs := flag.NewFlagSet("backlinks", flag.ContinueOnError)
+	jsonOut := fs.Bool("json", false, "print backlinks as JSON")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: gonotes backlinks [-json] <id>
+
+List every note that links to the note with the given ID, with the
+line of context where each link occurs.
+`)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly one note ID")
+	}
+
+	baseDir, cfg, err := openVault()
+	if err != nil {
+		return err
+	}
+
+	report, err := gonotes.ScanNotes(baseDir, cfg)
+	if err != nil {
+		return err
+	}
+
+	id, _ := gonotes.IDFromFilename(strings.TrimSuffix(fs.Arg(0), ".md") + ".md")
+	if *jsonOut {
+		out := []backlinkJSON{}
+		for _, ref := range report.Graph.Backlinks(id) {
+			out = append(out, backlinkJSON{LinkRef: ref, SourceTitle: report.Graph.Titles[ref.SourceID]})
+		}
+		return writeJSON(os.Stdout, out)
+	}
+	for _, ref := range report.Graph.Backlinks(id) {
+		fmt.Fprintf(os.Stdout, "%s  %s\n", ref.SourceID, report.Graph.Titles[ref.SourceID])
+		fmt.Fprintf(os.Stdout, "    %d: %s\n", ref.Line, ref.Context)
+	}
+
+	return nil
+}
+
+// backlinkJSON is one entry of backlinks -json.
+type backlinkJSON struct {
+	gonotes.LinkRef
+	SourceTitle string `json:"source_title"`
+}
+
+func runList(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	sortBy := fs.String("s", "id", "sort by: id, date or title")
+	reverse := fs.Bool("r", false, "reverse sort order")
+	limit := fs.Int("l", 0, "maximum number of notes (0 for all)")
+	format := fs.String("o", "table", "output format: table, paths or json")
+	jsonOut := fs.Bool("json", false, "shorthand for -o json")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: gonotes list [flags] [query]
+
+List notes matching a query. Terms are ANDed by default and can be
+combined with OR, negated with NOT or a leading -, and grouped with
+parentheses. Quote values containing spaces or parentheses.
+
+  tag:programming/go      note has the tag or a tag below it
+  date:>=2026-01-01       date comparison with =, >, >=, <, <=
+  title:~regex            regular expression match on any field
+  author:Alice            frontmatter field equals value
+  links-to:20260328-1     note links to the given ID
+  word                    title or body contains word
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *jsonOut {
+		*format = "json"
+	}
+
+	switch *format {
+	case "table", "paths", "json":
+	default:
+		return fmt.Errorf("unknown output format %q", *format)
+	}
+
+	query, err := gonotes.ParseQuery(strings.Join(fs.Args(), " "))
+	if err != nil {
+		return err
+	}
+
+	baseDir, cfg, err := openVault()
+	if err != nil {
+		return err
+	}
+
+	notes, errs, err := gonotes.ListNotes(baseDir, cfg, gonotes.ListOptions{
+		Query:   query,
+		SortBy:  *sortBy,
+		Reverse: *reverse,
+		Limit:   *limit,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, e := range errs {
+		fmt.Fprintf(os.Stderr, "warning: %s: %s\n", e.Filename, e.Message)
+	}
+
+	switch *format {
+	case "paths":
+		for _, n := range notes {
+			fmt.Fprintln(os.Stdout, n.Path)
+		}
+	case "json":
+		return writeNotesJSON(os.Stdout, notes)
+	default:
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, n := range notes {
+			date := ""
+			if !n.Date.IsZero() {
+				date = n.Date.Format("2006-01-02")
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", n.ID, date, n.Title, gonotes.FormatTags(n.Tags))
+		}
+		return tw.Flush()
+	}
+
+	return nil
+}
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	jsonOut := fs.Bool("json", false, "print the export report as JSON")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: gonotes export html [-json] <outdir>
+
+Render every note to HTML in <outdir> for read-only browsing. Wiki-links
+become relative page links, linked files are copied to <outdir>/files,
+and index pages are written for tags and dates. Broken links are shown
+as broken and listed in the report on stderr. <outdir> must be new, empty
+or a previous export, which is replaced.
+`)
+		fs.PrintDefaults()
+	}
+
+	if len(args) == 0 || args[0] != "html" {
+		fs.Usage()
+		return fmt.Errorf("expected export format \"html\"")
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly one output directory")
+	}
+
+	baseDir, cfg, err := openVault()
+	if err != nil {
+		return err
+	}
+
+	report, err := gonotes.ExportHTML(baseDir, cfg, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *jsonOut {
+		return writeJSON(os.Stdout, report)
+	}
+	fmt.Fprint(os.Stderr, report)
+	return nil
+}
+
+func runGraph(args []string) error {
+	fs := flag.NewFlagSet("graph", flag.ContinueOnError)
+	format := fs.String("format", "dot", "output format: dot, graphml or json")
+	neighbors := fs.Bool("neighbors", false, "include notes linked to or from the selected notes")
+	var tags stringSliceFlag
+	fs.Var(&tags, "tag", "only include notes with this tag or a tag below it (repeatable)")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: gonotes graph [flags] [query]
+
+Print the link graph of the notes matching the query (see list) as
+Graphviz DOT, GraphML or JSON. Links to files become file nodes and
+links that resolve to nothing become missing nodes.
+
+Examples:
+  gonotes graph | dot -Tsvg > notes.svg
+  gonotes graph -format graphml -tag project/alpha > alpha.graphml
+  gonotes graph -format json -neighbors 'date:>=2026-01-01'
+`)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var write func(g *gonotes.NoteGraph, w io.Writer) error
+	switch *format {
+	case "dot":
+		write = (*gonotes.NoteGraph).WriteDOT
+	case "graphml":
+		write = (*gonotes.NoteGraph).WriteGraphML
+	case "json":
+		write = func(g *gonotes.NoteGraph, w io.Writer) error { return writeJSON(w, g) }
+	default:
+		return fmt.Errorf("unknown graph format %q", *format)
+	}
+
+	q := strings.Join(fs.Args(), " ")
+	if len(tags) > 0 {
+		terms := make([]string, len(tags))
+		for i, tag := range tags {
+			terms[i] = `tag:"` + tag + `"`
+		}
+		q = "(" + strings.Join(terms, " OR ") + ") " + q
+	}
+	query, err := gonotes.ParseQuery(q)
+	if err != nil {
+		return err
+	}
+
+	baseDir, cfg, err := openVault()
+	if err != nil {
+		return err
+	}
+
+	g, errs, err := gonotes.BuildNoteGraph(baseDir, cfg, gonotes.GraphOptions{
+		Query:     query,
+		Neighbors: *neighbors,
+	})
+	if err != nil {
+		return err
+	}
+	for _, e := range errs {
+		fmt.Fprintf(os.Stderr, "warning: %s: %s\n", e.Filename, e.Message)
+	}
+
+	return write(g, os.Stdout)
+}
+
+func runImport(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, "Usage: gonotes import obsidian|enex [flags] <source>\n")
+		return fmt.Errorf("expected import source \"obsidian\" or \"enex\"")
+	}
+	switch args[0] {
+	case "obsidian":
+		return runImportObsidian(args[1:])
+	case "enex":
+		return runImportENEX(args[1:])
+	default:
+		return fmt.Errorf("unknown import source %q", args[0])
+	}
+}
+
+func runImportObsidian(args []string) error {
+	fs := flag.NewFlagSet("import obsidian", flag.ContinueOnError)
+	confirm := fs.Bool("y", false, "import without asking for confirmation")
+	jsonOut := fs.Bool("json", false, "print the plan as JSON on stdout; notes are only imported with -y")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: gonotes import obsidian [-y] [-json] <vault-dir>
+
+Import the notes of an Obsidian vault. Each note gets an ID from its
+frontmatter date or, failing that, its modification time; inline #tags
+and frontmatter tags become its tags; [[Note Name]] links become ID
+links; and linked attachments are copied into files/<id>-<slug>/.
+The plan is printed first and nothing is written until confirmed.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly one vault directory")
+	}
+
+	baseDir, cfg, err := openVault()
+	if err != nil {
+		return err
+	}
+
+	imp, err := gonotes.PlanObsidianImport(baseDir, cfg, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if *jsonOut {
+		if *confirm {
+			if err := gonotes.ExecuteObsidianImport(baseDir, cfg, imp); err != nil {
+				return err
+			}
+		}
+		return writeJSON(os.Stdout, struct {
+			Plan    *gonotes.ObsidianImport `json:"plan"`
+			Applied bool                    `json:"applied"`
+		}{imp, *confirm})
+	}
+
+	fmt.Fprint(os.Stderr, imp.String())
+	if len(imp.Notes) == 0 {
+		return nil
+	}
+	if !*confirm && !promptYN(fmt.Sprintf("Import %d note(s)?", len(imp.Notes))) {
+		fmt.Fprintln(os.Stderr, "Skipping import.")
+		return nil
+	}
+	if err := gonotes.ExecuteObsidianImport(baseDir, cfg, imp); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Imported %d note(s) and %d attachment(s).\n", len(imp.Notes), len(imp.Attachments))
+	return nil
+}
+
+func runImportENEX(args []string) error {
+	fs := flag.NewFlagSet("import enex", flag.ContinueOnError)
+	confirm := fs.Bool("y", false, "import without asking for confirmation")
+	jsonOut := fs.Bool("json", false, "print the report as JSON on stdout; notes are only imported with -y")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: gonotes import enex [-y] [-json] <file.enex>
+
+Import the notes of an Evernote export. Each note keeps its title, tags
+and creation date, its body is converted to markdown, and its attachments
+are decoded into a new folder under files/ and linked from the note.
+A dry run is printed first and nothing is written until confirmed.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly one .enex file")
+	}
+
+	baseDir, cfg, err := openVault()
+	if err != nil {
+		return err
+	}
+
+	importFile := func(dryRun bool) (*gonotes.ENEXImport, error) {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return gonotes.ImportENEX(baseDir, cfg, f, dryRun)
+	}
+
+	if *jsonOut {
+		imp, err := importFile(!*confirm)
+		// A failed import still reports the notes it created.
+		if err != nil && (!*confirm || imp == nil || len(imp.Notes) == 0) {
+			return err
+		}
+		if jerr := writeJSON(os.Stdout, struct {
+			Report  *gonotes.ENEXImport `json:"report"`
+			Applied bool                `json:"applied"`
+		}{imp, *confirm}); jerr != nil {
+			return jerr
+		}
+		return err
+	}
+
+	plan, err := importFile(true)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(os.Stderr, plan.String())
+	if len(plan.Notes) == 0 {
+		return nil
+	}
+	if !*confirm && !promptYN(fmt.Sprintf("Import %d note(s)?", len(plan.Notes))) {
+		fmt.Fprintln(os.Stderr, "Skipping import.")
+		return nil
+	}
+
+	imp, err := importFile(false)
+	if err != nil {
+		if imp != nil && len(imp.Notes) > 0 {
+			fmt.Fprintf(os.Stderr, "Imported before the failure:\n%s", imp.String())
+		}
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Imported %d note(s) and %d attachment(s).\n", len(imp.Notes), len(imp.Attachments))
+	return nil
+}
+
+func runEdit(args []string) error {
+	fs := flag.NewFlagSet("edit", flag.ContinueOnError)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: gonotes edit <id-or-prefix>
+
+Open a note in $EDITOR (falling back to vi). After the editor exits,
+the note is re-read, renamed if its title changed, and only its own
+symlinks under notes/by/date/ and notes/by/tags/ are updated.
+`)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected exactly one note ID or prefix")
+	}
+
+	baseDir, cfg, err := openVault()
+	if err != nil {
+		return err
+	}
+
+	idDir := filepath.Join(baseDir, cfg.IDDir)
+	name, err := gonotes.FindNote(idDir, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(idDir, name)
+
+	before, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if err := runEditor(path); err != nil {
+		return err
+	}
+
+	after, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if after.ModTime().Equal(before.ModTime()) && after.Size() == before.Size() {
+		return nil
+	}
+
+	res, err := gonotes.RelinkNote(baseDir, cfg, name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprint(os.Stderr, res.String())
+	fmt.Fprintln(os.Stdout, filepath.Join(idDir, res.NewName))
+	return nil
+}
+
+func runEditor(path string) error {
+	editor := os.Getenv("EDITOR")
+	if strings.TrimSpace(editor) == "" {
+		editor = "vi"
+	}
+	parts := strings.Fields(editor)
+	cmd := exec.Command(parts[0], append(parts[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("run editor: %w", err)
+	}
+	return nil
+}
+
+func runWatch(args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	interval := fs.Duration("i", time.Second, "polling interval")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: gonotes watch [flags]
+
+Poll notes/by/id/ for created, modified and deleted notes. Changed notes
+are renamed to match their title, given an ID if they have none, and their
+symlinks under notes/by/date/ and notes/by/tags/ are updated. The first
+poll brings every note in line. Stop with Ctrl-C.
+
+Flags:
+`)
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+
+	baseDir, cfg, err := openVault()
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintf(os.Stderr, "Watching %s\n", filepath.Join(baseDir, cfg.IDDir))
+	w := gonotes.NewWatcher(baseDir, cfg)
+	return w.Run(ctx, *interval, func(ev gonotes.WatchEvent) {
+		fmt.Fprint(os.Stderr, ev.String())
+	})
+}
+
+type noteJSON struct {
+	ID          string            `json:"id"`
+	Path        string            `json:"path"`
+	Title       string            `json:"title"`
+	Date        string            `json:"date,omitempty"`
+	Tags        []string          `json:"tags"`
+	Links       []string          `json:"links"`
+	Frontmatter map[string]string `json:"frontmatter"`
+}
+
+func writeNotesJSON(w io.Writer, notes []gonotes.ListedNote) error {
+	out := make([]noteJSON, len(notes))
+	for i, n := range notes {
+		out[i] = noteJSON{
+			ID:          n.ID,
+			Path:        n.Path,
+			Title:       n.Title,
+			Tags:        n.Tags,
+			Links:       n.InternalLinks,
+			Frontmatter: n.Frontmatter.Map(),
+		}
+		if out[i].Tags == nil {
+			out[i].Tags = []string{}
+		}
+		if out[i].Links == nil {
+			out[i].Links = []string{}
+		}
+		if !n.Date.IsZero() {
+			out[i].Date = n.Date.Format(time.RFC3339)
+		}
+	}
+	return writeJSON(w, out)
+}
+
+// writeJSON writes v to w as indented JSON.
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// openVault returns the vault root enclosing startDir and its
+// configuration. Without an enclosing vault, startDir itself is used so a
+// new vault can be started there.
+func openVault() (string, *gonotes.Config, error) {
+	dir := startDir
+	if dir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return "", nil, fmt.Errorf("get working directory: %w", err)
+		}
+		dir = wd
+	}
+
+	if info, err := os.Stat(dir); err != nil {
+		return "", nil, err
+	} else if !info.IsDir() {
+		return "", nil, fmt.Errorf("%s is not a directory", dir)
+	}
+
+	baseDir, err := gonotes.FindVaultRoot(dir)
+	if errors.Is(err, gonotes.ErrNoVault) {
+		baseDir, err = filepath.Abs(dir)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	cfg, err := gonotes.LoadConfig(baseDir)
+	if err != nil {
+		return "", nil, err
+	}
+	return baseDir, cfg, nil
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
 var stdinScanner = bufio.NewScanner(os.Stdin)
 
 func promptYN(question string) bool {
@@ -68,3 +68,35 @@ func TestRunNewDryRunInEmptyWorkspace(t *testing.T) {
 		t.Fatalf("runNew() err = %v", err)
 	}
 }
+
+func TestRunRebuildJSONIgnoresAssumeYes(t *testing.T) {
+	tmp := withTempCWD(t)
+	idDir := filepath.Join(tmp, "notes", "by", "id")
+	if err := os.MkdirAll(idDir, 0o755); err != nil {
+		t.Fatalf("MkdirAll() err = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(tmp, ".gonotes.yaml"), []byte("rebuild:\n  assume_yes: true\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(idDir, "20260328-1-old.md"), []byte("---\ntitle: New\n---\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	stdout := os.Stdout
+	os.Stdout = devNull
+	defer func() { os.Stdout = stdout }()
+
+	for _, args := range [][]string{{"-json"}, {"-json", "-r"}} {
+		if err := runRebuild(args); err != nil {
+			t.Fatalf("runRebuild(%v) err = %v", args, err)
+		}
+		if _, err := os.Stat(filepath.Join(idDir, "20260328-1-old.md")); err != nil {
+			t.Errorf("runRebuild(%v) applied changes without -y: %v", args, err)
+		}
+	}
+}
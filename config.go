@@ -0,0 +1,166 @@
+package gonotes
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFilename is the name of the optional configuration file at the
+// vault root.
+const ConfigFilename = ".gonotes.yaml"
+
+// Symlink views that can be enabled in Config.Views.
+const (
+	ViewDate = "date"
+	ViewTags = "tags"
+)
+
+// Config holds the vault settings read from .gonotes.yaml. Every field
+// that is left out of the file keeps its default, which matches the
+// behavior of a vault without a configuration file. Functions taking a
+// *Config treat nil as DefaultConfig().
+type Config struct {
+	// DateLayout is the Go time layout used to write note dates. Dates in
+	// the default layout are still read when a different one is set.
+	DateLayout string `yaml:"date_layout"`
+	// IDDir is where notes are stored, relative to the vault root.
+	IDDir string `yaml:"id_dir"`
+	// FilesDir is where file folders are stored, relative to the vault root.
+	FilesDir string `yaml:"files_dir"`
+	// Views lists the symlink views to generate under notes/by.
+	Views []string `yaml:"views"`
+	// Slug configures how titles are turned into filename slugs.
+	Slug SlugConfig `yaml:"slug"`
+	// DefaultTags are added to every note created with CreateNote.
+	DefaultTags []string `yaml:"default_tags"`
+	// InlineTags adds the #hashtags written in note bodies to their tags.
+	InlineTags bool `yaml:"inline_tags"`
+	// Rebuild configures the rebuild command.
+	Rebuild RebuildConfig `yaml:"rebuild"`
+}
+
+type SlugConfig struct {
+	// NonLatin is one of "transliterate", "keep" or "ascii"; see
+	// SlugNonLatin.
+	NonLatin string `yaml:"non_latin"`
+	// MaxLength is the maximum slug length in bytes; -1 disables the limit.
+	MaxLength int `yaml:"max_length"`
+}
+
+type RebuildConfig struct {
+	// AssumeYes answers yes to all rebuild prompts, as with -y.
+	AssumeYes bool `yaml:"assume_yes"`
+}
+
+// DefaultConfig returns the configuration of a vault without .gonotes.yaml.
+func DefaultConfig() *Config {
+	return &Config{
+		DateLayout: dateLayout,
+		IDDir:      filepath.Join("notes", "by", "id"),
+		FilesDir:   "files",
+		Views:      []string{ViewDate, ViewTags},
+		Slug: SlugConfig{
+			NonLatin:  "transliterate",
+			MaxLength: DefaultMaxSlugLength,
+		},
+	}
+}
+
+// LoadConfig reads .gonotes.yaml from baseDir on top of DefaultConfig. A
+// missing file is not an error.
+func LoadConfig(baseDir string) (*Config, error) {
+	cfg := DefaultConfig()
+
+	data, err := os.ReadFile(filepath.Join(baseDir, ConfigFilename))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("load config: %w", err)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("load config: %s: %w", ConfigFilename, err)
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("load config: %s: %w", ConfigFilename, err)
+	}
+	return cfg, nil
+}
+
+func (c *Config) validate() error {
+	if c.DateLayout == "" {
+		return fmt.Errorf("date_layout must not be empty")
+	}
+	for _, dir := range []struct{ key, value string }{
+		{"id_dir", c.IDDir},
+		{"files_dir", c.FilesDir},
+	} {
+		if dir.value == "" || filepath.IsAbs(dir.value) || !filepath.IsLocal(dir.value) {
+			return fmt.Errorf("%s must be a relative path inside the vault, got %q", dir.key, dir.value)
+		}
+	}
+	for _, view := range []string{ViewDate, ViewTags} {
+		viewDir := filepath.Join("notes", "by", view)
+		if rel, err := filepath.Rel(viewDir, c.IDDir); err == nil && filepath.IsLocal(rel) {
+			return fmt.Errorf("id_dir must not be inside %s", viewDir)
+		}
+	}
+	for _, v := range c.Views {
+		if v != ViewDate && v != ViewTags {
+			return fmt.Errorf("unknown view %q (want %q or %q)", v, ViewDate, ViewTags)
+		}
+	}
+	if _, err := parseSlugNonLatin(c.Slug.NonLatin); err != nil {
+		return err
+	}
+	return nil
+}
+
+// orDefault returns c, or DefaultConfig() when c is nil.
+func (c *Config) orDefault() *Config {
+	if c == nil {
+		return DefaultConfig()
+	}
+	return c
+}
+
+func (c *Config) idDir(baseDir string) string {
+	return filepath.Join(baseDir, c.IDDir)
+}
+
+func (c *Config) filesDir(baseDir string) string {
+	return filepath.Join(baseDir, c.FilesDir)
+}
+
+func (c *Config) viewEnabled(view string) bool {
+	return slices.Contains(c.Views, view)
+}
+
+func (c *Config) slugOptions() SlugOptions {
+	nonLatin, _ := parseSlugNonLatin(c.Slug.NonLatin)
+	return SlugOptions{NonLatin: nonLatin, MaxLength: c.Slug.MaxLength}
+}
+
+func parseSlugNonLatin(s string) (SlugNonLatin, error) {
+	switch s {
+	case "", "transliterate":
+		return SlugTransliterate, nil
+	case "keep":
+		return SlugKeepLetters, nil
+	case "ascii":
+		return SlugASCII, nil
+	default:
+		return 0, fmt.Errorf("unknown slug.non_latin %q (want transliterate, keep or ascii)", s)
+	}
+}
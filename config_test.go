@@ -0,0 +1,155 @@
+package gonotes
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("missing file gives defaults", func(t *testing.T) {
+		cfg, err := LoadConfig(t.TempDir())
+		if err != nil {
+			t.Fatalf("LoadConfig() err = %q", err)
+		}
+		if diff := cmp.Diff(DefaultConfig(), cfg); diff != "" {
+			t.Errorf("LoadConfig() diff (-want, +got):\n%s", diff)
+		}
+	})
+
+	t.Run("overrides keep other defaults", func(t *testing.T) {
+		baseDir := t.TempDir()
+		writeTestNote(t, baseDir, ConfigFilename, `id_dir: notes/all
+views: [tags]
+slug:
+  non_latin: keep
+default_tags: [inbox]
+inline_tags: true
+rebuild:
+  assume_yes: true
+`)
+		cfg, err := LoadConfig(baseDir)
+		if err != nil {
+			t.Fatalf("LoadConfig() err = %q", err)
+		}
+
+		want := DefaultConfig()
+		want.IDDir = "notes/all"
+		want.Views = []string{ViewTags}
+		want.Slug.NonLatin = "keep"
+		want.DefaultTags = []string{"inbox"}
+		want.InlineTags = true
+		want.Rebuild.AssumeYes = true
+		if diff := cmp.Diff(want, cfg); diff != "" {
+			t.Errorf("LoadConfig() diff (-want, +got):\n%s", diff)
+		}
+	})
+
+	invalid := []struct {
+		name    string
+		content string
+	}{
+		{"unknown key", "date_format: x\n"},
+		{"unknown view", "views: [month]\n"},
+		{"absolute id_dir", "id_dir: /notes\n"},
+		{"id_dir escapes vault", "id_dir: ../notes\n"},
+		{"id_dir inside view", "id_dir: notes/by/tags/all\n"},
+		{"unknown slug mode", "slug:\n  non_latin: drop\n"},
+		{"empty date layout", "date_layout: \"\"\n"},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			baseDir := t.TempDir()
+			writeTestNote(t, baseDir, ConfigFilename, tt.content)
+			if _, err := LoadConfig(baseDir); err == nil {
+				t.Error("LoadConfig() err = <nil>, want error")
+			}
+		})
+	}
+}
+
+func TestConfigVault(t *testing.T) {
+	baseDir := t.TempDir()
+	cfg := DefaultConfig()
+	cfg.IDDir = filepath.Join("notes", "all")
+	cfg.FilesDir = "attachments"
+	cfg.DateLayout = "02.01.2006 15:04"
+	cfg.Views = []string{ViewTags}
+	cfg.Slug.MaxLength = 5
+	cfg.DefaultTags = []string{"inbox"}
+
+	note, _, err := CreateNote(baseDir, cfg, nil, PrepareOptions{
+		Title: "Configured note",
+		Tags:  []string{"work"},
+		Now:   fixedNow,
+	}, false)
+	if err != nil {
+		t.Fatalf("CreateNote() err = %q", err)
+	}
+
+	path := filepath.Join(baseDir, "notes", "all", "20260328-1-confi.md")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("note not written to configured dir: %v", err)
+	}
+	for _, want := range []string{"date: 28.03.2026 14:30", "tags: inbox, work"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("note content missing %q:\n%s", want, data)
+		}
+	}
+	if note.Date.IsZero() {
+		t.Error("Date is zero, want date parsed with configured layout")
+	}
+
+	// An older note in the default layout is still dated and linked.
+	writeTestNote(t, cfg.idDir(baseDir), "20260101-1-old.md", `---
+title: Old
+date: 2026-01-01 09:00:00
+tags: work
+---
+
+Links to [[20260328-1-confi]] and [[20260101-1-slides/deck.pdf]].`)
+	writeTestNote(t, filepath.Join(baseDir, "attachments", "20260101-1-slides"), "deck.pdf", "")
+
+	// A stale date view is removed because the view is disabled.
+	stale := filepath.Join(baseDir, "notes", "by", "date", "2026-03-28")
+	if err := os.MkdirAll(stale, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../../../all/20260328-1-confi.md", filepath.Join(stale, "20260328-1-confi.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := ScanNotes(baseDir, cfg)
+	if err != nil {
+		t.Fatalf("ScanNotes() err = %q", err)
+	}
+	if len(report.BrokenLinks) > 0 || len(report.Renames) > 0 || len(report.Errors) > 0 {
+		t.Errorf("ScanNotes() report = %s, want no issues", report)
+	}
+
+	if err := RebuildSymlinks(baseDir, cfg); err != nil {
+		t.Fatalf("RebuildSymlinks() err = %q", err)
+	}
+
+	got, err := snapshotNoteSymlinks(baseDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"notes/by/tags/inbox/20260328-1-confi.md": "../../../all/20260328-1-confi.md",
+		"notes/by/tags/work/20260328-1-confi.md":  "../../../all/20260328-1-confi.md",
+		"notes/by/tags/work/20260101-1-old.md":    "../../../all/20260101-1-old.md",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("symlinks diff (-want, +got):\n%s", diff)
+	}
+	for path := range got {
+		if _, err := os.Stat(filepath.Join(baseDir, path)); err != nil {
+			t.Errorf("symlink %s does not resolve: %v", path, err)
+		}
+	}
+}
@@ -1,14 +1,37 @@
 package gonotes
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"time"
 )
 
-func CreateNote(baseDir string, r io.Reader, opts PrepareOptions, dryRun bool) (*Note, *Plan, error) {
+// CreateNote prepares a note from r and opts, writes it under a freshly
+// allocated ID and creates its symlinks. The configured default tags are
+// added to opts.Tags. With dryRun nothing is written and the ID is the one
+// the note would most likely get.
+func CreateNote(baseDir string, cfg *Config, r io.Reader, opts PrepareOptions, dryRun bool) (*Note, *Plan, error) {
+	return createNote(baseDir, cfg, r, opts, dryRun, nil)
+}
+
+// createNote is CreateNote with an optional attach hook for notes that
+// come with a folder under the files dir. The ID is then numbered after
+// both the notes and the files dir, so note and folder can share it, and
+// attach is called with the ID set, under the vault lock, before the note
+// is written. It may create the folder and change the note's Body; it must
+// fail with an error wrapping os.ErrExist if the folder exists, and return
+// an undo func that removes what it created. With dryRun attach is not
+// called.
+func createNote(baseDir string, cfg *Config, r io.Reader, opts PrepareOptions, dryRun bool, attach func(note *Note) (undo func(), err error)) (*Note, *Plan, error) {
+	cfg = cfg.orDefault()
+	if len(cfg.DefaultTags) > 0 {
+		opts.Tags = append(slices.Clone(cfg.DefaultTags), opts.Tags...)
+	}
+
 	now := time.Now
 	if opts.Now != nil {
 		now = opts.Now
@@ -16,34 +39,51 @@ func CreateNote(baseDir string, r io.Reader, opts PrepareOptions, dryRun bool) (
 	nowTime := now()
 	opts.Now = func() time.Time { return nowTime }
 
-	note, err := Prepare(r, opts)
+	note, err := prepare(r, opts, cfg)
 	if err != nil {
 		return nil, nil, fmt.Errorf("create note: %w", err)
 	}
 
-	idDir := filepath.Join(baseDir, "notes", "by", "id")
-	id, err := NextID(idDir, nowTime)
-	if err != nil {
-		return nil, nil, fmt.Errorf("create note: %w", err)
+	idDir := cfg.idDir(baseDir)
+	dirs := []string{idDir}
+	if attach != nil {
+		dirs = append(dirs, cfg.filesDir(baseDir))
 	}
-	note.ID = id
-
-	plan := NotePlan(note)
 
 	if dryRun {
-		return note, plan, nil
+		num, err := maxNumFromDirs(dirs, nowTime)
+		if err != nil {
+			return nil, nil, fmt.Errorf("create note: %w", err)
+		}
+		note.ID = fmtID(idPrefix(nowTime), num+1)
+		return note, NotePlan(note, cfg), nil
 	}
 
-	filename := NoteFilename(note.ID, note.Slug)
-	writePath := filepath.Join(baseDir, "notes", "by", "id", filename)
-	writeDir := filepath.Dir(writePath)
-	if err := os.MkdirAll(writeDir, 0o755); err != nil {
+	if err := os.MkdirAll(idDir, 0o755); err != nil {
 		return nil, nil, fmt.Errorf("create note: %w", err)
 	}
-	if err := os.WriteFile(writePath, []byte(note.Markdown()), 0o644); err != nil {
+
+	err = allocateID(baseDir, dirs, nowTime, func(id string) error {
+		note.ID = id
+		undo := func() {}
+		if attach != nil {
+			var err error
+			if undo, err = attach(note); err != nil {
+				return err
+			}
+		}
+		path := filepath.Join(idDir, NoteFilename(id, note.Slug))
+		if err := writeNewFile(path, []byte(note.Markdown()), 0o644); err != nil {
+			undo()
+			return err
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, nil, fmt.Errorf("create note: %w", err)
 	}
 
+	plan := NotePlan(note, cfg)
 	if err := plan.CreateLinks(baseDir); err != nil {
 		return nil, nil, fmt.Errorf("create note: %w", err)
 	}
@@ -51,21 +91,77 @@ func CreateNote(baseDir string, r io.Reader, opts PrepareOptions, dryRun bool) (
 	return note, plan, nil
 }
 
-func CreateFolder(baseDir, title string, now func() time.Time) (string, error) {
-	filesDir := filepath.Join(baseDir, "files")
+func CreateFolder(baseDir string, cfg *Config, title string, now func() time.Time) (string, error) {
+	cfg = cfg.orDefault()
+	filesDir := cfg.filesDir(baseDir)
+	if err := os.MkdirAll(filesDir, 0o755); err != nil {
+		return "", fmt.Errorf("create folder: %w", err)
+	}
 
-	id, err := NextID(filesDir, now())
+	slug := Slugify(title, cfg.slugOptions())
+	var absPath string
+	err := allocateID(baseDir, []string{filesDir}, now(), func(id string) error {
+		absPath = filepath.Join(filesDir, FolderName(id, slug))
+		return os.Mkdir(absPath, 0o755)
+	})
 	if err != nil {
 		return "", fmt.Errorf("create folder: %w", err)
 	}
 
-	slug := slugify(title)
-	name := FolderName(id, slug)
-	absPath := filepath.Join(filesDir, name)
+	return absPath, nil
+}
 
-	if err := os.MkdirAll(absPath, 0o755); err != nil {
-		return "", fmt.Errorf("create folder: %w", err)
+// allocateID picks the next ID that is free in all of dirs and passes it
+// to create, which must fail with an error wrapping os.ErrExist if the ID
+// turns out to be taken. The vault lock is held throughout, so concurrent
+// gonotes processes never pick the same ID. Files from other tools are
+// covered twice: an ID any entry of dirs already starts with is skipped,
+// whatever its slug, and create is retried when it still collides.
+func allocateID(baseDir string, dirs []string, now time.Time, create func(id string) error) error {
+	unlock, err := lockVault(baseDir)
+	if err != nil {
+		return err
 	}
+	defer unlock()
 
-	return absPath, nil
+	prefix := idPrefix(now)
+	num, err := maxNumFromDirs(dirs, now)
+	if err != nil {
+		return fmt.Errorf("max id: %w", err)
+	}
+
+	for range maxIDAttempts {
+		num++
+		id := fmtID(prefix, num)
+		// A file may appear after the max was taken, so each ID is
+		// checked again right before it is used.
+		used, err := usedIDs(dirs...)
+		if err != nil {
+			return err
+		}
+		if _, taken := used[id]; taken {
+			continue
+		}
+		err = create(id)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return err
+		}
+	}
+	return fmt.Errorf("no free ID after %d attempts", maxIDAttempts)
+}
+
+// maxNumFromDirs is the highest MaxNumFromDir of dirs.
+func maxNumFromDirs(dirs []string, now time.Time) (int, error) {
+	num := 0
+	for _, dir := range dirs {
+		n, err := MaxNumFromDir(dir, now)
+		if err != nil {
+			return 0, err
+		}
+		num = max(num, n)
+	}
+	return num, nil
 }
@@ -1,9 +1,12 @@
 package gonotes
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -23,7 +26,7 @@ Test body with [[20260101-1]] link.`)
 
 	opts := PrepareOptions{Now: now}
 
-	note, plan, err := CreateNote(baseDir, input, opts, false)
+	note, plan, err := CreateNote(baseDir, nil, input, opts, false)
 	if err != nil {
 		t.Fatalf("CreateNote() err = %q", err)
 	}
@@ -66,7 +69,7 @@ func TestCreateNoteUsesNextID(t *testing.T) {
 		Now:   now,
 	}
 
-	note, _, err := CreateNote(baseDir, nil, opts, false)
+	note, _, err := CreateNote(baseDir, nil, nil, opts, false)
 	if err != nil {
 		t.Fatalf("CreateNote() err = %q", err)
 	}
@@ -91,7 +94,7 @@ func TestCreateNoteDryRun(t *testing.T) {
 		Now:   now,
 	}
 
-	note, plan, err := CreateNote(baseDir, nil, opts, true)
+	note, plan, err := CreateNote(baseDir, nil, nil, opts, true)
 	if err != nil {
 		t.Fatalf("CreateNote() err = %q", err)
 	}
@@ -125,7 +128,7 @@ func TestCreateNoteSequentialIDs(t *testing.T) {
 		Now:   now,
 	}
 
-	note1, _, err := CreateNote(baseDir, nil, opts, false)
+	note1, _, err := CreateNote(baseDir, nil, nil, opts, false)
 	if err != nil {
 		t.Fatalf("first CreateNote() err = %q", err)
 	}
@@ -134,7 +137,7 @@ func TestCreateNoteSequentialIDs(t *testing.T) {
 	}
 
 	opts.Title = "Second"
-	note2, _, err := CreateNote(baseDir, nil, opts, false)
+	note2, _, err := CreateNote(baseDir, nil, nil, opts, false)
 	if err != nil {
 		t.Fatalf("second CreateNote() err = %q", err)
 	}
@@ -153,7 +156,7 @@ func TestCreateNoteAndRebuildSymlinksAreStable(t *testing.T) {
 		Now:   now,
 	}
 
-	_, _, err := CreateNote(baseDir, nil, opts, false)
+	_, _, err := CreateNote(baseDir, nil, nil, opts, false)
 	if err != nil {
 		t.Fatalf("CreateNote() err = %q", err)
 	}
@@ -163,7 +166,7 @@ func TestCreateNoteAndRebuildSymlinksAreStable(t *testing.T) {
 		t.Fatalf("snapshot before rebuild: %v", err)
 	}
 
-	if err := RebuildSymlinks(baseDir); err != nil {
+	if err := RebuildSymlinks(baseDir, nil); err != nil {
 		t.Fatalf("RebuildSymlinks() err = %q", err)
 	}
 
@@ -183,7 +186,7 @@ func TestCreateFolder(t *testing.T) {
 		return time.Date(2026, 4, 3, 10, 0, 0, 0, time.UTC)
 	}
 
-	path, err := CreateFolder(baseDir, "Contract PDFs", now)
+	path, err := CreateFolder(baseDir, nil, "Contract PDFs", now)
 	if err != nil {
 		t.Fatalf("CreateFolder() err = %q", err)
 	}
@@ -208,12 +211,12 @@ func TestCreateFolderSequentialIDs(t *testing.T) {
 		return time.Date(2026, 4, 3, 10, 0, 0, 0, time.UTC)
 	}
 
-	path1, err := CreateFolder(baseDir, "First", now)
+	path1, err := CreateFolder(baseDir, nil, "First", now)
 	if err != nil {
 		t.Fatalf("CreateFolder(1) err = %q", err)
 	}
 
-	path2, err := CreateFolder(baseDir, "Second", now)
+	path2, err := CreateFolder(baseDir, nil, "Second", now)
 	if err != nil {
 		t.Fatalf("CreateFolder(2) err = %q", err)
 	}
@@ -235,7 +238,7 @@ func TestCreateFolderNoTitle(t *testing.T) {
 		return time.Date(2026, 4, 3, 10, 0, 0, 0, time.UTC)
 	}
 
-	path, err := CreateFolder(baseDir, "", now)
+	path, err := CreateFolder(baseDir, nil, "", now)
 	if err != nil {
 		t.Fatalf("CreateFolder() err = %q", err)
 	}
@@ -245,3 +248,236 @@ func TestCreateFolderNoTitle(t *testing.T) {
 		t.Errorf("CreateFolder() = %q, want %q", path, wantDir)
 	}
 }
+
+func TestCreateNoteConcurrentUniqueIDs(t *testing.T) {
+	baseDir := t.TempDir()
+	now := func() time.Time { return testTime }
+
+	const n = 20
+	ids := make([]string, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			opts := PrepareOptions{Title: fmt.Sprintf("Note %d", i), Now: now}
+			note, _, err := CreateNote(baseDir, nil, nil, opts, false)
+			errs[i] = err
+			if err == nil {
+				ids[i] = note.ID
+			}
+		}()
+	}
+	wg.Wait()
+
+	seen := map[string]struct{}{}
+	for i := range n {
+		if errs[i] != nil {
+			t.Fatalf("CreateNote() #%d err = %q", i, errs[i])
+		}
+		if _, ok := seen[ids[i]]; ok {
+			t.Errorf("duplicate ID %q", ids[i])
+		}
+		seen[ids[i]] = struct{}{}
+	}
+
+	entries, err := os.ReadDir(filepath.Join(baseDir, "notes", "by", "id"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != n {
+		t.Errorf("got %d note files, want %d", len(entries), n)
+	}
+}
+
+func TestCreateNoteSkipsTakenFilename(t *testing.T) {
+	baseDir := t.TempDir()
+	idDir := filepath.Join(baseDir, "notes", "by", "id")
+	now := func() time.Time { return testTime }
+
+	// Simulate a note written by another tool after the ID was chosen:
+	// allocateID must move on instead of overwriting it.
+	calls := 0
+	err := allocateID(baseDir, []string{idDir}, testTime, func(id string) error {
+		calls++
+		if calls == 1 {
+			return fmt.Errorf("taken: %w", os.ErrExist)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("allocateID() err = %q", err)
+	}
+	if calls != 2 {
+		t.Errorf("create called %d times, want 2", calls)
+	}
+
+	writeTestNote(t, idDir, "20260328-1-taken.md", "original")
+	if err := writeNewFile(filepath.Join(idDir, "20260328-1-taken.md"), []byte("new"), 0o644); !errors.Is(err, os.ErrExist) {
+		t.Errorf("writeNewFile() on existing file err = %v, want os.ErrExist", err)
+	}
+
+	note, _, err := CreateNote(baseDir, nil, nil, PrepareOptions{Title: "Taken", Now: now}, false)
+	if err != nil {
+		t.Fatalf("CreateNote() err = %q", err)
+	}
+	if note.ID != "20260328-2" {
+		t.Errorf("ID = %q, want %q", note.ID, "20260328-2")
+	}
+	content, err := os.ReadFile(filepath.Join(idDir, "20260328-1-taken.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "original" {
+		t.Errorf("existing note overwritten: %q", content)
+	}
+}
+
+func TestCreateNoteSkipsIDUsedUnderOtherSlug(t *testing.T) {
+	baseDir := t.TempDir()
+	idDir := filepath.Join(baseDir, "notes", "by", "id")
+
+	// Another tool writes a note with the next ID but a different slug
+	// while the first create fails: O_EXCL would not catch it, so the ID
+	// must be skipped.
+	var ids []string
+	err := allocateID(baseDir, []string{idDir}, testTime, func(id string) error {
+		ids = append(ids, id)
+		if len(ids) == 1 {
+			writeTestNote(t, idDir, "20260328-2-other-tool.md", "other")
+			return fmt.Errorf("taken: %w", os.ErrExist)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("allocateID() err = %q", err)
+	}
+	if diff := cmp.Diff([]string{"20260328-1", "20260328-3"}, ids); diff != "" {
+		t.Errorf("create IDs diff (-want, +got):\n%s", diff)
+	}
+}
+
+func TestCreateFolderConcurrentUniqueIDs(t *testing.T) {
+	baseDir := t.TempDir()
+	now := func() time.Time { return testTime }
+
+	const n = 10
+	paths := make([]string, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			paths[i], errs[i] = CreateFolder(baseDir, nil, "", now)
+		}()
+	}
+	wg.Wait()
+
+	seen := map[string]struct{}{}
+	for i := range n {
+		if errs[i] != nil {
+			t.Fatalf("CreateFolder() #%d err = %q", i, errs[i])
+		}
+		if _, ok := seen[paths[i]]; ok {
+			t.Errorf("duplicate folder %q", paths[i])
+		}
+		seen[paths[i]] = struct{}{}
+	}
+}
+
+func TestLockVaultBreaksStaleLock(t *testing.T) {
+	baseDir := t.TempDir()
+
+	lockPath := filepath.Join(stateDir(baseDir), "lock")
+	writeTestNote(t, stateDir(baseDir), "lock", "12345\n")
+	old := time.Now().Add(-2 * lockStaleAge)
+	if err := os.Chtimes(lockPath, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	unlock, err := lockVault(baseDir)
+	if err != nil {
+		t.Fatalf("lockVault() err = %q", err)
+	}
+	unlock()
+
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Errorf("lock file still exists after unlock: %v", err)
+	}
+}
+
+func TestLockVaultKeepsOtherLocks(t *testing.T) {
+	baseDir := t.TempDir()
+	lockPath := filepath.Join(stateDir(baseDir), "lock")
+
+	// A waiter that saw a stale lock must not remove the lock another
+	// process took after it looked.
+	writeTestNote(t, stateDir(baseDir), "lock", "12345\n")
+	stale, err := os.Stat(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Moved rather than removed, so the new lock cannot reuse its inode.
+	if err := os.Rename(lockPath, filepath.Join(t.TempDir(), "lock")); err != nil {
+		t.Fatal(err)
+	}
+	writeTestNote(t, stateDir(baseDir), "lock", "23456\n")
+	old := time.Now().Add(-2 * lockStaleAge)
+	if err := os.Chtimes(lockPath, old, old); err != nil {
+		t.Fatal(err)
+	}
+	breakStaleLock(lockPath, stale)
+	if got, err := os.ReadFile(lockPath); err != nil || string(got) != "23456\n" {
+		t.Errorf("lock after breaking another lock = %q, %v; want it kept", got, err)
+	}
+
+	// Nor may it break a stale lock while another waiter is breaking it,
+	// or one refreshed since it looked.
+	stale, err = os.Stat(lockPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestNote(t, stateDir(baseDir), "lock.break", "")
+	if breakStaleLock(lockPath, stale) {
+		t.Error("breakStaleLock() = true while the breaker is held")
+	}
+	if err := os.Remove(lockPath + ".break"); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	if err := os.Chtimes(lockPath, now, now); err != nil {
+		t.Fatal(err)
+	}
+	breakStaleLock(lockPath, stale)
+	if _, err := os.Stat(lockPath); err != nil {
+		t.Errorf("refreshed lock broken: %v", err)
+	}
+	if err := os.Remove(lockPath); err != nil {
+		t.Fatal(err)
+	}
+
+	// Releasing a lock that was broken meanwhile leaves the new holder's
+	// lock alone.
+	unlock, err := lockVault(baseDir)
+	if err != nil {
+		t.Fatalf("lockVault() err = %q", err)
+	}
+	if err := os.Rename(lockPath, filepath.Join(t.TempDir(), "lock")); err != nil {
+		t.Fatal(err)
+	}
+	writeTestNote(t, stateDir(baseDir), "lock", "34567\n")
+	unlock()
+	if got, err := os.ReadFile(lockPath); err != nil || string(got) != "34567\n" {
+		t.Errorf("lock after unlock = %q, %v; want the new holder's lock kept", got, err)
+	}
+
+	entries, err := os.ReadDir(stateDir(baseDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("state dir has %d entries, want only the lock", len(entries))
+	}
+}
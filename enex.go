@@ -0,0 +1,694 @@
+package gonotes
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ENEXImport reports the notes an Evernote import created, or with a dry
+// run would create.
+type ENEXImport struct {
+	Notes []ImportedNote `json:"notes"`
+	// Attachments are the decoded resources. Source is the resource's file
+	// name in Evernote, if it had one.
+	Attachments []ImportedFile `json:"attachments"`
+	// Errors are per-note problems; Filename holds the note's title.
+	Errors []ScanError `json:"errors"`
+}
+
+// MarshalJSON encodes the report with empty lists rather than null.
+func (imp ENEXImport) MarshalJSON() ([]byte, error) {
+	type report ENEXImport
+	out := report(imp)
+	out.Notes = nonNil(out.Notes)
+	for i := range out.Notes {
+		out.Notes[i].Tags = nonNil(out.Notes[i].Tags)
+	}
+	out.Attachments = nonNil(out.Attachments)
+	out.Errors = nonNil(out.Errors)
+	return json.Marshal(out)
+}
+
+func (imp *ENEXImport) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Notes (%d):\n", len(imp.Notes))
+	for _, n := range imp.Notes {
+		fmt.Fprintf(&b, "  %s -> %s\n", n.Title, n.Filename)
+	}
+
+	if len(imp.Attachments) > 0 {
+		fmt.Fprintf(&b, "Attachments (%d):\n", len(imp.Attachments))
+		for _, f := range imp.Attachments {
+			fmt.Fprintf(&b, "  %s\n", f.Dest)
+		}
+	}
+
+	if len(imp.Errors) > 0 {
+		fmt.Fprintf(&b, "Errors (%d):\n", len(imp.Errors))
+		for _, e := range imp.Errors {
+			fmt.Fprintf(&b, "  %s: %s\n", e.Filename, e.Message)
+		}
+	}
+
+	return b.String()
+}
+
+// enexNote is a <note> element of an Evernote export.
+type enexNote struct {
+	Title     string         `xml:"title"`
+	Content   string         `xml:"content"`
+	Created   string         `xml:"created"`
+	Tags      []string       `xml:"tag"`
+	SourceURL string         `xml:"note-attributes>source-url"`
+	Resources []enexResource `xml:"resource"`
+}
+
+type enexResource struct {
+	Data     string `xml:"data"`
+	Mime     string `xml:"mime"`
+	FileName string `xml:"resource-attributes>file-name"`
+}
+
+// enexTimeLayout is the timestamp format of ENEX files, always in UTC.
+const enexTimeLayout = "20060102T150405Z"
+
+// ImportENEX creates a note through CreateNote for every <note> in the
+// Evernote export read from r. The note keeps its title, is dated and
+// numbered by its creation time, and gets its tags, with spaces in tag
+// names replaced by dashes, and its source URL as source. The ENML body is
+// converted to markdown. Resources are decoded into a new folder under the
+// files dir that shares the note's ID, and linked from where the body shows
+// them; resources the body does not show are linked at the end. IDs are
+// numbered after both existing notes and existing folders.
+//
+// With dryRun nothing is written and the report shows the names notes and
+// folders would most likely get. On error, the returned import lists the
+// notes already created, which are kept.
+func ImportENEX(baseDir string, cfg *Config, r io.Reader, dryRun bool) (*ENEXImport, error) {
+	cfg = cfg.orDefault()
+	imp := &ENEXImport{}
+	dry := &dryRunIDs{next: map[string]int{}}
+
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return imp, fmt.Errorf("import enex: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "note" {
+			continue
+		}
+
+		var en enexNote
+		if err := dec.DecodeElement(&en, &start); err != nil {
+			return imp, fmt.Errorf("import enex: %w", err)
+		}
+		if err := importENEXNote(baseDir, cfg, &en, imp, dryRun, dry); err != nil {
+			return imp, fmt.Errorf("import enex: %s: %w", en.Title, err)
+		}
+	}
+
+	return imp, nil
+}
+
+// importENEXNote creates the note and attachment folder for en and adds
+// them to imp.
+func importENEXNote(baseDir string, cfg *Config, en *enexNote, imp *ENEXImport, dryRun bool, dry *dryRunIDs) error {
+	title := strings.TrimSpace(en.Title)
+	if title == "" {
+		title = "Untitled"
+	}
+
+	created := time.Now()
+	if en.Created != "" {
+		t, err := time.Parse(enexTimeLayout, strings.TrimSpace(en.Created))
+		if err != nil {
+			imp.Errors = append(imp.Errors, ScanError{
+				Filename: title,
+				Message:  fmt.Sprintf("unrecognized created date %q; using the current time", en.Created),
+			})
+		} else {
+			created = t.Local()
+		}
+	}
+	now := func() time.Time { return created }
+
+	// Decode resources first: the body links to them by content hash.
+	type resource struct {
+		name string
+		data []byte
+	}
+	var resources []resource
+	byHash := map[string]int{}
+	usedNames := map[string]bool{}
+	for i, res := range en.Resources {
+		data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(res.Data), ""))
+		if err != nil {
+			imp.Errors = append(imp.Errors, ScanError{
+				Filename: title,
+				Message:  fmt.Sprintf("resource %d: decode: %v", i+1, err),
+			})
+			continue
+		}
+		name := resourceFilename(res, i+1, usedNames)
+		sum := md5.Sum(data)
+		byHash[hex.EncodeToString(sum[:])] = len(resources)
+		resources = append(resources, resource{name: name, data: data})
+	}
+
+	var tags []string
+	for _, tag := range en.Tags {
+		if tag = strings.Join(strings.FieldsFunc(tag, isTagSeparator), "-"); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	opts := PrepareOptions{Title: title, Tags: tags, Now: now}
+	if en.SourceURL != "" {
+		opts.ExtraFrontmatter = append(opts.ExtraFrontmatter, FrontmatterField{Key: "source", Value: en.SourceURL})
+	}
+
+	// The body links into the folder, which is named after the note's ID,
+	// so it is converted once the ID is known.
+	var folder string
+	var convErr error
+	setBody := func(note *Note) {
+		folder = FolderName(note.ID, note.Slug)
+		linked := make([]bool, len(resources))
+		conv := &enmlConverter{
+			media: func(hash string) (string, bool) {
+				i, ok := byHash[strings.ToLower(hash)]
+				if !ok {
+					return "", false
+				}
+				linked[i] = true
+				return folder + "/" + resources[i].name, true
+			},
+		}
+		body, err := conv.convert(en.Content)
+		convErr = err
+		if err != nil {
+			body = strings.TrimSpace(en.Content)
+		}
+
+		var unlinked []string
+		for i, res := range resources {
+			if !linked[i] {
+				unlinked = append(unlinked, resourceLink(folder+"/"+res.name))
+			}
+		}
+		if len(unlinked) > 0 {
+			body = strings.TrimRight(body, "\n") + "\n\n" + strings.Join(unlinked, "\n")
+		}
+		note.Body = "\n" + strings.TrimRight(body, "\n")
+		note.deriveFields(cfg)
+	}
+
+	attach := func(note *Note) (func(), error) {
+		setBody(note)
+		if len(resources) == 0 {
+			return func() {}, nil
+		}
+		dir := filepath.Join(cfg.filesDir(baseDir), folder)
+		if err := os.MkdirAll(cfg.filesDir(baseDir), 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			return nil, err
+		}
+		undo := func() { os.RemoveAll(dir) }
+		for _, res := range resources {
+			if err := writeNewFile(filepath.Join(dir, res.name), res.data, 0o644); err != nil {
+				undo()
+				return nil, err
+			}
+		}
+		return undo, nil
+	}
+
+	note, _, err := createNote(baseDir, cfg, nil, opts, dryRun, attach)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		note.ID = dry.adjust("notes", note.ID)
+		setBody(note)
+	}
+	if convErr != nil {
+		imp.Errors = append(imp.Errors, ScanError{
+			Filename: title,
+			Message:  fmt.Sprintf("convert content: %v; imported as plain text", convErr),
+		})
+	}
+	for _, res := range resources {
+		imp.Attachments = append(imp.Attachments, ImportedFile{Source: res.name, Dest: folder + "/" + res.name})
+	}
+
+	imp.Notes = append(imp.Notes, ImportedNote{
+		Source:   en.Title,
+		Filename: NoteFilename(note.ID, note.Slug),
+		Title:    note.Title,
+		Tags:     note.Tags,
+		note:     note,
+	})
+	return nil
+}
+
+func isTagSeparator(r rune) bool {
+	return r == ',' || r == ' ' || r == '\t'
+}
+
+// dryRunIDs predicts IDs for a dry run. The predicted ID stays the same
+// until a note is written, so each further prediction for the same day and
+// directory is numbered one higher.
+type dryRunIDs struct {
+	next map[string]int
+}
+
+func (d *dryRunIDs) adjust(dir, id string) string {
+	m := reIDPrefix.FindStringSubmatch(id)
+	if m == nil {
+		return id
+	}
+	key := dir + "/" + m[1]
+	num, _ := strconv.Atoi(m[2])
+	num += d.next[key]
+	d.next[key]++
+	return fmtID(m[1], num)
+}
+
+// resourceFilename returns a safe, unique file name for a resource: its
+// Evernote file name if it has one, else resource-<n> with an extension
+// for its MIME type. Path separators and the characters that end a wiki
+// link target are replaced, so the note can link to it.
+func resourceFilename(res enexResource, n int, used map[string]bool) string {
+	name := strings.TrimSpace(res.FileName)
+	name = strings.NewReplacer("/", "-", `\`, "-", "#", "-", "|", "-", "^", "-", "[", "-", "]", "-").Replace(name)
+	if name == "" || name == "." || name == ".." || strings.HasPrefix(name, ".") {
+		name = "resource-" + strconv.Itoa(n) + mimeExtension(res.Mime)
+	}
+
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	candidate := name
+	for i := 2; used[strings.ToLower(candidate)]; i++ {
+		candidate = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+	used[strings.ToLower(candidate)] = true
+	return candidate
+}
+
+func mimeExtension(mimeType string) string {
+	switch mimeType {
+	case "image/png":
+		return ".png"
+	case "image/jpeg":
+		return ".jpg"
+	case "image/gif":
+		return ".gif"
+	case "application/pdf":
+		return ".pdf"
+	case "text/plain":
+		return ".txt"
+	}
+	if exts, err := mime.ExtensionsByType(mimeType); err == nil && len(exts) > 0 {
+		return exts[0]
+	}
+	return ""
+}
+
+// resourceLink links to a file under the files dir, embedding images.
+func resourceLink(target string) string {
+	if isImagePath(target) {
+		return "![[" + target + "]]"
+	}
+	return "[[" + target + "]]"
+}
+
+// enmlNode is an element or, when name is empty, a text node of an ENML
+// document.
+type enmlNode struct {
+	name     string
+	attrs    map[string]string
+	children []*enmlNode
+	text     string
+}
+
+// enmlConverter converts ENML, the XHTML dialect of Evernote note bodies,
+// to markdown.
+type enmlConverter struct {
+	// media returns the files-dir path of the resource with the given MD5
+	// hash.
+	media func(hash string) (string, bool)
+
+	listDepth int
+}
+
+// mdBlock is a converted block. Consecutive line blocks are joined by a
+// single newline, like the <div> lines Evernote writes; other blocks are
+// separated by a blank line. An empty block only forces a blank line.
+type mdBlock struct {
+	text string
+	line bool
+}
+
+func (c *enmlConverter) convert(content string) (string, error) {
+	root, err := parseENML(content)
+	if err != nil {
+		return "", err
+	}
+	return joinBlocks(c.blocks(root.children)), nil
+}
+
+func parseENML(content string) (*enmlNode, error) {
+	dec := xml.NewDecoder(strings.NewReader(content))
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+
+	root := &enmlNode{name: "root"}
+	stack := []*enmlNode{root}
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		top := stack[len(stack)-1]
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &enmlNode{name: strings.ToLower(t.Name.Local), attrs: map[string]string{}}
+			for _, a := range t.Attr {
+				n.attrs[strings.ToLower(a.Name.Local)] = a.Value
+			}
+			top.children = append(top.children, n)
+			stack = append(stack, n)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			top.children = append(top.children, &enmlNode{text: string(t)})
+		}
+	}
+	return root, nil
+}
+
+func isENMLBlock(name string) bool {
+	switch name {
+	case "div", "p", "h1", "h2", "h3", "h4", "h5", "h6", "ul", "ol", "li", "pre",
+		"blockquote", "table", "hr", "en-note", "center", "section", "article":
+		return true
+	}
+	return false
+}
+
+// blocks converts a sequence of nodes, grouping inline content into lines.
+func (c *enmlConverter) blocks(nodes []*enmlNode) []mdBlock {
+	var out []mdBlock
+	var inline strings.Builder
+	flush := func() {
+		if text := strings.TrimSpace(inline.String()); text != "" {
+			out = append(out, mdBlock{text: text, line: true})
+		}
+		inline.Reset()
+	}
+	for _, n := range nodes {
+		if n.name == "" || !isENMLBlock(n.name) {
+			inline.WriteString(c.inline(n))
+			continue
+		}
+		flush()
+		out = append(out, c.block(n)...)
+	}
+	flush()
+	return out
+}
+
+func joinBlocks(blocks []mdBlock) string {
+	var b strings.Builder
+	prevLine, para := false, false
+	for _, blk := range blocks {
+		if blk.text == "" {
+			para = true
+			continue
+		}
+		if b.Len() > 0 {
+			if prevLine && blk.line && !para {
+				b.WriteString("\n")
+			} else {
+				b.WriteString("\n\n")
+			}
+		}
+		b.WriteString(blk.text)
+		prevLine, para = blk.line, false
+	}
+	return b.String()
+}
+
+func (c *enmlConverter) block(n *enmlNode) []mdBlock {
+	switch n.name {
+	case "div", "center", "section", "article", "en-note":
+		inner := c.blocks(n.children)
+		if len(inner) == 0 {
+			// <div><br/></div> is how Evernote writes an empty line.
+			return []mdBlock{{}}
+		}
+		return inner
+	case "p":
+		return []mdBlock{{text: joinBlocks(c.blocks(n.children))}}
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		level := int(n.name[1] - '0')
+		text := strings.TrimSpace(strings.ReplaceAll(c.inlineChildren(n), "\n", " "))
+		if text == "" {
+			return nil
+		}
+		return []mdBlock{{text: strings.Repeat("#", level) + " " + text}}
+	case "ul", "ol":
+		return []mdBlock{{text: c.list(n)}}
+	case "li":
+		return []mdBlock{{text: joinLines(c.blocks(n.children)), line: true}}
+	case "pre":
+		return []mdBlock{{text: "```\n" + strings.Trim(textContent(n), "\n") + "\n```"}}
+	case "blockquote":
+		inner := joinBlocks(c.blocks(n.children))
+		lines := strings.Split(inner, "\n")
+		for i, l := range lines {
+			lines[i] = strings.TrimRight("> "+l, " ")
+		}
+		return []mdBlock{{text: strings.Join(lines, "\n")}}
+	case "hr":
+		return []mdBlock{{text: "---"}}
+	case "table":
+		return []mdBlock{{text: c.table(n)}}
+	}
+	return c.blocks(n.children)
+}
+
+// joinLines joins blocks with single newlines, as in a list item.
+func joinLines(blocks []mdBlock) string {
+	var lines []string
+	for _, b := range blocks {
+		if b.text != "" {
+			lines = append(lines, b.text)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (c *enmlConverter) list(n *enmlNode) string {
+	c.listDepth++
+	defer func() { c.listDepth-- }()
+
+	var items []string
+	num := 1
+	for _, child := range n.children {
+		if child.name != "li" {
+			continue
+		}
+		marker := "- "
+		if n.name == "ol" {
+			marker = strconv.Itoa(num) + ". "
+			num++
+		}
+		text := joinLines(c.blocks(child.children))
+		lines := strings.Split(text, "\n")
+		for i := 1; i < len(lines); i++ {
+			lines[i] = strings.Repeat(" ", len(marker)) + lines[i]
+		}
+		items = append(items, marker+strings.Join(lines, "\n"))
+	}
+	return strings.Join(items, "\n")
+}
+
+func (c *enmlConverter) table(n *enmlNode) string {
+	var rows [][]string
+	var collect func(n *enmlNode)
+	collect = func(n *enmlNode) {
+		for _, child := range n.children {
+			switch child.name {
+			case "tr":
+				var cells []string
+				for _, cell := range child.children {
+					if cell.name == "td" || cell.name == "th" {
+						text := strings.TrimSpace(strings.ReplaceAll(c.inlineChildren(cell), "\n", " "))
+						cells = append(cells, strings.ReplaceAll(text, "|", `\|`))
+					}
+				}
+				rows = append(rows, cells)
+			case "thead", "tbody", "tfoot":
+				collect(child)
+			}
+		}
+	}
+	collect(n)
+	if len(rows) == 0 {
+		return ""
+	}
+
+	width := 0
+	for _, r := range rows {
+		width = max(width, len(r))
+	}
+	var b strings.Builder
+	for i, r := range rows {
+		for len(r) < width {
+			r = append(r, "")
+		}
+		b.WriteString("| " + strings.Join(r, " | ") + " |\n")
+		if i == 0 {
+			b.WriteString(strings.Repeat("| --- ", width) + "|\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+func (c *enmlConverter) inlineChildren(n *enmlNode) string {
+	var b strings.Builder
+	for _, child := range n.children {
+		b.WriteString(c.inline(child))
+	}
+	return b.String()
+}
+
+// inline converts a node inside a line. Whitespace is collapsed as in
+// HTML.
+func (c *enmlConverter) inline(n *enmlNode) string {
+	if n.name == "" {
+		return collapseSpace(n.text)
+	}
+
+	switch n.name {
+	case "br":
+		return "\n"
+	case "b", "strong":
+		return wrapInline(c.inlineChildren(n), "**")
+	case "i", "em":
+		return wrapInline(c.inlineChildren(n), "*")
+	case "s", "strike", "del":
+		return wrapInline(c.inlineChildren(n), "~~")
+	case "code", "tt":
+		return wrapInline(textContent(n), "`")
+	case "a":
+		text := c.inlineChildren(n)
+		href := n.attrs["href"]
+		if href == "" || strings.HasPrefix(href, "evernote:") {
+			return text
+		}
+		if strings.TrimSpace(text) == "" || strings.TrimSpace(text) == href {
+			return "<" + href + ">"
+		}
+		return "[" + strings.TrimSpace(text) + "](" + href + ")"
+	case "img":
+		return "![" + n.attrs["alt"] + "](" + n.attrs["src"] + ")"
+	case "en-todo":
+		prefix := "- "
+		if c.listDepth > 0 {
+			prefix = ""
+		}
+		if n.attrs["checked"] == "true" {
+			return prefix + "[x] "
+		}
+		return prefix + "[ ] "
+	case "en-media":
+		if c.media != nil {
+			if target, ok := c.media(n.attrs["hash"]); ok {
+				return resourceLink(target)
+			}
+		}
+		return ""
+	case "en-crypt":
+		return "[encrypted content]"
+	}
+
+	if isENMLBlock(n.name) {
+		return "\n" + joinBlocks(c.block(n)) + "\n"
+	}
+	return c.inlineChildren(n)
+}
+
+// wrapInline wraps text in delim, keeping surrounding spaces outside.
+func wrapInline(text, delim string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	lead := text[:len(text)-len(strings.TrimLeft(text, " \n"))]
+	trail := text[len(strings.TrimRight(text, " \n")):]
+	return lead + delim + trimmed + delim + trail
+}
+
+// collapseSpace replaces runs of whitespace with a single space.
+func collapseSpace(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		if r == ' ' || r == '\t' || r == '\n' || r == '\r' {
+			if !space {
+				b.WriteByte(' ')
+			}
+			space = true
+			continue
+		}
+		space = false
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func textContent(n *enmlNode) string {
+	if n.name == "" {
+		return n.text
+	}
+	if n.name == "br" {
+		return "\n"
+	}
+	var b strings.Builder
+	for _, child := range n.children {
+		b.WriteString(textContent(child))
+		if child.name == "div" || child.name == "p" {
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,197 @@
+package gonotes
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestImportENEX(t *testing.T) {
+	png := []byte("fake png")
+	pdf := []byte("fake pdf")
+	sum := md5.Sum(png)
+	pngHash := hex.EncodeToString(sum[:])
+
+	enex := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE en-export SYSTEM "http://xml.evernote.com/pub/evernote-export3.dtd">
+<en-export application="Evernote">
+  <note>
+    <title>Trip &amp; plans</title>
+    <content><![CDATA[<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE en-note SYSTEM "http://xml.evernote.com/pub/enml2.dtd">
+<en-note><h1>Packing</h1><div>First <b>bold </b>line</div><div>second <a href="https://example.com">link</a></div><div><br/></div>
+<ul><li><div><en-todo checked="true"/>passport</div></li><li>tickets<ol><li>train</li></ol></li></ul>
+<div><en-media type="image/png" hash="` + pngHash + `"/></div><hr/><table><tr><th>a</th><th>b</th></tr><tr><td>1</td><td>2|3</td></tr></table>
+<div><en-todo/>book hotel</div></en-note>]]></content>
+    <created>20260328T120000Z</created>
+    <tag>travel</tag>
+    <tag>to do</tag>
+    <note-attributes><source-url>https://example.com/trip</source-url></note-attributes>
+    <resource>
+      <data encoding="base64">` + base64.StdEncoding.EncodeToString(png) + `</data>
+      <mime>image/png</mime>
+      <resource-attributes><file-name>map.png</file-name></resource-attributes>
+    </resource>
+    <resource>
+      <data encoding="base64">
+` + base64.StdEncoding.EncodeToString(pdf) + `
+      </data>
+      <mime>application/pdf</mime>
+    </resource>
+  </note>
+  <note>
+    <title>Second</title>
+    <content><![CDATA[<en-note><div>Plain.</div></en-note>]]></content>
+    <created>20260328T130000Z</created>
+  </note>
+</en-export>`
+
+	created := time.Date(2026, 3, 28, 12, 0, 0, 0, time.UTC).Local()
+	prefix := created.Format("20060102")
+	baseDir := t.TempDir()
+	// The note and its folder share one ID, numbered after both existing
+	// notes and existing folders.
+	writeTestNote(t, filepath.Join(baseDir, "notes", "by", "id"), prefix+"-1-existing.md", "---\ntitle: Existing\n---\n")
+	if err := os.MkdirAll(filepath.Join(baseDir, "files", prefix+"-2-old"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	dry, err := ImportENEX(baseDir, nil, strings.NewReader(enex), true)
+	if err != nil {
+		t.Fatalf("ImportENEX(dry run) err = %q", err)
+	}
+	if _, err := os.Stat(filepath.Join(baseDir, "notes", "by", "tags")); !os.IsNotExist(err) {
+		t.Errorf("dry run wrote notes: %v", err)
+	}
+
+	imp, err := ImportENEX(baseDir, nil, strings.NewReader(enex), false)
+	if err != nil {
+		t.Fatalf("ImportENEX() err = %q", err)
+	}
+
+	folder := prefix + "-3-trip-plans"
+	want := &ENEXImport{
+		Notes: []ImportedNote{
+			{Source: "Trip & plans", Filename: prefix + "-3-trip-plans.md", Title: "Trip & plans", Tags: []string{"travel", "to-do"}},
+			{Source: "Second", Filename: prefix + "-4-second.md", Title: "Second"},
+		},
+		Attachments: []ImportedFile{
+			{Source: "map.png", Dest: folder + "/map.png"},
+			{Source: "resource-2.pdf", Dest: folder + "/resource-2.pdf"},
+		},
+	}
+	opt := cmp.AllowUnexported(ImportedNote{})
+	ignoreNote := cmp.FilterPath(func(p cmp.Path) bool { return p.Last().String() == ".note" }, cmp.Ignore())
+	if diff := cmp.Diff(want, imp, opt, ignoreNote); diff != "" {
+		t.Errorf("ImportENEX() diff (-want, +got):\n%s", diff)
+	}
+	if diff := cmp.Diff(imp, dry, opt, ignoreNote); diff != "" {
+		t.Errorf("dry run differs from import (-import, +dry run):\n%s", diff)
+	}
+
+	data, err := os.ReadFile(filepath.Join(baseDir, "notes", "by", "id", prefix+"-3-trip-plans.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantBody := `
+# Packing
+
+First **bold** line
+second [link](https://example.com)
+
+- [x] passport
+- tickets
+  1. train
+
+![[` + folder + `/map.png]]
+
+---
+
+| a | b |
+| --- | --- |
+| 1 | 2\|3 |
+
+- [ ] book hotel
+
+[[` + folder + `/resource-2.pdf]]`
+	_, body, _ := strings.Cut(string(data), "\n---\n")
+	if diff := cmp.Diff(wantBody, body); diff != "" {
+		t.Errorf("note body diff (-want, +got):\n%s", diff)
+	}
+	for _, want := range []string{"title: Trip & plans", "tags: travel, to-do", "source: https://example.com/trip", "date: " + created.Format(dateLayout)} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("note missing %q:\n%s", want, data)
+		}
+	}
+
+	if got, err := os.ReadFile(filepath.Join(baseDir, "files", folder, "resource-2.pdf")); err != nil || string(got) != string(pdf) {
+		t.Errorf("resource not decoded: %q, %v", got, err)
+	}
+	if _, err := os.Lstat(filepath.Join(baseDir, "notes", "by", "tags", "to-do", prefix+"-3-trip-plans.md")); err != nil {
+		t.Errorf("tag symlink missing: %v", err)
+	}
+
+	report, err := ScanNotes(baseDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.BrokenLinks) > 0 || len(report.Renames) > 0 || len(report.Errors) > 0 {
+		t.Errorf("ScanNotes() report = %s, want no issues", report)
+	}
+}
+
+func TestImportENEXReturnsImportedNotesOnError(t *testing.T) {
+	enex := `<en-export>
+  <note>
+    <title>First</title>
+    <content><![CDATA[<en-note><div>Kept.</div></en-note>]]></content>
+    <created>20260328T120000Z</created>
+  </note>
+  <note>
+    <title>Truncated`
+
+	baseDir := t.TempDir()
+	imp, err := ImportENEX(baseDir, nil, strings.NewReader(enex), false)
+	if err == nil {
+		t.Fatal("ImportENEX() err = nil, want error")
+	}
+	if imp == nil || len(imp.Notes) != 1 || imp.Notes[0].Title != "First" {
+		t.Fatalf("ImportENEX() import = %v, want the first note", imp)
+	}
+	if _, err := os.Stat(filepath.Join(baseDir, "notes", "by", "id", imp.Notes[0].Filename)); err != nil {
+		t.Errorf("imported note missing: %v", err)
+	}
+}
+
+func TestResourceFilename(t *testing.T) {
+	tests := []struct {
+		name string
+		res  enexResource
+		want string
+	}{
+		{"plain", enexResource{FileName: "map.png"}, "map.png"},
+		{"path", enexResource{FileName: `a/b\c.png`}, "a-b-c.png"},
+		{"link syntax", enexResource{FileName: "Q1 [final] report #2|v^3.pdf"}, "Q1 -final- report -2-v-3.pdf"},
+		{"no name", enexResource{Mime: "application/pdf"}, "resource-1.pdf"},
+		{"hidden", enexResource{FileName: ".env", Mime: "image/png"}, "resource-1.png"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := resourceFilename(tt.res, 1, map[string]bool{})
+			if got != tt.want {
+				t.Errorf("resourceFilename() = %q, want %q", got, tt.want)
+			}
+			target := "20260328-1-note/" + got
+			if link := ParseWikiLink(target); link.Target != target {
+				t.Errorf("ParseWikiLink(%q).Target = %q", target, link.Target)
+			}
+		})
+	}
+}
@@ -0,0 +1,603 @@
+package gonotes
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ExportReport summarizes an HTML export.
+type ExportReport struct {
+	// Notes is the number of note pages written.
+	Notes int `json:"notes"`
+	// Files lists the linked files copied, relative to the files dir.
+	Files       []string     `json:"files"`
+	BrokenLinks []BrokenLink `json:"broken_links"`
+	Errors      []ScanError  `json:"errors"`
+}
+
+// MarshalJSON encodes the report with empty lists rather than null.
+func (r ExportReport) MarshalJSON() ([]byte, error) {
+	type report ExportReport
+	out := report(r)
+	out.Files = nonNil(out.Files)
+	out.BrokenLinks = nonNil(out.BrokenLinks)
+	out.Errors = nonNil(out.Errors)
+	return json.Marshal(out)
+}
+
+func (r *ExportReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Exported %d notes and %d files.\n", r.Notes, len(r.Files))
+
+	if len(r.BrokenLinks) > 0 {
+		fmt.Fprintf(&b, "Broken links (%d):\n", len(r.BrokenLinks))
+		for _, bl := range r.BrokenLinks {
+			fmt.Fprintf(&b, "  %s -> %s\n", bl.SourceID, bl.TargetID)
+		}
+	}
+
+	if len(r.Errors) > 0 {
+		fmt.Fprintf(&b, "Errors (%d):\n", len(r.Errors))
+		for _, e := range r.Errors {
+			fmt.Fprintf(&b, "  %s: %s\n", e.Filename, e.Message)
+		}
+	}
+
+	return b.String()
+}
+
+// exportNote is a note as rendered by ExportHTML.
+type exportNote struct {
+	ID    string
+	Title string
+	Stem  string
+	Date  time.Time
+	Day   string
+	Tags  []string
+	Body  template.HTML
+
+	Backlinks []*exportNote
+
+	anchors noteAnchors
+}
+
+// exportTag is a node in the hierarchical tag index. Notes holds the notes
+// tagged with the tag itself or any tag below it.
+type exportTag struct {
+	Path     string
+	Name     string
+	Children []*exportTag
+	Notes    []*exportNote
+}
+
+// ExportHTML renders the vault at baseDir as a static, read-only website in
+// outDir. Every note becomes notes/<id-slug>.html with its [[links]]
+// resolved to relative page links; links to files under the files dir are
+// copied to files/ and linked there. Links that resolve to nothing render
+// with the broken-link class. Index pages are written for all notes, for
+// tags (one page per level of the tag hierarchy) and for dates.
+//
+// outDir must not exist yet, be empty or hold a previous export. The site
+// is built in a temporary directory next to it and then swapped into
+// place, so pages of deleted notes and tags do not survive a re-export.
+func ExportHTML(baseDir string, cfg *Config, outDir string) (*ExportReport, error) {
+	cfg = cfg.orDefault()
+	if err := checkExportDir(baseDir, cfg, outDir); err != nil {
+		return nil, fmt.Errorf("export html: %w", err)
+	}
+
+	files, errs, err := readNoteFiles(cfg.idDir(baseDir), cfg)
+	if err != nil {
+		return nil, fmt.Errorf("export html: %w", err)
+	}
+	report := &ExportReport{Errors: errs}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Filename < files[j].Filename })
+
+	notes := map[string]*exportNote{}
+	var order []*exportNote
+	var sources []*noteFile
+	ids := map[string]struct{}{}
+	names := noteNames{}
+	graph := newLinkGraph()
+	for i := range files {
+		nf := &files[i]
+		id, ok := IDFromFilename(nf.Filename)
+		if !ok {
+			report.Errors = append(report.Errors, ScanError{
+				Filename: nf.Filename,
+				Message:  "cannot determine note ID; run rebuild first",
+			})
+			continue
+		}
+		if _, dup := notes[id]; dup {
+			report.Errors = append(report.Errors, ScanError{
+				Filename: nf.Filename,
+				Message:  fmt.Sprintf("duplicate note ID %q", id),
+			})
+			continue
+		}
+
+		n := &exportNote{
+			ID:    id,
+			Title: nf.Title,
+			Stem:  strings.TrimSuffix(nf.Filename, ".md"),
+			Tags:  nf.Tags,
+
+			anchors: parseAnchors(nf.Body),
+		}
+		if n.Title == "" {
+			n.Title = id
+		}
+		if !nf.Date.IsZero() {
+			n.Date = nf.Date
+			n.Day = wallClock(nf.Date).Format("2006-01-02")
+		}
+		notes[id] = n
+		ids[id] = struct{}{}
+		names.add(id, &nf.Note)
+		order = append(order, n)
+		sources = append(sources, nf)
+		graph.addNote(id, nf.Title, nf.Body)
+	}
+	graph.resolveNames(ids, names)
+	graph.sort()
+
+	filesDir := cfg.filesDir(baseDir)
+	copied := map[string]struct{}{}
+	for i, n := range order {
+		nf := sources[i]
+		md := &markdownRenderer{
+			wikiLink: func(target string, embed bool) string {
+				out, ok := exportLink(target, embed, "../", n, notes, names, filesDir, copied)
+				if !ok && !linkIgnored(target, nf.IgnoreLinks) {
+					report.BrokenLinks = append(report.BrokenLinks, BrokenLink{
+						SourceID: n.ID,
+						TargetID: brokenLinkTarget(target),
+					})
+				}
+				return out
+			},
+		}
+		n.Body = template.HTML(md.render(nf.Body))
+
+		seen := map[string]struct{}{}
+		for _, ref := range graph.Backlinks(n.ID) {
+			src, ok := notes[ref.SourceID]
+			if _, dup := seen[ref.SourceID]; ok && !dup && src != n {
+				seen[ref.SourceID] = struct{}{}
+				n.Backlinks = append(n.Backlinks, src)
+			}
+		}
+	}
+
+	// Newest first on every index page.
+	sort.SliceStable(order, func(i, j int) bool { return order[i].ID > order[j].ID })
+
+	if err := os.MkdirAll(filepath.Dir(filepath.Clean(outDir)), 0o755); err != nil {
+		return nil, fmt.Errorf("export html: %w", err)
+	}
+	tmpDir, err := os.MkdirTemp(filepath.Dir(filepath.Clean(outDir)), "."+filepath.Base(outDir)+".tmp-")
+	if err != nil {
+		return nil, fmt.Errorf("export html: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	w := &exportWriter{outDir: tmpDir}
+	for _, n := range order {
+		w.page(path.Join("notes", n.Stem+".html"), "note", n.Title, n)
+	}
+	w.page("index.html", "notes", "All notes", order)
+
+	root := buildTagTree(order)
+	w.page(path.Join("tags", "index.html"), "tags", "Tags", root)
+	var writeTag func(t *exportTag)
+	writeTag = func(t *exportTag) {
+		w.page(path.Join("tags", t.Path, "index.html"), "tag", "#"+t.Path, t)
+		for _, c := range t.Children {
+			writeTag(c)
+		}
+	}
+	for _, t := range root.Children {
+		writeTag(t)
+	}
+
+	days := map[string][]*exportNote{}
+	var dayOrder []string
+	for _, n := range order {
+		if n.Day == "" {
+			continue
+		}
+		if _, ok := days[n.Day]; !ok {
+			dayOrder = append(dayOrder, n.Day)
+		}
+		days[n.Day] = append(days[n.Day], n)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(dayOrder)))
+	type dayEntry struct {
+		Day   string
+		Notes []*exportNote
+	}
+	var dayIndex []dayEntry
+	for _, d := range dayOrder {
+		dayIndex = append(dayIndex, dayEntry{Day: d, Notes: days[d]})
+		w.page(path.Join("dates", d+".html"), "day", d, dayEntry{Day: d, Notes: days[d]})
+	}
+	w.page(path.Join("dates", "index.html"), "dates", "Dates", dayIndex)
+	w.file("style.css", []byte(exportCSS))
+	w.file(exportMarker, nil)
+
+	for target := range copied {
+		report.Files = append(report.Files, target)
+	}
+	sort.Strings(report.Files)
+	for _, target := range report.Files {
+		if w.err != nil {
+			break
+		}
+		w.err = copyTree(filepath.Join(filesDir, filepath.FromSlash(target)), filepath.Join(tmpDir, "files", filepath.FromSlash(target)))
+	}
+
+	if w.err == nil {
+		w.err = os.Chmod(tmpDir, 0o755)
+	}
+	if w.err == nil {
+		w.err = replaceDir(tmpDir, outDir)
+	}
+	if w.err != nil {
+		return nil, fmt.Errorf("export html: %w", w.err)
+	}
+	report.Notes = len(order)
+	return report, nil
+}
+
+// exportMarker is the file ExportHTML leaves in its output dir, marking it
+// as a previous export that may be replaced.
+const exportMarker = ".gonotes-export"
+
+// replaceDir moves dir to dst, replacing what was there. An existing dst is
+// moved aside first and only removed once dir is in place; it is restored
+// if that fails.
+func replaceDir(dir, dst string) error {
+	if _, err := os.Lstat(dst); errors.Is(err, fs.ErrNotExist) {
+		return os.Rename(dir, dst)
+	}
+	old := dir + "-old"
+	if err := os.Rename(dst, old); err != nil {
+		return err
+	}
+	if err := os.Rename(dir, dst); err != nil {
+		return errors.Join(err, os.Rename(old, dst))
+	}
+	return os.RemoveAll(old)
+}
+
+// checkExportDir refuses output directories that would mix generated pages
+// into the vault itself, and existing ones that hold anything but a
+// previous export, which ExportHTML replaces.
+func checkExportDir(baseDir string, cfg *Config, outDir string) error {
+	absOut, err := filepath.Abs(outDir)
+	if err != nil {
+		return err
+	}
+	absBase, err := filepath.Abs(baseDir)
+	if err != nil {
+		return err
+	}
+	for _, dir := range []string{
+		absBase,
+		filepath.Join(absBase, "notes"),
+		filepath.Join(absBase, stateDirName),
+		filepath.Join(absBase, cfg.IDDir),
+		filepath.Join(absBase, cfg.FilesDir),
+	} {
+		if rel, err := filepath.Rel(dir, absOut); err == nil && (rel == "." || dir != absBase && filepath.IsLocal(rel)) {
+			return fmt.Errorf("output dir %s is the vault root or inside its notes, files or state", outDir)
+		}
+	}
+
+	entries, err := os.ReadDir(outDir)
+	if errors.Is(err, fs.ErrNotExist) || err == nil && len(entries) == 0 {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(filepath.Join(outDir, exportMarker)); err != nil {
+		return fmt.Errorf("output dir %s is not empty and holds no previous export", outDir)
+	}
+	return nil
+}
+
+// exportLink renders the [[link]] text raw for page self at depth root.
+// Note links, by ID or by a title or alias in names that only one note
+// uses, point at the note's page, or the heading on it, and show the
+// link's display text or else the note's title. Links to files under
+// filesDir are recorded in copied and point at the copy. Anything else,
+// including a link to a heading or block the note lacks, renders as a
+// broken link and ok is false.
+func exportLink(raw string, embed bool, root string, self *exportNote, notes map[string]*exportNote, names noteNames, filesDir string, copied map[string]struct{}) (out string, ok bool) {
+	link := ParseWikiLink(raw)
+	target := link.Target
+	if strings.Contains(target, "/") && filepath.IsLocal(filepath.FromSlash(target)) {
+		if _, err := os.Stat(filepath.Join(filesDir, filepath.FromSlash(target))); err == nil {
+			copied[target] = struct{}{}
+			href := html.EscapeString(root + "files/" + escapePath(target))
+			if embed && isImagePath(target) {
+				return `<img src="` + href + `" alt="` + html.EscapeString(path.Base(target)) + `">`, true
+			}
+			text := target
+			if link.Text != "" {
+				text = link.Text
+			}
+			return `<a class="file-link" href="` + href + `">` + html.EscapeString(text) + "</a>", true
+		}
+	}
+
+	// Not a file, so a note, whose title may contain a slash too.
+	n, exists := notes[linkTargetID(raw)]
+	if ids := names.lookup(target); !exists && len(ids) == 1 {
+		n, exists = notes[ids[0]]
+	}
+	text := link.Text
+	if target == "" {
+		// A link into the page itself is named after its heading.
+		n, exists = self, true
+		if text == "" {
+			text = link.Anchor
+		}
+	}
+	if exists && n.anchors.has(link) {
+		href := root + "notes/" + url.PathEscape(n.Stem) + ".html"
+		if link.Anchor != "" {
+			headings := strings.Split(link.Anchor, "#")
+			href += "#" + headingID(headings[len(headings)-1])
+		}
+		if text == "" {
+			text = n.Title
+		}
+		return `<a href="` + html.EscapeString(href) + `">` + html.EscapeString(text) + "</a>", true
+	}
+	return `<span class="broken-link" title="Broken link">[[` + html.EscapeString(raw) + "]]</span>", false
+}
+
+func isImagePath(p string) bool {
+	switch strings.ToLower(path.Ext(p)) {
+	case ".png", ".jpg", ".jpeg", ".gif", ".svg", ".webp":
+		return true
+	}
+	return false
+}
+
+// escapePath escapes each segment of a slash-separated path for use in a
+// URL.
+func escapePath(p string) string {
+	parts := strings.Split(p, "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return strings.Join(parts, "/")
+}
+
+// buildTagTree returns the root of the tag hierarchy for notes. Tags whose
+// path would leave the tags directory are left out.
+func buildTagTree(notes []*exportNote) *exportTag {
+	root := &exportTag{}
+	byPath := map[string]*exportTag{}
+	for _, n := range notes {
+		seen := map[*exportTag]struct{}{}
+		for _, tag := range n.Tags {
+			if !filepath.IsLocal(filepath.FromSlash(tag)) {
+				continue
+			}
+			parent := root
+			parts := strings.Split(tag, "/")
+			for i := range parts {
+				p := strings.Join(parts[:i+1], "/")
+				t, ok := byPath[p]
+				if !ok {
+					t = &exportTag{Path: p, Name: parts[i]}
+					byPath[p] = t
+					parent.Children = append(parent.Children, t)
+				}
+				if _, dup := seen[t]; !dup {
+					seen[t] = struct{}{}
+					t.Notes = append(t.Notes, n)
+				}
+				parent = t
+			}
+		}
+	}
+	var sortTags func(t *exportTag)
+	sortTags = func(t *exportTag) {
+		sort.Slice(t.Children, func(i, j int) bool { return t.Children[i].Name < t.Children[j].Name })
+		for _, c := range t.Children {
+			sortTags(c)
+		}
+	}
+	sortTags(root)
+	return root
+}
+
+// copyTree copies the file or directory at src to dst.
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		return copyFile(p, target)
+	})
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// exportWriter writes the pages of an export, keeping the first error.
+type exportWriter struct {
+	outDir string
+	err    error
+}
+
+// page renders the named template into the page at rel, a slash-separated
+// path relative to the output dir.
+func (w *exportWriter) page(rel, name, title string, data any) {
+	if w.err != nil {
+		return
+	}
+	root := strings.Repeat("../", strings.Count(rel, "/"))
+	var buf bytes.Buffer
+	err := exportTemplates.ExecuteTemplate(&buf, name, struct {
+		Root  string
+		Title string
+		Data  any
+	}{root, title, data})
+	if err != nil {
+		w.err = fmt.Errorf("render %s: %w", rel, err)
+		return
+	}
+	w.file(rel, buf.Bytes())
+}
+
+func (w *exportWriter) file(rel string, data []byte) {
+	if w.err != nil {
+		return
+	}
+	p := filepath.Join(w.outDir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		w.err = err
+		return
+	}
+	if err := os.WriteFile(p, data, 0o644); err != nil {
+		w.err = fmt.Errorf("write %s: %w", rel, err)
+	}
+}
+
+var exportTemplates = template.Must(template.New("export").Funcs(template.FuncMap{
+	"noteURL": func(root string, n *exportNote) string {
+		return root + "notes/" + url.PathEscape(n.Stem) + ".html"
+	},
+	"tagURL": func(root, tag string) string {
+		return root + "tags/" + escapePath(tag) + "/index.html"
+	},
+	"dayURL": func(root, day string) string {
+		return root + "dates/" + day + ".html"
+	},
+	"listData": func(root string, notes []*exportNote) any {
+		return struct {
+			Root  string
+			Notes []*exportNote
+		}{root, notes}
+	},
+}).Parse(`
+{{define "header"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="{{.Root}}style.css">
+</head>
+<body>
+<nav><a href="{{.Root}}index.html">Notes</a> <a href="{{.Root}}tags/index.html">Tags</a> <a href="{{.Root}}dates/index.html">Dates</a></nav>
+<main>
+{{end}}
+
+{{define "footer"}}</main>
+</body>
+</html>
+{{end}}
+
+{{define "list"}}{{$root := .Root}}<ul class="notes">
+{{range .Notes}}<li><a href="{{noteURL $root .}}">{{.Title}}</a>{{if .Day}} <span class="date">{{.Day}}</span>{{end}}</li>
+{{end}}</ul>
+{{end}}
+
+{{define "note"}}{{template "header" .}}{{$root := .Root}}{{with .Data}}<article>
+<h1 class="title">{{.Title}}</h1>
+<p class="meta"><span class="id">{{.ID}}</span>{{if .Day}} · <a href="{{dayURL $root .Day}}">{{.Date.Format "2006-01-02 15:04"}}</a>{{end}}{{range .Tags}} <a class="tag" href="{{tagURL $root .}}">#{{.}}</a>{{end}}</p>
+{{.Body}}</article>
+{{if .Backlinks}}<section class="backlinks">
+<h2>Linked from</h2>
+{{template "list" (listData $root .Backlinks)}}</section>
+{{end}}{{end}}{{template "footer" .}}{{end}}
+
+{{define "notes"}}{{template "header" .}}<h1>{{.Title}}</h1>
+{{template "list" (listData .Root .Data)}}{{template "footer" .}}{{end}}
+
+{{define "tags"}}{{template "header" .}}{{$root := .Root}}<h1>{{.Title}}</h1>
+<ul class="tags">
+{{range .Data.Children}}<li><a href="{{tagURL $root .Path}}">#{{.Path}}</a> <span class="count">{{len .Notes}}</span></li>
+{{end}}</ul>
+{{template "footer" .}}{{end}}
+
+{{define "tag"}}{{template "header" .}}{{$root := .Root}}<h1>{{.Title}}</h1>
+{{with .Data.Children}}<ul class="tags">
+{{range .}}<li><a href="{{tagURL $root .Path}}">#{{.Path}}</a> <span class="count">{{len .Notes}}</span></li>
+{{end}}</ul>
+{{end}}{{template "list" (listData $root .Data.Notes)}}{{template "footer" .}}{{end}}
+
+{{define "dates"}}{{template "header" .}}{{$root := .Root}}<h1>{{.Title}}</h1>
+<ul class="dates">
+{{range .Data}}<li><a href="{{dayURL $root .Day}}">{{.Day}}</a> <span class="count">{{len .Notes}}</span></li>
+{{end}}</ul>
+{{template "footer" .}}{{end}}
+
+{{define "day"}}{{template "header" .}}<h1>{{.Title}}</h1>
+{{template "list" (listData .Root .Data.Notes)}}{{template "footer" .}}{{end}}
+`))
+
+const exportCSS = `body { font: 16px/1.5 system-ui, sans-serif; max-width: 46rem; margin: 0 auto; padding: 1rem; color: #222; }
+nav { margin-bottom: 1.5rem; } nav a { margin-right: 1rem; }
+a { color: #0b5cad; }
+.meta, .date, .count, .id { color: #666; font-size: 0.9em; }
+.tag { margin-left: 0.3rem; }
+.broken-link { color: #b00020; text-decoration: line-through wavy; }
+pre { background: #f5f5f5; padding: 0.75rem; overflow-x: auto; }
+code { font-family: ui-monospace, monospace; }
+blockquote { border-left: 3px solid #ccc; margin-left: 0; padding-left: 1rem; color: #555; }
+table { border-collapse: collapse; } th, td { border: 1px solid #ccc; padding: 0.25rem 0.5rem; }
+img { max-width: 100%; }
+.backlinks { border-top: 1px solid #ddd; margin-top: 2rem; }
+`
@@ -0,0 +1,165 @@
+package gonotes
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExportHTML(t *testing.T) {
+	baseDir := t.TempDir()
+	idDir := filepath.Join(baseDir, "notes", "by", "id")
+
+	writeTestNote(t, idDir, "20260328-1-target.md", `---
+title: Target <note>
+aliases: [target, Q1/Q2]
+date: 2026-03-28 14:30:00
+tags: project/alpha
+---
+
+# Hello
+
+Nothing here.`)
+
+	writeTestNote(t, idDir, "20260329-1-source.md", `---
+title: Source
+date: 2026-03-29 09:00:00
+tags: project, reading
+ignore-links: draft-*
+---
+
+See [[20260328-1]] and [[20260403-1-slides/deck.pdf]].
+Also [[20260328-1#Hello|the greeting]], [[Target|by alias]] and [[Q1/Q2|the plan]].
+Missing [[20991231-9]], [[20260328-1#Bye]] and [[draft-idea]].`)
+
+	writeTestNote(t, filepath.Join(baseDir, "files", "20260403-1-slides"), "deck.pdf", "pdf")
+
+	outDir := filepath.Join(t.TempDir(), "site")
+	report, err := ExportHTML(baseDir, nil, outDir)
+	if err != nil {
+		t.Fatalf("ExportHTML() err = %q", err)
+	}
+
+	want := &ExportReport{
+		Notes: 2,
+		Files: []string{"20260403-1-slides/deck.pdf"},
+		BrokenLinks: []BrokenLink{
+			{SourceID: "20260329-1", TargetID: "20991231-9"},
+			{SourceID: "20260329-1", TargetID: "20260328-1#Bye"},
+		},
+	}
+	if diff := cmp.Diff(want, report); diff != "" {
+		t.Errorf("ExportHTML() report diff (-want, +got):\n%s", diff)
+	}
+
+	pages := map[string][]string{
+		"index.html": {
+			`href="notes/20260329-1-source.html">Source</a>`,
+			`href="notes/20260328-1-target.html">Target &lt;note&gt;</a>`,
+		},
+		"notes/20260329-1-source.html": {
+			`<a href="../notes/20260328-1-target.html">Target &lt;note&gt;</a>`,
+			`<a class="file-link" href="../files/20260403-1-slides/deck.pdf">`,
+			`<a href="../notes/20260328-1-target.html#hello">the greeting</a>`,
+			`<a href="../notes/20260328-1-target.html">by alias</a>`,
+			`<a href="../notes/20260328-1-target.html">the plan</a>`,
+			`<span class="broken-link" title="Broken link">[[20991231-9]]</span>`,
+			`<span class="broken-link" title="Broken link">[[20260328-1#Bye]]</span>`,
+			`<a class="tag" href="../tags/reading/index.html">#reading</a>`,
+			`href="../dates/2026-03-29.html"`,
+		},
+		"notes/20260328-1-target.html": {
+			`<h1 id="hello">Hello</h1>`,
+			"<h2>Linked from</h2>",
+			`href="../notes/20260329-1-source.html">Source</a>`,
+		},
+		"tags/index.html": {
+			`href="../tags/project/index.html">#project</a> <span class="count">2</span>`,
+			`href="../tags/reading/index.html">#reading</a> <span class="count">1</span>`,
+		},
+		"tags/project/index.html": {
+			`href="../../tags/project/alpha/index.html">#project/alpha</a>`,
+			`href="../../notes/20260328-1-target.html"`,
+			`href="../../notes/20260329-1-source.html"`,
+		},
+		"tags/project/alpha/index.html": {
+			`href="../../../notes/20260328-1-target.html"`,
+		},
+		"dates/index.html": {
+			`href="../dates/2026-03-29.html">2026-03-29</a>`,
+			`href="../dates/2026-03-28.html">2026-03-28</a>`,
+		},
+		"dates/2026-03-28.html": {
+			`href="../notes/20260328-1-target.html"`,
+		},
+	}
+	for page, wants := range pages {
+		data, err := os.ReadFile(filepath.Join(outDir, filepath.FromSlash(page)))
+		if err != nil {
+			t.Errorf("page %s not written: %v", page, err)
+			continue
+		}
+		for _, w := range wants {
+			if !strings.Contains(string(data), w) {
+				t.Errorf("page %s missing %q:\n%s", page, w, data)
+			}
+		}
+	}
+
+	if data, err := os.ReadFile(filepath.Join(outDir, "files", "20260403-1-slides", "deck.pdf")); err != nil || string(data) != "pdf" {
+		t.Errorf("attachment not copied: %q, %v", data, err)
+	}
+	if data, err := os.ReadFile(filepath.Join(outDir, "tags", "project", "alpha", "index.html")); err == nil &&
+		strings.Contains(string(data), "20260329-1-source") {
+		t.Error("tags/project/alpha lists a note tagged only with its parent")
+	}
+
+	t.Run("re-export drops stale pages", func(t *testing.T) {
+		if err := os.Remove(filepath.Join(idDir, "20260328-1-target.md")); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ExportHTML(baseDir, nil, outDir); err != nil {
+			t.Fatalf("ExportHTML() again err = %q", err)
+		}
+		for _, page := range []string{"notes/20260328-1-target.html", "tags/project/alpha/index.html", "dates/2026-03-28.html"} {
+			if _, err := os.Stat(filepath.Join(outDir, filepath.FromSlash(page))); !os.IsNotExist(err) {
+				t.Errorf("stale page %s kept: %v", page, err)
+			}
+		}
+		if _, err := os.Stat(filepath.Join(outDir, "notes", "20260329-1-source.html")); err != nil {
+			t.Errorf("page of remaining note missing: %v", err)
+		}
+		entries, err := os.ReadDir(filepath.Dir(outDir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 {
+			t.Errorf("output parent has %d entries, want only the site", len(entries))
+		}
+	})
+
+	t.Run("refuses dirs with other files", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTestNote(t, dir, "keep.txt", "mine")
+		if _, err := ExportHTML(baseDir, nil, dir); err == nil {
+			t.Error("ExportHTML() into a non-empty dir err = <nil>, want error")
+		}
+		if _, err := os.Stat(filepath.Join(dir, "keep.txt")); err != nil {
+			t.Errorf("existing file touched: %v", err)
+		}
+		if _, err := ExportHTML(baseDir, nil, t.TempDir()); err != nil {
+			t.Errorf("ExportHTML() into an empty dir err = %q", err)
+		}
+	})
+
+	t.Run("refuses vault dirs", func(t *testing.T) {
+		for _, dir := range []string{baseDir, idDir, filepath.Join(baseDir, "notes", "site")} {
+			if _, err := ExportHTML(baseDir, nil, dir); err == nil {
+				t.Errorf("ExportHTML(%s) err = <nil>, want error", dir)
+			}
+		}
+	})
+}
@@ -1,6 +1,12 @@
 package gonotes
 
 import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
 	"gopkg.in/yaml.v3"
 )
 
@@ -50,16 +56,69 @@ func (f *Frontmatter) mappingNode() *yaml.Node {
 	return &f.Node
 }
 
+// valueNode returns the value node for key, or nil if key is not set.
+func (f *Frontmatter) valueNode(key string) *yaml.Node {
+	return mappingValue(f.mappingNode(), key)
+}
+
+// Get returns the scalar value of key. Sequences and mappings yield an
+// empty string; use GetList for multi-value fields.
 func (f *Frontmatter) Get(key string) (string, bool) {
-	mn := f.mappingNode()
+	if n := f.valueNode(key); n != nil {
+		return n.Value, true
+	}
+	return "", false
+}
 
-	for i := 0; i+1 < len(mn.Content); i += 2 {
-		if mn.Content[i].Value == key {
-			return mn.Content[i+1].Value, true
+// GetList returns the values at path as a list. A sequence yields its
+// scalar items, a non-empty scalar a single value, and anything else no
+// values. Paths are dotted as for GetString. It reports false when path is
+// not set.
+func (f *Frontmatter) GetList(path string) ([]string, bool) {
+	n, err := f.lookup(path)
+	if err != nil {
+		return nil, false
+	}
+
+	switch n.Kind {
+	case yaml.SequenceNode:
+		var out []string
+		for _, item := range n.Content {
+			item = resolveAlias(item)
+			if item.Kind == yaml.ScalarNode && item.Value != "" {
+				out = append(out, item.Value)
+			}
+		}
+		return out, true
+	case yaml.ScalarNode:
+		if n.Value == "" {
+			return nil, true
 		}
+		return []string{n.Value}, true
 	}
+	return nil, true
+}
 
-	return "", false
+// SetList sets path to values while keeping the style of the current value:
+// a flow sequence stays a flow sequence, a block sequence stays a block
+// sequence, and a scalar becomes a comma-separated scalar. A new value is
+// written as a block sequence, creating mappings along the way.
+func (f *Frontmatter) SetList(path string, values []string) error {
+	items := make([]*yaml.Node, len(values))
+	for i, v := range values {
+		items[i] = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: v}
+	}
+	value := &yaml.Node{Kind: yaml.SequenceNode, Content: items}
+
+	if cur, err := f.lookup(path); err == nil {
+		switch cur.Kind {
+		case yaml.ScalarNode:
+			value = &yaml.Node{Kind: yaml.ScalarNode, Style: cur.Style, Value: strings.Join(values, ", ")}
+		case yaml.SequenceNode:
+			value.Style = cur.Style
+		}
+	}
+	return f.setNode(path, value)
 }
 
 func (f *Frontmatter) Set(key, value string) {
@@ -105,13 +164,207 @@ func (f *Frontmatter) Keys() []string {
 	return keys
 }
 
-// Map returns all scalar key-value pairs as a map. Non-scalar values are
-// represented by their string Value (which may be empty for sequences/mappings).
+// Map returns all key-value pairs as a map. Sequences are represented by
+// their items joined with ", "; mappings by an empty string.
 func (f *Frontmatter) Map() map[string]string {
 	mn := f.mappingNode()
 	m := make(map[string]string, len(mn.Content)/2)
 	for i := 0; i+1 < len(mn.Content); i += 2 {
-		m[mn.Content[i].Value] = mn.Content[i+1].Value
+		key, value := mn.Content[i].Value, mn.Content[i+1]
+		if value.Kind == yaml.SequenceNode {
+			items, _ := f.GetList(key)
+			m[key] = strings.Join(items, ", ")
+			continue
+		}
+		m[key] = value.Value
 	}
 	return m
 }
+
+// ErrFieldNotFound is returned by the typed accessors when a field, or a
+// mapping on the way to it, is not set.
+var ErrFieldNotFound = errors.New("field not found")
+
+// FieldTypeError is returned by the typed accessors when a field holds a
+// value of a different type than requested.
+type FieldTypeError struct {
+	Path string
+	Want string
+	Got  string
+}
+
+func (e *FieldTypeError) Error() string {
+	return fmt.Sprintf("frontmatter field %s: want %s, got %s", e.Path, e.Want, e.Got)
+}
+
+// GetString returns the scalar at path. Paths are keys separated by dots,
+// so "source.url" is the url key of the source mapping.
+func (f *Frontmatter) GetString(path string) (string, error) {
+	n, err := f.lookupScalar(path, "string")
+	if err != nil {
+		return "", err
+	}
+	return n.Value, nil
+}
+
+// GetInt returns the integer at path.
+func (f *Frontmatter) GetInt(path string) (int, error) {
+	n, err := f.lookupScalar(path, "int")
+	if err != nil {
+		return 0, err
+	}
+	var v int
+	if err := n.Decode(&v); err != nil || isNull(n) {
+		return 0, &FieldTypeError{Path: path, Want: "int", Got: describeNode(n)}
+	}
+	return v, nil
+}
+
+// GetBool returns the boolean at path. Besides true and false, the YAML 1.1
+// forms yes, no, on and off are accepted.
+func (f *Frontmatter) GetBool(path string) (bool, error) {
+	n, err := f.lookupScalar(path, "bool")
+	if err != nil {
+		return false, err
+	}
+	var v bool
+	if err := n.Decode(&v); err != nil || isNull(n) {
+		return false, &FieldTypeError{Path: path, Want: "bool", Got: describeNode(n)}
+	}
+	return v, nil
+}
+
+// GetTime returns the time at path. It accepts the same formats as
+// ParseDate.
+func (f *Frontmatter) GetTime(path string) (time.Time, error) {
+	n, err := f.lookupScalar(path, "time")
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := ParseDate(n.Value)
+	if err != nil {
+		return time.Time{}, &FieldTypeError{Path: path, Want: "time", Got: describeNode(n)}
+	}
+	return t, nil
+}
+
+// SetString sets the scalar at path, creating mappings along the way. The
+// value is quoted when written if it would otherwise read as another type.
+func (f *Frontmatter) SetString(path, value string) error {
+	return f.setNode(path, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value})
+}
+
+// SetInt sets the integer at path, creating mappings along the way.
+func (f *Frontmatter) SetInt(path string, value int) error {
+	return f.setNode(path, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(value)})
+}
+
+// SetBool sets the boolean at path, creating mappings along the way.
+func (f *Frontmatter) SetBool(path string, value bool) error {
+	return f.setNode(path, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(value)})
+}
+
+// SetTime sets the time at path formatted with layout, creating mappings
+// along the way.
+func (f *Frontmatter) SetTime(path string, value time.Time, layout string) error {
+	return f.setNode(path, &yaml.Node{Kind: yaml.ScalarNode, Value: value.Format(layout)})
+}
+
+// lookup resolves a dotted path through nested mappings.
+func (f *Frontmatter) lookup(path string) (*yaml.Node, error) {
+	node := f.mappingNode()
+	parts := strings.Split(path, ".")
+	for i, part := range parts {
+		if node.Kind != yaml.MappingNode {
+			return nil, &FieldTypeError{Path: strings.Join(parts[:i], "."), Want: "mapping", Got: describeNode(node)}
+		}
+		next := mappingValue(node, part)
+		if next == nil {
+			return nil, fmt.Errorf("frontmatter field %s: %w", path, ErrFieldNotFound)
+		}
+		node = resolveAlias(next)
+	}
+	return node, nil
+}
+
+func (f *Frontmatter) lookupScalar(path, want string) (*yaml.Node, error) {
+	n, err := f.lookup(path)
+	if err != nil {
+		return nil, err
+	}
+	if n.Kind != yaml.ScalarNode {
+		return nil, &FieldTypeError{Path: path, Want: want, Got: describeNode(n)}
+	}
+	return n, nil
+}
+
+// setNode stores value at path, creating missing mappings. It fails with a
+// FieldTypeError if a parent on the path is not a mapping. Comments on a
+// replaced value are kept.
+func (f *Frontmatter) setNode(path string, value *yaml.Node) error {
+	node := f.mappingNode()
+	parts := strings.Split(path, ".")
+	for i, part := range parts {
+		if node.Kind != yaml.MappingNode {
+			return &FieldTypeError{Path: strings.Join(parts[:i], "."), Want: "mapping", Got: describeNode(node)}
+		}
+
+		next := mappingValue(node, part)
+		last := i == len(parts)-1
+		switch {
+		case next != nil && last:
+			value.HeadComment = next.HeadComment
+			value.LineComment = next.LineComment
+			value.FootComment = next.FootComment
+			*next = *value
+			return nil
+		case next != nil:
+			node = resolveAlias(next)
+		case last:
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: part}, value)
+			return nil
+		default:
+			child := &yaml.Node{Kind: yaml.MappingNode}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: part}, child)
+			node = child
+		}
+	}
+	return nil
+}
+
+func mappingValue(mn *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mn.Content); i += 2 {
+		if mn.Content[i].Value == key {
+			return mn.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func resolveAlias(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	return n
+}
+
+// describeNode names a node's type for error messages.
+func describeNode(n *yaml.Node) string {
+	switch n.Kind {
+	case yaml.ScalarNode:
+		if isNull(n) {
+			return "null"
+		}
+		return fmt.Sprintf("%q", n.Value)
+	case yaml.SequenceNode:
+		return "list"
+	case yaml.MappingNode:
+		return "mapping"
+	default:
+		return "unknown value"
+	}
+}
+
+func isNull(n *yaml.Node) bool {
+	return n.Kind == yaml.ScalarNode && n.ShortTag() == "!!null"
+}
@@ -1,7 +1,9 @@
 package gonotes
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"gopkg.in/yaml.v3"
@@ -116,6 +118,13 @@ href: https://example.com`,
 			input: `title: Test`,
 			want:  map[string]string{"title": "Test"},
 		},
+		{
+			name: "sequence joined",
+			input: `tags: [foo, bar]
+meta:
+  a: b`,
+			want: map[string]string{"tags": "foo, bar", "meta": ""},
+		},
 	}
 
 	for _, tt := range tests {
@@ -205,3 +214,244 @@ title: Hello world
 		})
 	}
 }
+
+func TestFrontmatterGetList(t *testing.T) {
+	input := `flow: [a, b]
+block:
+  - a
+  - b
+scalar: a, b
+empty:
+mapping:
+  a: b
+source:
+  keywords: [a, b]`
+
+	tests := []struct {
+		key    string
+		want   []string
+		wantOK bool
+	}{
+		{"flow", []string{"a", "b"}, true},
+		{"block", []string{"a", "b"}, true},
+		{"scalar", []string{"a, b"}, true},
+		{"empty", nil, true},
+		{"mapping", nil, true},
+		{"source.keywords", []string{"a", "b"}, true},
+		{"missing", nil, false},
+		{"scalar.x", nil, false},
+	}
+
+	f := NewFrontmatter()
+	if err := yaml.Unmarshal([]byte(input), f); err != nil {
+		t.Fatalf("Unmarshal() err = %q", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			got, ok := f.GetList(tt.key)
+			if ok != tt.wantOK {
+				t.Errorf("GetList(%q) ok = %v, want %v", tt.key, ok, tt.wantOK)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("GetList(%q) diff (-want, +got):\n%s", tt.key, diff)
+			}
+		})
+	}
+}
+
+func TestFrontmatterSetList(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		wantOutput string
+	}{
+		{
+			name:       "flow stays flow",
+			input:      "tags: [a, b]\n",
+			wantOutput: "tags: [x, y]\n",
+		},
+		{
+			name:       "block stays block",
+			input:      "tags:\n    - a\n    - b\n",
+			wantOutput: "tags:\n    - x\n    - y\n",
+		},
+		{
+			name:       "scalar stays scalar",
+			input:      "tags: a, b\n",
+			wantOutput: "tags: x, y\n",
+		},
+		{
+			name:       "new key is block",
+			input:      "title: T\n",
+			wantOutput: "title: T\ntags:\n    - x\n    - y\n",
+		},
+		{
+			name:       "mapping replaced",
+			input:      "tags:\n    a: b\n",
+			wantOutput: "tags:\n    - x\n    - y\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := NewFrontmatter()
+			if err := yaml.Unmarshal([]byte(tt.input), f); err != nil {
+				t.Fatalf("Unmarshal() err = %q", err)
+			}
+
+			if err := f.SetList("tags", []string{"x", "y"}); err != nil {
+				t.Fatalf("SetList() err = %q", err)
+			}
+
+			b, err := yaml.Marshal(f)
+			if err != nil {
+				t.Fatalf("Marshal() err = %q", err)
+			}
+			if diff := cmp.Diff(tt.wantOutput, string(b)); diff != "" {
+				t.Errorf("Marshal() diff (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestFrontmatterTypedGet(t *testing.T) {
+	input := `title: Hello
+count: 42
+draft: yes
+published: false
+date: 2026-01-15 20:43:09
+updated: 2026-01-15T20:43:09+02:00
+day: 2026-01-15
+tags: [a, b]
+empty:
+source:
+  url: https://example.com
+  meta:
+    stars: 5
+base: &base
+  kind: ref
+copy: *base`
+
+	f := NewFrontmatter()
+	if err := yaml.Unmarshal([]byte(input), f); err != nil {
+		t.Fatalf("Unmarshal() err = %q", err)
+	}
+
+	t.Run("values", func(t *testing.T) {
+		check := func(name string, got, want any, err error) {
+			t.Helper()
+			if err != nil {
+				t.Errorf("%s err = %q", name, err)
+				return
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Errorf("%s diff (-want, +got):\n%s", name, diff)
+			}
+		}
+
+		s, err := f.GetString("title")
+		check("GetString(title)", s, "Hello", err)
+		s, err = f.GetString("source.url")
+		check("GetString(source.url)", s, "https://example.com", err)
+		s, err = f.GetString("copy.kind")
+		check("GetString(copy.kind)", s, "ref", err)
+		i, err := f.GetInt("count")
+		check("GetInt(count)", i, 42, err)
+		i, err = f.GetInt("source.meta.stars")
+		check("GetInt(source.meta.stars)", i, 5, err)
+		b, err := f.GetBool("draft")
+		check("GetBool(draft)", b, true, err)
+		b, err = f.GetBool("published")
+		check("GetBool(published)", b, false, err)
+		tm, err := f.GetTime("date")
+		check("GetTime(date)", tm, time.Date(2026, 1, 15, 20, 43, 9, 0, time.UTC), err)
+		tm, err = f.GetTime("updated")
+		check("GetTime(updated)", tm.UTC(), time.Date(2026, 1, 15, 18, 43, 9, 0, time.UTC), err)
+		tm, err = f.GetTime("day")
+		check("GetTime(day)", tm, time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC), err)
+	})
+
+	errs := []struct {
+		name     string
+		get      func() error
+		notFound bool
+	}{
+		{"missing", func() error { _, err := f.GetString("nope"); return err }, true},
+		{"missing nested", func() error { _, err := f.GetString("source.nope"); return err }, true},
+		{"int from string", func() error { _, err := f.GetInt("title"); return err }, false},
+		{"int from null", func() error { _, err := f.GetInt("empty"); return err }, false},
+		{"bool from int", func() error { _, err := f.GetBool("count"); return err }, false},
+		{"time from string", func() error { _, err := f.GetTime("title"); return err }, false},
+		{"string from list", func() error { _, err := f.GetString("tags"); return err }, false},
+		{"path through scalar", func() error { _, err := f.GetString("title.x"); return err }, false},
+	}
+	for _, tt := range errs {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.get()
+			if err == nil {
+				t.Fatal("err = <nil>, want error")
+			}
+			var typeErr *FieldTypeError
+			if tt.notFound && !errors.Is(err, ErrFieldNotFound) {
+				t.Errorf("err = %v, want ErrFieldNotFound", err)
+			}
+			if !tt.notFound && !errors.As(err, &typeErr) {
+				t.Errorf("err = %v, want *FieldTypeError", err)
+			}
+		})
+	}
+}
+
+func TestFrontmatterTypedSet(t *testing.T) {
+	input := `title: Hello
+tags: [a] # keep
+source:
+    url: old
+`
+
+	f := NewFrontmatter()
+	if err := yaml.Unmarshal([]byte(input), f); err != nil {
+		t.Fatalf("Unmarshal() err = %q", err)
+	}
+
+	steps := []error{
+		f.SetString("source.url", "https://example.com"),
+		f.SetInt("source.meta.stars", 5),
+		f.SetBool("draft", true),
+		f.SetString("version", "2"),
+		f.SetTime("reviewed", time.Date(2026, 3, 28, 14, 30, 0, 0, time.UTC), dateLayout),
+		f.SetList("tags", []string{"x", "y"}),
+		f.SetList("source.keywords", []string{"go"}),
+	}
+	for i, err := range steps {
+		if err != nil {
+			t.Fatalf("step %d err = %q", i, err)
+		}
+	}
+
+	want := `title: Hello
+tags: [x, y] # keep
+source:
+    url: https://example.com
+    meta:
+        stars: 5
+    keywords:
+        - go
+draft: true
+version: "2"
+reviewed: 2026-03-28 14:30:00
+`
+	b, err := yaml.Marshal(f)
+	if err != nil {
+		t.Fatalf("Marshal() err = %q", err)
+	}
+	if diff := cmp.Diff(want, string(b)); diff != "" {
+		t.Errorf("Marshal() diff (-want, +got):\n%s", diff)
+	}
+
+	var typeErr *FieldTypeError
+	if err := f.SetInt("title.x", 1); !errors.As(err, &typeErr) {
+		t.Errorf("SetInt(title.x) err = %v, want *FieldTypeError", err)
+	}
+}
@@ -0,0 +1,422 @@
+package gonotes
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LinkRef is a single [[link]] occurrence in a note body. Line is the
+// 1-based line number within the body and Context is that line's text.
+type LinkRef struct {
+	SourceID string `json:"source_id"`
+	TargetID string `json:"target_id"`
+	Line     int    `json:"line"`
+	Context  string `json:"context"`
+}
+
+// LinkGraph holds the links between notes in both directions. Targets are
+// normalized to note IDs; links to files keep their path as the target.
+type LinkGraph struct {
+	Titles   map[string]string
+	Outgoing map[string][]LinkRef
+	Incoming map[string][]LinkRef
+}
+
+func newLinkGraph() *LinkGraph {
+	return &LinkGraph{
+		Titles:   map[string]string{},
+		Outgoing: map[string][]LinkRef{},
+		Incoming: map[string][]LinkRef{},
+	}
+}
+
+// addNote records the note and every link in its body.
+func (g *LinkGraph) addNote(id, title, body string) {
+	g.Titles[id] = title
+	for _, ref := range parseLinkRefs(id, body) {
+		g.Outgoing[id] = append(g.Outgoing[id], ref)
+		g.Incoming[ref.TargetID] = append(g.Incoming[ref.TargetID], ref)
+	}
+}
+
+// resolveNames points refs that name a note by title or alias at that
+// note's ID. Names that match no note, or several, are left as written.
+func (g *LinkGraph) resolveNames(ids map[string]struct{}, names noteNames) {
+	g.Incoming = map[string][]LinkRef{}
+	for _, refs := range g.Outgoing {
+		for i := range refs {
+			if _, ok := ids[refs[i].TargetID]; !ok {
+				if m := names.lookup(refs[i].TargetID); len(m) == 1 {
+					refs[i].TargetID = m[0]
+				}
+			}
+			g.Incoming[refs[i].TargetID] = append(g.Incoming[refs[i].TargetID], refs[i])
+		}
+	}
+}
+
+// sort orders Incoming refs by source ID and line so output is stable
+// regardless of directory order.
+func (g *LinkGraph) sort() {
+	for _, refs := range g.Incoming {
+		sort.SliceStable(refs, func(i, j int) bool {
+			if refs[i].SourceID != refs[j].SourceID {
+				return refs[i].SourceID < refs[j].SourceID
+			}
+			return refs[i].Line < refs[j].Line
+		})
+	}
+}
+
+// Backlinks returns every link pointing at id, ordered by source ID.
+func (g *LinkGraph) Backlinks(id string) []LinkRef {
+	return g.Incoming[id]
+}
+
+// Links returns every link in note id, in body order.
+func (g *LinkGraph) Links(id string) []LinkRef {
+	return g.Outgoing[id]
+}
+
+// parseLinkRefs finds the [[links]] in body together with their line.
+// Like parseInternalLinks, it skips code.
+func parseLinkRefs(sourceID, body string) []LinkRef {
+	var refs []LinkRef
+	lines := strings.Split(body, "\n")
+	code := fencedLines(lines)
+	for i, line := range lines {
+		if code[i] {
+			continue
+		}
+		mapOutsideCodeSpans(line, func(text string) string {
+			for _, m := range reWikiLink.FindAllStringSubmatch(text, -1) {
+				targetID := linkTargetID(m[1])
+				if targetID == "" {
+					// A link to a heading in the same note.
+					continue
+				}
+				refs = append(refs, LinkRef{
+					SourceID: sourceID,
+					TargetID: targetID,
+					Line:     i + 1,
+					Context:  strings.TrimSpace(line),
+				})
+			}
+			return text
+		})
+	}
+	return refs
+}
+
+// linkTargetID normalizes the target of a [[link]] to a note ID. Note
+// links may carry a slug after the ID ([[20260328-2-some-title]]); file
+// links are returned unchanged. Anchors and display text are dropped.
+func linkTargetID(link string) string {
+	target := ParseWikiLink(link).Target
+	if strings.Contains(target, "/") {
+		return target
+	}
+	if m := reIDPrefix.FindStringSubmatch(target); m != nil {
+		return m[1] + "-" + m[2]
+	}
+	return target
+}
+
+// Node kinds in a NoteGraph.
+const (
+	GraphNodeNote = "note"
+	// GraphNodeFile is a file or folder under the files dir; its node ID is
+	// the link target prefixed with "files/".
+	GraphNodeFile = "file"
+	// GraphNodeMissing is a link target that resolves to nothing.
+	GraphNodeMissing = "missing"
+)
+
+// GraphNode is a node of a NoteGraph. Only note nodes have a title, tags
+// and date.
+type GraphNode struct {
+	ID    string
+	Kind  string
+	Title string
+	Tags  []string
+	Date  time.Time
+}
+
+// GraphEdge links a note to another node. Count is the number of times the
+// source note links to the target.
+type GraphEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Count  int    `json:"count"`
+}
+
+// NoteGraph is the link graph of a vault, or of a selection of its notes,
+// in a form suited to export. Nodes are sorted by ID and edges by source
+// and target.
+type NoteGraph struct {
+	Nodes []GraphNode
+	Edges []GraphEdge
+}
+
+type GraphOptions struct {
+	// Query selects the notes in the graph; nil selects every note.
+	Query *Query
+	// Neighbors also includes the notes that selected notes link to or are
+	// linked from.
+	Neighbors bool
+}
+
+// BuildNoteGraph returns the link graph of the notes in the vault at
+// baseDir selected by opts. Edges come from each note's InternalLinks:
+// links to notes, by ID or by a title or alias only one note uses, to
+// files under the files dir, and to targets that do not resolve, which
+// become missing nodes. Links matching the note's
+// ignore-links patterns are left out. An edge is included when at least
+// one of its ends is a selected note.
+func BuildNoteGraph(baseDir string, cfg *Config, opts GraphOptions) (*NoteGraph, []ScanError, error) {
+	cfg = cfg.orDefault()
+	filesDir := cfg.filesDir(baseDir)
+
+	files, errs, err := readNoteFiles(cfg.idDir(baseDir), cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("build note graph: %w", err)
+	}
+
+	byID := map[string]*Note{}
+	names := noteNames{}
+	for i := range files {
+		n := &files[i].Note
+		if n.ID == "" {
+			errs = append(errs, ScanError{Filename: files[i].Filename, Message: "cannot determine note ID"})
+			continue
+		}
+		if _, dup := byID[n.ID]; dup {
+			errs = append(errs, ScanError{Filename: files[i].Filename, Message: fmt.Sprintf("duplicate note ID %q", n.ID)})
+			continue
+		}
+		byID[n.ID] = n
+		names.add(n.ID, n)
+	}
+
+	selected := map[string]bool{}
+	for id, n := range byID {
+		if opts.Query == nil || opts.Query.Match(n) {
+			selected[id] = true
+		}
+	}
+
+	nodes := map[string]GraphNode{}
+	addNote := func(n *Note) {
+		nodes[n.ID] = GraphNode{ID: n.ID, Kind: GraphNodeNote, Title: n.Title, Tags: n.Tags, Date: n.Date}
+	}
+	counts := map[GraphEdge]int{}
+
+	for id, n := range byID {
+		for _, target := range n.InternalLinks {
+			if linkIgnored(target, n.IgnoreLinks) {
+				continue
+			}
+			targetID := linkTargetID(target)
+			if targetID == "" {
+				continue
+			}
+			dst, isNote := byID[targetID]
+			if ids := names.lookup(targetID); !isNote && len(ids) == 1 {
+				targetID = ids[0]
+				dst, isNote = byID[targetID]
+			}
+			if !selected[id] && (!isNote || !selected[targetID]) {
+				continue
+			}
+
+			switch {
+			case isNote:
+				if !opts.Neighbors && !(selected[id] && selected[targetID]) {
+					continue
+				}
+				addNote(dst)
+			case strings.Contains(targetID, "/") && linkResolves(target, nil, filesDir):
+				targetID = "files/" + targetID
+				nodes[targetID] = GraphNode{ID: targetID, Kind: GraphNodeFile}
+			default:
+				nodes[targetID] = GraphNode{ID: targetID, Kind: GraphNodeMissing}
+			}
+			addNote(n)
+			counts[GraphEdge{Source: id, Target: targetID}]++
+		}
+	}
+	for id := range selected {
+		addNote(byID[id])
+	}
+
+	g := &NoteGraph{}
+	for _, node := range nodes {
+		g.Nodes = append(g.Nodes, node)
+	}
+	for e, count := range counts {
+		e.Count = count
+		g.Edges = append(g.Edges, e)
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool { return g.Nodes[i].ID < g.Nodes[j].ID })
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i].Source != g.Edges[j].Source {
+			return g.Edges[i].Source < g.Edges[j].Source
+		}
+		return g.Edges[i].Target < g.Edges[j].Target
+	})
+
+	return g, errs, nil
+}
+
+// MarshalJSON encodes the graph as {"nodes": [...], "edges": [...]}. Node
+// dates are RFC 3339 and left out when the note has none.
+func (g NoteGraph) MarshalJSON() ([]byte, error) {
+	type node struct {
+		ID    string   `json:"id"`
+		Kind  string   `json:"kind"`
+		Title string   `json:"title,omitempty"`
+		Tags  []string `json:"tags"`
+		Date  string   `json:"date,omitempty"`
+	}
+	out := struct {
+		Nodes []node      `json:"nodes"`
+		Edges []GraphEdge `json:"edges"`
+	}{Nodes: []node{}, Edges: nonNil(g.Edges)}
+	for _, n := range g.Nodes {
+		jn := node{ID: n.ID, Kind: n.Kind, Title: n.Title, Tags: nonNil(n.Tags)}
+		if !n.Date.IsZero() {
+			jn.Date = n.Date.Format(time.RFC3339)
+		}
+		out.Nodes = append(out.Nodes, jn)
+	}
+	return json.Marshal(out)
+}
+
+// WriteDOT writes the graph in Graphviz DOT format. Notes are labelled
+// with their title, files are drawn as boxes and missing targets as dashed
+// red nodes. Kind, tags and date are kept as extra node attributes.
+func (g *NoteGraph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph notes {\n")
+	for _, n := range g.Nodes {
+		attrs := []string{"kind=" + dotQuote(n.Kind)}
+		switch n.Kind {
+		case GraphNodeNote:
+			label := n.Title
+			if label == "" {
+				label = n.ID
+			}
+			attrs = append(attrs, "label="+dotQuote(label))
+			if len(n.Tags) > 0 {
+				attrs = append(attrs, "tags="+dotQuote(FormatTags(n.Tags)))
+			}
+			if !n.Date.IsZero() {
+				attrs = append(attrs, "date="+dotQuote(n.Date.Format(time.RFC3339)))
+			}
+		case GraphNodeFile:
+			attrs = append(attrs, "label="+dotQuote(strings.TrimPrefix(n.ID, "files/")), "shape=box")
+		case GraphNodeMissing:
+			attrs = append(attrs, "style=dashed", "color=red")
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", dotQuote(n.ID), strings.Join(attrs, ", "))
+	}
+	for _, e := range g.Edges {
+		fmt.Fprintf(&b, "  %s -> %s", dotQuote(e.Source), dotQuote(e.Target))
+		if e.Count > 1 {
+			fmt.Fprintf(&b, " [weight=%d]", e.Count)
+		}
+		b.WriteString(";\n")
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func dotQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// WriteGraphML writes the graph as GraphML, with kind, title, tags and
+// date as node data and count as edge weight, for tools such as Gephi.
+func (g *NoteGraph) WriteGraphML(w io.Writer) error {
+	type data struct {
+		Key   string `xml:"key,attr"`
+		Value string `xml:",chardata"`
+	}
+	type node struct {
+		ID   string `xml:"id,attr"`
+		Data []data `xml:"data"`
+	}
+	type edge struct {
+		Source string `xml:"source,attr"`
+		Target string `xml:"target,attr"`
+		Data   []data `xml:"data"`
+	}
+	type key struct {
+		ID   string `xml:"id,attr"`
+		For  string `xml:"for,attr"`
+		Name string `xml:"attr.name,attr"`
+		Type string `xml:"attr.type,attr"`
+	}
+	type graphML struct {
+		XMLName xml.Name `xml:"graphml"`
+		XMLNS   string   `xml:"xmlns,attr"`
+		Keys    []key    `xml:"key"`
+		Graph   struct {
+			EdgeDefault string `xml:"edgedefault,attr"`
+			Nodes       []node `xml:"node"`
+			Edges       []edge `xml:"edge"`
+		} `xml:"graph"`
+	}
+
+	out := graphML{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []key{
+			{ID: "kind", For: "node", Name: "kind", Type: "string"},
+			{ID: "title", For: "node", Name: "title", Type: "string"},
+			{ID: "tags", For: "node", Name: "tags", Type: "string"},
+			{ID: "date", For: "node", Name: "date", Type: "string"},
+			{ID: "weight", For: "edge", Name: "weight", Type: "int"},
+		},
+	}
+	out.Graph.EdgeDefault = "directed"
+	for _, n := range g.Nodes {
+		xn := node{ID: n.ID, Data: []data{{Key: "kind", Value: n.Kind}}}
+		if n.Title != "" {
+			xn.Data = append(xn.Data, data{Key: "title", Value: n.Title})
+		}
+		if len(n.Tags) > 0 {
+			xn.Data = append(xn.Data, data{Key: "tags", Value: FormatTags(n.Tags)})
+		}
+		if !n.Date.IsZero() {
+			xn.Data = append(xn.Data, data{Key: "date", Value: n.Date.Format(time.RFC3339)})
+		}
+		out.Graph.Nodes = append(out.Graph.Nodes, xn)
+	}
+	for _, e := range g.Edges {
+		out.Graph.Edges = append(out.Graph.Edges, edge{
+			Source: e.Source,
+			Target: e.Target,
+			Data:   []data{{Key: "weight", Value: strconv.Itoa(e.Count)}},
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
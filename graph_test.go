@@ -0,0 +1,205 @@
+package gonotes
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestScanNotesGraphBacklinks(t *testing.T) {
+	baseDir := t.TempDir()
+	idDir := filepath.Join(baseDir, "notes", "by", "id")
+
+	writeTestNote(t, idDir, "20260328-1-target.md", `---
+title: Target
+---
+
+Nothing here.`)
+
+	writeTestNote(t, idDir, "20260328-2-source.md", `---
+title: Source
+---
+
+First line.
+See [[20260328-1]] for details.
+Also [[20260328-1-target]] by filename.`)
+
+	writeTestNote(t, idDir, "20260328-3-other.md", `---
+title: Other
+---
+
+Points at [[20260328-1]] and [[20260328-2]].`)
+
+	report, err := ScanNotes(baseDir, nil)
+	if err != nil {
+		t.Fatalf("ScanNotes() err = %q", err)
+	}
+
+	want := []LinkRef{
+		{SourceID: "20260328-2", TargetID: "20260328-1", Line: 3, Context: "See [[20260328-1]] for details."},
+		{SourceID: "20260328-2", TargetID: "20260328-1", Line: 4, Context: "Also [[20260328-1-target]] by filename."},
+		{SourceID: "20260328-3", TargetID: "20260328-1", Line: 2, Context: "Points at [[20260328-1]] and [[20260328-2]]."},
+	}
+	if diff := cmp.Diff(want, report.Graph.Backlinks("20260328-1")); diff != "" {
+		t.Errorf("Backlinks() diff (-want, +got):\n%s", diff)
+	}
+
+	if got := report.Graph.Backlinks("20260328-3"); len(got) != 0 {
+		t.Errorf("Backlinks(20260328-3) = %v, want none", got)
+	}
+
+	if got := report.Graph.Titles["20260328-2"]; got != "Source" {
+		t.Errorf("Titles[20260328-2] = %q, want %q", got, "Source")
+	}
+
+	if got := len(report.Graph.Links("20260328-3")); got != 2 {
+		t.Errorf("len(Links(20260328-3)) = %d, want 2", got)
+	}
+}
+
+func TestLinkTargetID(t *testing.T) {
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"20260328-1", "20260328-1"},
+		{"20260328-1-some-title", "20260328-1"},
+		{"20260403-1-contract-pdfs/doc1.pdf", "20260403-1-contract-pdfs/doc1.pdf"},
+		{"not-an-id", "not-an-id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			if got := linkTargetID(tt.target); got != tt.want {
+				t.Errorf("linkTargetID(%q) = %q, want %q", tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildNoteGraph(t *testing.T) {
+	baseDir := t.TempDir()
+	idDir := filepath.Join(baseDir, "notes", "by", "id")
+
+	writeTestNote(t, idDir, "20260328-1-go.md", `---
+title: Go
+date: 2026-03-28 14:30:00
+tags: go
+ignore-links: draft-*
+---
+
+[[20260328-2]] [[20260328-2-generics]] [[20260328-3]] [[20260401-1-slides/deck.pdf]] [[20991231-9]] [[draft-x]]`)
+	writeTestNote(t, idDir, "20260328-2-generics.md", `---
+title: Generics
+tags: go/generics
+---
+
+Back to [[go]].`)
+	writeTestNote(t, idDir, "20260328-3-rust.md", `---
+title: Rust
+tags: rust
+---
+
+See [[20260328-1]].`)
+	writeTestNote(t, filepath.Join(baseDir, "files", "20260401-1-slides"), "deck.pdf", "")
+
+	query, err := ParseQuery("tag:go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("selection", func(t *testing.T) {
+		g, errs, err := BuildNoteGraph(baseDir, nil, GraphOptions{Query: query})
+		if err != nil || len(errs) > 0 {
+			t.Fatalf("BuildNoteGraph() errs = %v, err = %v", errs, err)
+		}
+		want := &NoteGraph{
+			Nodes: []GraphNode{
+				{ID: "20260328-1", Kind: GraphNodeNote, Title: "Go", Tags: []string{"go"}, Date: testTime},
+				{ID: "20260328-2", Kind: GraphNodeNote, Title: "Generics", Tags: []string{"go/generics"}},
+				{ID: "20991231-9", Kind: GraphNodeMissing},
+				{ID: "files/20260401-1-slides/deck.pdf", Kind: GraphNodeFile},
+			},
+			Edges: []GraphEdge{
+				{Source: "20260328-1", Target: "20260328-2", Count: 2},
+				{Source: "20260328-1", Target: "20991231-9", Count: 1},
+				{Source: "20260328-1", Target: "files/20260401-1-slides/deck.pdf", Count: 1},
+				{Source: "20260328-2", Target: "20260328-1", Count: 1},
+			},
+		}
+		if diff := cmp.Diff(want, g); diff != "" {
+			t.Errorf("BuildNoteGraph() diff (-want, +got):\n%s", diff)
+		}
+	})
+
+	t.Run("neighbors", func(t *testing.T) {
+		g, _, err := BuildNoteGraph(baseDir, nil, GraphOptions{Query: query, Neighbors: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, n := range g.Nodes {
+			ids = append(ids, n.ID)
+		}
+		wantIDs := []string{"20260328-1", "20260328-2", "20260328-3", "20991231-9", "files/20260401-1-slides/deck.pdf"}
+		if diff := cmp.Diff(wantIDs, ids); diff != "" {
+			t.Errorf("node IDs diff (-want, +got):\n%s", diff)
+		}
+		if len(g.Edges) != 6 {
+			t.Errorf("len(Edges) = %d, want 6: %v", len(g.Edges), g.Edges)
+		}
+	})
+
+	t.Run("formats", func(t *testing.T) {
+		g := &NoteGraph{
+			Nodes: []GraphNode{
+				{ID: "20260328-1", Kind: GraphNodeNote, Title: `Say "hi"`, Tags: []string{"go"}},
+				{ID: "files/a/b.pdf", Kind: GraphNodeFile},
+			},
+			Edges: []GraphEdge{{Source: "20260328-1", Target: "files/a/b.pdf", Count: 2}},
+		}
+
+		var dot strings.Builder
+		if err := g.WriteDOT(&dot); err != nil {
+			t.Fatal(err)
+		}
+		wantDOT := `digraph notes {
+  "20260328-1" [kind="note", label="Say \"hi\"", tags="go"];
+  "files/a/b.pdf" [kind="file", label="a/b.pdf", shape=box];
+  "20260328-1" -> "files/a/b.pdf" [weight=2];
+}
+`
+		if diff := cmp.Diff(wantDOT, dot.String()); diff != "" {
+			t.Errorf("WriteDOT() diff (-want, +got):\n%s", diff)
+		}
+
+		var gml strings.Builder
+		if err := g.WriteGraphML(&gml); err != nil {
+			t.Fatal(err)
+		}
+		for _, want := range []string{
+			`<node id="20260328-1">`,
+			`<data key="title">Say &#34;hi&#34;</data>`,
+			`<edge source="20260328-1" target="files/a/b.pdf">`,
+			`<data key="weight">2</data>`,
+		} {
+			if !strings.Contains(gml.String(), want) {
+				t.Errorf("WriteGraphML() missing %q:\n%s", want, gml.String())
+			}
+		}
+
+		data, err := json.Marshal(g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wantJSON := `{"nodes":[{"id":"20260328-1","kind":"note","title":"Say \"hi\"","tags":["go"]},` +
+			`{"id":"files/a/b.pdf","kind":"file","tags":[]}],` +
+			`"edges":[{"source":"20260328-1","target":"files/a/b.pdf","count":2}]}`
+		if diff := cmp.Diff(wantJSON, string(data)); diff != "" {
+			t.Errorf("json.Marshal() diff (-want, +got):\n%s", diff)
+		}
+	})
+}
@@ -59,6 +59,54 @@ func MaxNumFromDir(dir string, now time.Time) (int, error) {
 	return maxNum, nil
 }
 
+// compareIDs orders note IDs by date and then by number, so 20260328-10
+// sorts after 20260328-9. IDs without a date prefix sort as strings after
+// those with one.
+func compareIDs(a, b string) int {
+	ma, mb := reIDPrefix.FindStringSubmatch(a), reIDPrefix.FindStringSubmatch(b)
+	switch {
+	case ma == nil && mb == nil:
+		return strings.Compare(a, b)
+	case ma == nil:
+		return 1
+	case mb == nil:
+		return -1
+	}
+	if c := strings.Compare(ma[1], mb[1]); c != 0 {
+		return c
+	}
+	// Compare the numbers by length first, so they never overflow.
+	na, nb := strings.TrimLeft(ma[2], "0"), strings.TrimLeft(mb[2], "0")
+	if c := len(na) - len(nb); c != 0 {
+		return c
+	}
+	if c := strings.Compare(na, nb); c != 0 {
+		return c
+	}
+	return strings.Compare(a, b)
+}
+
+// usedIDs returns the IDs that names of entries in dirs start with,
+// whatever their slug or extension. Missing dirs are skipped.
+func usedIDs(dirs ...string) (map[string]struct{}, error) {
+	ids := map[string]struct{}{}
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, fmt.Errorf("read dir: %w", err)
+		}
+		for _, e := range entries {
+			if m := reIDPrefix.FindStringSubmatch(e.Name()); m != nil {
+				ids[m[1]+"-"+m[2]] = struct{}{}
+			}
+		}
+	}
+	return ids, nil
+}
+
 func idPrefix(t time.Time) string {
 	return t.Format("20060102")
 }
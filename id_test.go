@@ -221,3 +221,15 @@ func TestMaxNumFromDirNonExistent(t *testing.T) {
 		t.Errorf("MaxNumFromDir() = %d, want 0", got)
 	}
 }
+
+func TestCompareIDs(t *testing.T) {
+	ordered := []string{"20260327-12", "20260328-2", "20260328-9", "20260328-10", "20260328-100", "20260329-1", "notes", "other"}
+	for i, a := range ordered {
+		for j, b := range ordered {
+			got := compareIDs(a, b)
+			if got < 0 != (i < j) || got > 0 != (i > j) {
+				t.Errorf("compareIDs(%q, %q) = %d, want sign of %d", a, b, got, i-j)
+			}
+		}
+	}
+}
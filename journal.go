@@ -0,0 +1,329 @@
+package gonotes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// journalVersion is bumped whenever the journal format changes.
+const journalVersion = 1
+
+// JournalOpKind is the kind of change a JournalOp makes.
+type JournalOpKind string
+
+const (
+	JournalRewrite JournalOpKind = "rewrite"
+	JournalRename  JournalOpKind = "rename"
+)
+
+// JournalOp is a single step of a journaled rebuild. Rewrite steps replace
+// the content of File; rename steps move From to To. Names are relative to
+// the notes directory. Rewrite steps carry both contents so they can be
+// redone and undone without re-reading the vault.
+type JournalOp struct {
+	Kind       JournalOpKind `json:"kind"`
+	File       string        `json:"file,omitempty"`
+	OldContent string        `json:"old_content,omitempty"`
+	NewContent string        `json:"new_content,omitempty"`
+	From       string        `json:"from,omitempty"`
+	To         string        `json:"to,omitempty"`
+	Done       bool          `json:"done"`
+}
+
+func (op JournalOp) String() string {
+	state := "pending"
+	if op.Done {
+		state = "done"
+	}
+	if op.Kind == JournalRename {
+		return fmt.Sprintf("rename %s -> %s (%s)", op.From, op.To, state)
+	}
+	return fmt.Sprintf("rewrite %s (%s)", op.File, state)
+}
+
+// Journal is the on-disk record of a rebuild's renames and link rewrites.
+// It is written before anything changes and updated after every step, so
+// an interrupted rebuild can be resumed or rolled back on the next run.
+type Journal struct {
+	Version int         `json:"version"`
+	Ops     []JournalOp `json:"ops"`
+
+	baseDir string
+	cfg     *Config
+}
+
+// JournalPath returns the location of the rebuild journal for the vault at
+// baseDir.
+func JournalPath(baseDir string) string {
+	return filepath.Join(stateDir(baseDir), "journal")
+}
+
+// BeginRebuild records the given link rewrites and renames in a new
+// journal without applying them. Rewrites come first because they refer to
+// current filenames. It fails if an unfinished journal already exists.
+func BeginRebuild(baseDir string, cfg *Config, rewrites []LinkRewrite, renames []Rename) (*Journal, error) {
+	cfg = cfg.orDefault()
+	path := JournalPath(baseDir)
+	if _, err := os.Stat(path); err == nil {
+		return nil, fmt.Errorf("begin rebuild: unfinished journal at %s", path)
+	}
+
+	idDir := cfg.idDir(baseDir)
+	j := &Journal{Version: journalVersion, baseDir: baseDir, cfg: cfg}
+
+	order, byFile := groupLinkRewrites(rewrites)
+	for _, name := range order {
+		data, err := os.ReadFile(filepath.Join(idDir, name))
+		if err != nil {
+			return nil, fmt.Errorf("begin rebuild: %w", err)
+		}
+		content := applyLinkRewrites(string(data), byFile[name])
+		if content == string(data) {
+			continue
+		}
+		j.Ops = append(j.Ops, JournalOp{
+			Kind:       JournalRewrite,
+			File:       name,
+			OldContent: string(data),
+			NewContent: content,
+		})
+	}
+
+	for _, rn := range renames {
+		j.Ops = append(j.Ops, JournalOp{
+			Kind: JournalRename,
+			From: rn.OldName,
+			To:   rn.NewName,
+		})
+	}
+
+	if err := j.save(); err != nil {
+		return nil, fmt.Errorf("begin rebuild: %w", err)
+	}
+	return j, nil
+}
+
+// OpenJournal loads an unfinished journal for the vault at baseDir. It
+// returns nil without error when there is none.
+func OpenJournal(baseDir string, cfg *Config) (*Journal, error) {
+	data, err := os.ReadFile(JournalPath(baseDir))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("open journal: %w", err)
+	}
+
+	j := &Journal{baseDir: baseDir, cfg: cfg.orDefault()}
+	if err := json.Unmarshal(data, j); err != nil {
+		return nil, fmt.Errorf("open journal: %w", err)
+	}
+	if j.Version != journalVersion {
+		return nil, fmt.Errorf("open journal: unsupported version %d", j.Version)
+	}
+	return j, nil
+}
+
+func (j *Journal) String() string {
+	var b strings.Builder
+	done := 0
+	for _, op := range j.Ops {
+		if op.Done {
+			done++
+		}
+	}
+	fmt.Fprintf(&b, "Unfinished rebuild (%d of %d steps done):\n", done, len(j.Ops))
+	for _, op := range j.Ops {
+		fmt.Fprintf(&b, "  %s\n", op.String())
+	}
+	return b.String()
+}
+
+// Rewritten returns the files changed by the journal's rewrite steps.
+func (j *Journal) Rewritten() []string {
+	var out []string
+	for _, op := range j.Ops {
+		if op.Kind == JournalRewrite {
+			out = append(out, op.File)
+		}
+	}
+	return out
+}
+
+// Apply performs the remaining steps in order, recording each one as done.
+// Steps are idempotent, so a step interrupted by a crash is simply redone.
+// On success the journal file is removed. On failure the journal is left in
+// place for Rollback or a later Apply. Like Rollback, it refuses, changing
+// nothing, when a file still to be rewritten holds neither its old nor its
+// new content, since rewriting it would lose the edits made since.
+func (j *Journal) Apply() error {
+	unlock, err := lockVault(j.baseDir)
+	if err != nil {
+		return fmt.Errorf("apply journal: %w", err)
+	}
+	defer unlock()
+
+	idDir := j.cfg.idDir(j.baseDir)
+	first := len(j.Ops)
+	for i, op := range j.Ops {
+		if !op.Done {
+			first = i
+			break
+		}
+	}
+	if changed := j.changedRewrites(idDir, j.Ops[first:]); len(changed) > 0 {
+		return fmt.Errorf("apply journal: edited since the rebuild: %s", strings.Join(changed, ", "))
+	}
+	for i := range j.Ops {
+		op := &j.Ops[i]
+		if op.Done {
+			continue
+		}
+		if err := op.apply(idDir); err != nil {
+			return fmt.Errorf("apply journal: %s: %w", op.String(), err)
+		}
+		op.Done = true
+		if err := j.save(); err != nil {
+			return fmt.Errorf("apply journal: %w", err)
+		}
+	}
+
+	if err := os.Remove(JournalPath(j.baseDir)); err != nil {
+		return fmt.Errorf("apply journal: %w", err)
+	}
+	return nil
+}
+
+// Rollback undoes the steps in reverse order, including a step that may
+// have been interrupted midway, and removes the journal file. It refuses,
+// changing nothing, when a rewritten file no longer holds the content the
+// journal wrote, since restoring it would lose the edits made since.
+func (j *Journal) Rollback() error {
+	unlock, err := lockVault(j.baseDir)
+	if err != nil {
+		return fmt.Errorf("rollback journal: %w", err)
+	}
+	defer unlock()
+
+	// The first pending step may have been partially applied.
+	last := len(j.Ops) - 1
+	for i, op := range j.Ops {
+		if !op.Done {
+			last = i
+			break
+		}
+	}
+
+	idDir := j.cfg.idDir(j.baseDir)
+	if changed := j.changedRewrites(idDir, j.Ops[:last+1]); len(changed) > 0 {
+		return fmt.Errorf("rollback journal: edited since the rebuild: %s", strings.Join(changed, ", "))
+	}
+	for i := last; i >= 0; i-- {
+		op := &j.Ops[i]
+		if err := op.undo(idDir); err != nil {
+			return fmt.Errorf("rollback journal: %s: %w", op.String(), err)
+		}
+		op.Done = false
+		if err := j.save(); err != nil {
+			return fmt.Errorf("rollback journal: %w", err)
+		}
+	}
+
+	if err := os.Remove(JournalPath(j.baseDir)); err != nil {
+		return fmt.Errorf("rollback journal: %w", err)
+	}
+	return nil
+}
+
+// changedRewrites returns the current names of the files rewritten by the
+// rewrite steps among ops that hold neither the journaled old nor new
+// content, because they were edited since the rebuild began.
+func (j *Journal) changedRewrites(idDir string, ops []JournalOp) []string {
+	var changed []string
+	for _, op := range ops {
+		if op.Kind != JournalRewrite {
+			continue
+		}
+		name := j.currentName(idDir, op.File)
+		data, err := os.ReadFile(filepath.Join(idDir, name))
+		if err == nil && (string(data) == op.NewContent || string(data) == op.OldContent) {
+			continue
+		}
+		changed = append(changed, name)
+	}
+	return changed
+}
+
+// currentName follows the journal's rename steps from name to where the
+// file is now.
+func (j *Journal) currentName(idDir, name string) string {
+	for _, op := range j.Ops {
+		if op.Kind != JournalRename || op.From != name {
+			continue
+		}
+		if _, err := os.Lstat(filepath.Join(idDir, name)); errors.Is(err, os.ErrNotExist) {
+			name = op.To
+		}
+	}
+	return name
+}
+
+func (op *JournalOp) apply(idDir string) error {
+	switch op.Kind {
+	case JournalRewrite:
+		path := filepath.Join(idDir, op.File)
+		if data, err := os.ReadFile(path); err == nil && string(data) == op.NewContent {
+			// Written before an interruption.
+			return nil
+		}
+		return writeFileAtomic(path, []byte(op.NewContent), 0o644)
+	case JournalRename:
+		return renameIfPresent(filepath.Join(idDir, op.From), filepath.Join(idDir, op.To))
+	default:
+		return fmt.Errorf("unknown step kind %q", op.Kind)
+	}
+}
+
+func (op *JournalOp) undo(idDir string) error {
+	switch op.Kind {
+	case JournalRewrite:
+		return writeFileAtomic(filepath.Join(idDir, op.File), []byte(op.OldContent), 0o644)
+	case JournalRename:
+		return renameIfPresent(filepath.Join(idDir, op.To), filepath.Join(idDir, op.From))
+	default:
+		return fmt.Errorf("unknown step kind %q", op.Kind)
+	}
+}
+
+// renameIfPresent renames from to to. It succeeds without doing anything
+// when the rename has already happened, and refuses to replace an existing
+// file at to.
+func renameIfPresent(from, to string) error {
+	_, fromErr := os.Lstat(from)
+	_, toErr := os.Lstat(to)
+	switch {
+	case fromErr == nil && toErr == nil:
+		return fmt.Errorf("%s already exists", filepath.Base(to))
+	case fromErr != nil && toErr == nil:
+		return nil
+	case fromErr != nil:
+		return fromErr
+	}
+	return renameFile(from, to)
+}
+
+func (j *Journal) save() error {
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := JournalPath(j.baseDir)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0o644)
+}
@@ -0,0 +1,257 @@
+package gonotes
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func setupJournalVault(t *testing.T) (baseDir, idDir string, report *RebuildReport) {
+	t.Helper()
+	baseDir = t.TempDir()
+	idDir = filepath.Join(baseDir, "notes", "by", "id")
+
+	writeTestNote(t, idDir, "20260328-1-source.md", "---\ntitle: Source\n---\n\nSee [[20260328-2-a]] and [[20260328-3-b]].")
+	writeTestNote(t, idDir, "20260328-2-a.md", "---\ntitle: A New\n---\n")
+	writeTestNote(t, idDir, "20260328-3-b.md", "---\ntitle: B New\n---\n")
+
+	report, err := ScanNotes(baseDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return baseDir, idDir, report
+}
+
+func readDirNames(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.Name()
+	}
+	return names
+}
+
+func TestJournalApply(t *testing.T) {
+	baseDir, idDir, report := setupJournalVault(t)
+
+	j, err := BeginRebuild(baseDir, nil, report.LinkRewrites, report.Renames)
+	if err != nil {
+		t.Fatalf("BeginRebuild() err = %q", err)
+	}
+	if len(j.Ops) != 3 {
+		t.Fatalf("got %d ops, want 3: %v", len(j.Ops), j.Ops)
+	}
+	if _, err := os.Stat(JournalPath(baseDir)); err != nil {
+		t.Fatalf("journal not written: %v", err)
+	}
+
+	if err := j.Apply(); err != nil {
+		t.Fatalf("Apply() err = %q", err)
+	}
+
+	got := strings.Join(readDirNames(t, idDir), " ")
+	if want := "20260328-1-source.md 20260328-2-a-new.md 20260328-3-b-new.md"; got != want {
+		t.Errorf("files = %q, want %q", got, want)
+	}
+	content, err := os.ReadFile(filepath.Join(idDir, "20260328-1-source.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(content), "[[20260328-2-a-new]] and [[20260328-3-b-new]]") {
+		t.Errorf("links not rewritten: %q", content)
+	}
+	if _, err := os.Stat(JournalPath(baseDir)); !os.IsNotExist(err) {
+		t.Errorf("journal not removed after Apply: %v", err)
+	}
+}
+
+func TestJournalFailureRollback(t *testing.T) {
+	baseDir, idDir, report := setupJournalVault(t)
+	before := readDirNames(t, idDir)
+	source, err := os.ReadFile(filepath.Join(idDir, "20260328-1-source.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	origRename := renameFile
+	failHook(t, &renameFile, func(from, to string) error {
+		if filepath.Base(to) == "20260328-3-b-new.md" {
+			return syscall.EIO
+		}
+		return origRename(from, to)
+	})
+
+	j, err := BeginRebuild(baseDir, nil, report.LinkRewrites, report.Renames)
+	if err != nil {
+		t.Fatalf("BeginRebuild() err = %q", err)
+	}
+	if err := j.Apply(); err == nil {
+		t.Fatal("Apply() err = <nil>, want error")
+	}
+
+	// The failed run leaves a journal behind that a later run can find.
+	reopened, err := OpenJournal(baseDir, nil)
+	if err != nil {
+		t.Fatalf("OpenJournal() err = %q", err)
+	}
+	if reopened == nil {
+		t.Fatal("OpenJournal() = nil, want unfinished journal")
+	}
+	done := 0
+	for _, op := range reopened.Ops {
+		if op.Done {
+			done++
+		}
+	}
+	if done != 2 {
+		t.Errorf("%d steps done, want 2", done)
+	}
+
+	if err := reopened.Rollback(); err != nil {
+		t.Fatalf("Rollback() err = %q", err)
+	}
+
+	if got := readDirNames(t, idDir); strings.Join(got, " ") != strings.Join(before, " ") {
+		t.Errorf("files after rollback = %v, want %v", got, before)
+	}
+	got, err := os.ReadFile(filepath.Join(idDir, "20260328-1-source.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(source) {
+		t.Errorf("source after rollback = %q, want %q", got, source)
+	}
+	if j, err := OpenJournal(baseDir, nil); err != nil || j != nil {
+		t.Errorf("OpenJournal() after rollback = %v, %v, want nil, nil", j, err)
+	}
+}
+
+func TestJournalRollbackRefusesEditedFiles(t *testing.T) {
+	baseDir, idDir, report := setupJournalVault(t)
+
+	origRename := renameFile
+	failHook(t, &renameFile, func(from, to string) error {
+		if filepath.Base(to) == "20260328-3-b-new.md" {
+			return syscall.EIO
+		}
+		return origRename(from, to)
+	})
+
+	j, err := BeginRebuild(baseDir, nil, report.LinkRewrites, report.Renames)
+	if err != nil {
+		t.Fatalf("BeginRebuild() err = %q", err)
+	}
+	if err := j.Apply(); err == nil {
+		t.Fatal("Apply() err = <nil>, want error")
+	}
+
+	sourcePath := filepath.Join(idDir, "20260328-1-source.md")
+	rewritten, err := os.ReadFile(sourcePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited := string(rewritten) + "\nEdited after the rebuild."
+	writeTestNote(t, idDir, "20260328-1-source.md", edited)
+	before := readDirNames(t, idDir)
+
+	err = j.Rollback()
+	if err == nil || !strings.Contains(err.Error(), "20260328-1-source.md") {
+		t.Fatalf("Rollback() err = %v, want error naming the edited file", err)
+	}
+	if got := readDirNames(t, idDir); strings.Join(got, " ") != strings.Join(before, " ") {
+		t.Errorf("files after refused rollback = %v, want %v", got, before)
+	}
+	if got, err := os.ReadFile(sourcePath); err != nil || string(got) != edited {
+		t.Errorf("edited file after refused rollback = %q, %v; want the edit kept", got, err)
+	}
+	if j, err := OpenJournal(baseDir, nil); err != nil || j == nil {
+		t.Errorf("OpenJournal() after refused rollback = %v, %v, want the journal kept", j, err)
+	}
+
+	// Once the edit is undone, the rollback goes through.
+	writeTestNote(t, idDir, "20260328-1-source.md", string(rewritten))
+	if err := j.Rollback(); err != nil {
+		t.Fatalf("Rollback() err = %q", err)
+	}
+}
+
+func TestJournalApplyRefusesEditedFiles(t *testing.T) {
+	baseDir, idDir, report := setupJournalVault(t)
+
+	j, err := BeginRebuild(baseDir, nil, report.LinkRewrites, report.Renames)
+	if err != nil {
+		t.Fatalf("BeginRebuild() err = %q", err)
+	}
+
+	// The source note is edited after the journal was written but before
+	// it is applied, as after a crash.
+	sourcePath := filepath.Join(idDir, "20260328-1-source.md")
+	original, err := os.ReadFile(sourcePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	edited := string(original) + "\nEdited after the crash."
+	writeTestNote(t, idDir, "20260328-1-source.md", edited)
+	before := readDirNames(t, idDir)
+
+	err = j.Apply()
+	if err == nil || !strings.Contains(err.Error(), "20260328-1-source.md") {
+		t.Fatalf("Apply() err = %v, want error naming the edited file", err)
+	}
+	if got := readDirNames(t, idDir); strings.Join(got, " ") != strings.Join(before, " ") {
+		t.Errorf("files after refused apply = %v, want %v", got, before)
+	}
+	if got, err := os.ReadFile(sourcePath); err != nil || string(got) != edited {
+		t.Errorf("edited file after refused apply = %q, %v; want the edit kept", got, err)
+	}
+	if j, err := OpenJournal(baseDir, nil); err != nil || j == nil {
+		t.Errorf("OpenJournal() after refused apply = %v, %v, want the journal kept", j, err)
+	}
+
+	// Once the edit is undone, the journal applies.
+	writeTestNote(t, idDir, "20260328-1-source.md", string(original))
+	if err := j.Apply(); err != nil {
+		t.Fatalf("Apply() err = %q", err)
+	}
+}
+
+func TestJournalResumeAfterCrash(t *testing.T) {
+	baseDir, idDir, report := setupJournalVault(t)
+
+	j, err := BeginRebuild(baseDir, nil, report.LinkRewrites, report.Renames)
+	if err != nil {
+		t.Fatalf("BeginRebuild() err = %q", err)
+	}
+
+	// Simulate a crash right after the first rename happened but before it
+	// was recorded in the journal.
+	if err := os.Rename(filepath.Join(idDir, "20260328-2-a.md"), filepath.Join(idDir, "20260328-2-a-new.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := BeginRebuild(baseDir, nil, nil, nil); err == nil {
+		t.Error("BeginRebuild() with unfinished journal err = <nil>, want error")
+	}
+
+	reopened, err := OpenJournal(baseDir, nil)
+	if err != nil || reopened == nil {
+		t.Fatalf("OpenJournal() = %v, %v", reopened, err)
+	}
+	if len(reopened.Ops) != len(j.Ops) {
+		t.Fatalf("reopened %d ops, want %d", len(reopened.Ops), len(j.Ops))
+	}
+	if err := reopened.Apply(); err != nil {
+		t.Fatalf("Apply() err = %q", err)
+	}
+
+	got := strings.Join(readDirNames(t, idDir), " ")
+	if want := "20260328-1-source.md 20260328-2-a-new.md 20260328-3-b-new.md"; got != want {
+		t.Errorf("files = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,155 @@
+package gonotes
+
+import (
+	"regexp"
+	"strings"
+)
+
+// reBlockID matches a ^block ID at the end of a line.
+var reBlockID = regexp.MustCompile(`(?:^|\s)\^([A-Za-z0-9-]+)[ \t]*$`)
+
+// WikiLink is a parsed [[link]]. Besides the target, a link may name a
+// heading ([[target#Heading]]) or a ^block ID ([[target#^block]]) in the
+// target note, and carry display text ([[target|text]]). An empty target
+// points into the note containing the link.
+type WikiLink struct {
+	// Target is a note ID, optionally followed by a slug, or a path under
+	// the files dir.
+	Target string
+	// Anchor is a heading in the target note. Nested headings are written
+	// as in Obsidian, separated by "#".
+	Anchor string
+	// Block is a block ID in the target note, without the caret.
+	Block string
+	Text  string
+}
+
+// ParseWikiLink parses the text between the brackets of a [[link]]. A pipe
+// escaped as \|, as inside a table, also starts the display text.
+func ParseWikiLink(s string) WikiLink {
+	var l WikiLink
+	if i := strings.Index(s, "|"); i >= 0 {
+		l.Text = s[i+1:]
+		s = strings.TrimSuffix(s[:i], `\`)
+	}
+	l.Target, l.Anchor, _ = strings.Cut(s, "#")
+	if block, ok := strings.CutPrefix(l.Anchor, "^"); ok {
+		l.Anchor, l.Block = "", block
+	}
+	return l
+}
+
+// String formats l as the text between the brackets of a [[link]].
+func (l WikiLink) String() string {
+	s := l.Target
+	switch {
+	case l.Block != "":
+		s += "#^" + l.Block
+	case l.Anchor != "":
+		s += "#" + l.Anchor
+	}
+	if l.Text != "" {
+		s += "|" + l.Text
+	}
+	return s
+}
+
+// withLinkTarget returns the [[link]] text raw with its target replaced by
+// target. The anchor and display text are kept exactly as written.
+func withLinkTarget(raw, target string) string {
+	return target + raw[len(ParseWikiLink(raw).Target):]
+}
+
+// brokenLinkTarget returns the [[link]] text raw without its display text,
+// as reported in a BrokenLink.
+func brokenLinkTarget(raw string) string {
+	l := ParseWikiLink(raw)
+	l.Text = ""
+	return l.String()
+}
+
+// linkIgnored reports whether the [[link]] text raw is excluded from link
+// checking by one of the ignore-links patterns, which match its target.
+func linkIgnored(raw string, patterns []string) bool {
+	return matchesAny(ParseWikiLink(raw).Target, patterns)
+}
+
+// noteAnchors holds the headings and block IDs of a note that links can
+// point at. Headings are keyed by headingID, so a link may spell a heading
+// as written or as its anchor in the HTML export.
+type noteAnchors struct {
+	headings map[string]struct{}
+	blocks   map[string]struct{}
+}
+
+// parseAnchors collects the ATX headings and ^block IDs in body, skipping
+// fenced code.
+func parseAnchors(body string) noteAnchors {
+	a := noteAnchors{headings: map[string]struct{}{}, blocks: map[string]struct{}{}}
+	lines := strings.Split(body, "\n")
+	code := fencedLines(lines)
+	for i, line := range lines {
+		if code[i] {
+			continue
+		}
+		if m := reATXHeading.FindStringSubmatch(line); m != nil {
+			a.headings[headingID(m[2])] = struct{}{}
+			continue
+		}
+		if m := reBlockID.FindStringSubmatch(line); m != nil {
+			a.blocks[m[1]] = struct{}{}
+		}
+	}
+	return a
+}
+
+// has reports whether the heading or block l points at exists. Only the
+// last heading of a nested heading link is checked.
+func (a noteAnchors) has(l WikiLink) bool {
+	if l.Block != "" {
+		_, ok := a.blocks[l.Block]
+		return ok
+	}
+	if l.Anchor != "" {
+		headings := strings.Split(l.Anchor, "#")
+		_, ok := a.headings[headingID(headings[len(headings)-1])]
+		return ok
+	}
+	return true
+}
+
+// noteNames maps lowercased titles and aliases to the IDs of the notes
+// that use them, so that [[My Note Title]] links resolve.
+type noteNames map[string][]string
+
+// add records the title and aliases of note id.
+func (nn noteNames) add(id string, n *Note) {
+	seen := map[string]struct{}{}
+	for _, name := range append([]string{n.Title}, n.Aliases...) {
+		key := strings.ToLower(strings.TrimSpace(name))
+		if _, dup := seen[key]; dup || key == "" {
+			continue
+		}
+		seen[key] = struct{}{}
+		nn[key] = append(nn[key], id)
+	}
+}
+
+// lookup returns the IDs of the notes titled or aliased target, ignoring
+// case. More than one ID means the name is ambiguous.
+func (nn noteNames) lookup(target string) []string {
+	return nn[strings.ToLower(strings.TrimSpace(target))]
+}
+
+// idLinkTarget returns the [[link]] text raw, which names a note by title
+// or alias, as a link to id. The name becomes the display text unless the
+// link already has one.
+func idLinkTarget(raw, id string) string {
+	l := ParseWikiLink(raw)
+	if l.Text != "" {
+		return withLinkTarget(raw, id)
+	}
+	l.Text = l.Target
+	l.Target = id
+	return l.String()
+}
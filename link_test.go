@@ -0,0 +1,68 @@
+package gonotes
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseWikiLink(t *testing.T) {
+	tests := []struct {
+		input string
+		want  WikiLink
+	}{
+		{"20260328-2", WikiLink{Target: "20260328-2"}},
+		{"20260328-2|see this", WikiLink{Target: "20260328-2", Text: "see this"}},
+		{"20260328-2#Setup", WikiLink{Target: "20260328-2", Anchor: "Setup"}},
+		{"20260328-2#Setup#Linux|linux", WikiLink{Target: "20260328-2", Anchor: "Setup#Linux", Text: "linux"}},
+		{"20260328-2-title#^a1b2", WikiLink{Target: "20260328-2-title", Block: "a1b2"}},
+		{"#Setup", WikiLink{Anchor: "Setup"}},
+		{"folder/doc.pdf|the doc", WikiLink{Target: "folder/doc.pdf", Text: "the doc"}},
+		{`20260328-2\|in a table`, WikiLink{Target: "20260328-2", Text: "in a table"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got := ParseWikiLink(tt.input)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("ParseWikiLink() diff (-want, +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(got, ParseWikiLink(got.String())); diff != "" {
+				t.Errorf("ParseWikiLink(String()) diff (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWithLinkTarget(t *testing.T) {
+	got := withLinkTarget(`20260328-2-old\|shown#not-an-anchor`, "20260328-2-new")
+	if want := `20260328-2-new\|shown#not-an-anchor`; got != want {
+		t.Errorf("withLinkTarget() = %q, want %q", got, want)
+	}
+}
+
+func TestNoteAnchors(t *testing.T) {
+	a := parseAnchors("# Getting Started\n\nSome text. ^intro\n\n```\n## Fenced\nx ^fenced\n```\n\n## Setup & Linux ##\n- item ^item-1")
+
+	tests := []struct {
+		link string
+		want bool
+	}{
+		{"x", true},
+		{"x#Getting Started", true},
+		{"x#getting-started", true},
+		{"x#Getting Started#Setup & Linux", true},
+		{"x#setup-linux", true},
+		{"x#Fenced", false},
+		{"x#Missing", false},
+		{"x#^intro", true},
+		{"x#^item-1", true},
+		{"x#^fenced", false},
+		{"x#^intro2", false},
+	}
+	for _, tt := range tests {
+		if got := a.has(ParseWikiLink(tt.link)); got != tt.want {
+			t.Errorf("has(%q) = %v, want %v", tt.link, got, tt.want)
+		}
+	}
+}
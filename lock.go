@@ -0,0 +1,134 @@
+package gonotes
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// stateDirName is the directory at the vault root holding gonotes' own
+// state, such as the search index and the vault lock.
+const stateDirName = ".gonotes"
+
+// Vault lock timing. The holder of the lock touches it every lockRefresh,
+// so a lock older than lockStaleAge is assumed to be left behind by a
+// crashed process and is broken.
+const (
+	lockRetryDelay = 10 * time.Millisecond
+	lockTimeout    = 10 * time.Second
+	lockStaleAge   = 30 * time.Second
+	lockRefresh    = lockStaleAge / 3
+)
+
+// maxIDAttempts bounds how often ID allocation retries after finding the
+// chosen name already taken.
+const maxIDAttempts = 100
+
+func stateDir(baseDir string) string {
+	return filepath.Join(baseDir, stateDirName)
+}
+
+// lockVault takes the vault-wide lock used to serialize ID allocation
+// between concurrent gonotes processes. The returned function releases it.
+func lockVault(baseDir string) (func(), error) {
+	dir := stateDir(baseDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("lock vault: %w", err)
+	}
+	path := filepath.Join(dir, "lock")
+
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err == nil {
+			_, werr := f.WriteString(strconv.Itoa(os.Getpid()) + "\n")
+			info, serr := f.Stat()
+			cerr := f.Close()
+			if err := errors.Join(werr, serr, cerr); err != nil {
+				_ = os.Remove(path)
+				return nil, fmt.Errorf("lock vault: %w", err)
+			}
+			return holdLock(path, info), nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("lock vault: %w", err)
+		}
+
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > lockStaleAge {
+			if breakStaleLock(path, info) {
+				continue
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("lock vault: timed out waiting for %s", path)
+		}
+		time.Sleep(lockRetryDelay)
+	}
+}
+
+// holdLock keeps the lock file at path, described by info, from going
+// stale until the returned function is called, which releases it. Neither
+// touches nor removes a lock that is no longer ours.
+func holdLock(path string, info os.FileInfo) func() {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(lockRefresh)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case now := <-ticker.C:
+				if ownsLock(path, info) {
+					_ = os.Chtimes(path, now, now)
+				}
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+		if ownsLock(path, info) {
+			_ = os.Remove(path)
+		}
+	}
+}
+
+// ownsLock reports whether the file at path is still the lock described by
+// info.
+func ownsLock(path string, info os.FileInfo) bool {
+	cur, err := os.Stat(path)
+	return err == nil && os.SameFile(info, cur)
+}
+
+// breakStaleLock removes the lock file at path if it is still the stale
+// lock described by stale: the same file, not touched since. Waiters break
+// a lock one at a time, each holding the exclusively created breaker file
+// next to it, so none can remove a lock another waiter has just taken. It
+// reports whether it got to check.
+func breakStaleLock(path string, stale os.FileInfo) bool {
+	breaker := path + ".break"
+	f, err := os.OpenFile(breaker, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		// The breaker is only held for a few calls, so an old one was left
+		// by a process that crashed while breaking.
+		if info, err := os.Stat(breaker); err == nil && time.Since(info.ModTime()) > lockStaleAge {
+			_ = os.Remove(breaker)
+		}
+		return false
+	}
+	_ = f.Close()
+	defer os.Remove(breaker)
+
+	cur, err := os.Stat(path)
+	if err == nil && os.SameFile(stale, cur) && cur.ModTime().Equal(stale.ModTime()) {
+		_ = os.Remove(path)
+	}
+	return true
+}
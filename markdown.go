@@ -0,0 +1,654 @@
+package gonotes
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// markdownRenderer converts note bodies to HTML. It covers the subset of
+// CommonMark and GitHub-flavored markdown that notes commonly use: ATX
+// headings, paragraphs, emphasis, strikethrough, code spans, fenced and
+// indented code blocks, block quotes, nested lists with task items, tables,
+// thematic breaks, links, images and autolinks. Raw HTML is escaped rather
+// than passed through.
+type markdownRenderer struct {
+	// wikiLink renders a [[target]] link; embed is set for ![[target]].
+	wikiLink func(target string, embed bool) string
+}
+
+var (
+	reATXHeading  = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	reThematic    = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	reFence       = regexp.MustCompile("^( {0,3})(`{3,}|~{3,})[ \t]*([^`\\s]*)")
+	reListItem    = regexp.MustCompile(`^( {0,3})([-*+]|\d{1,9}[.)])([ \t]+|$)`)
+	reTaskItem    = regexp.MustCompile(`^\[([ xX])\][ \t]+`)
+	reTableDelim  = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	reAutolink    = regexp.MustCompile(`^<((?:https?|mailto):[^<>\s]+)>`)
+	reSafeURL     = regexp.MustCompile(`(?i)^(?:https?:|mailto:|#|[^:]*$|[^:]*[/?#])`)
+	reHeadingSlug = regexp.MustCompile(`[^\pL\pN]+`)
+)
+
+// render converts a markdown document to HTML.
+func (r *markdownRenderer) render(src string) string {
+	var b strings.Builder
+	r.renderBlocks(&b, strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n"), false)
+	return b.String()
+}
+
+// renderBlocks renders lines as a sequence of blocks. With tight, a lone
+// paragraph is rendered without <p> tags, as in tight list items.
+func (r *markdownRenderer) renderBlocks(b *strings.Builder, lines []string, tight bool) {
+	var para []string
+	flush := func() {
+		if len(para) == 0 {
+			return
+		}
+		text := r.renderInline(strings.Join(para, "\n"))
+		if tight {
+			b.WriteString(text)
+		} else {
+			b.WriteString("<p>" + text + "</p>\n")
+		}
+		para = nil
+	}
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+
+		if strings.TrimSpace(line) == "" {
+			flush()
+			i++
+			continue
+		}
+
+		if m := reFence.FindStringSubmatch(line); m != nil {
+			flush()
+			i = r.renderFence(b, lines, i, m)
+			continue
+		}
+
+		if m := reATXHeading.FindStringSubmatch(line); m != nil {
+			flush()
+			level := len(m[1])
+			text := strings.TrimSpace(m[2])
+			id := headingID(text)
+			b.WriteString("<h" + strconv.Itoa(level))
+			if id != "" {
+				b.WriteString(` id="` + html.EscapeString(id) + `"`)
+			}
+			b.WriteString(">" + r.renderInline(text) + "</h" + strconv.Itoa(level) + ">\n")
+			i++
+			continue
+		}
+
+		if reThematic.MatchString(line) {
+			flush()
+			b.WriteString("<hr>\n")
+			i++
+			continue
+		}
+
+		if strings.HasPrefix(strings.TrimLeft(line, " "), ">") && indentOf(line) < 4 {
+			flush()
+			var quoted []string
+			for ; i < len(lines) && strings.TrimSpace(lines[i]) != ""; i++ {
+				l := strings.TrimLeft(lines[i], " ")
+				if rest, ok := strings.CutPrefix(l, ">"); ok {
+					l = strings.TrimPrefix(rest, " ")
+				}
+				quoted = append(quoted, l)
+			}
+			b.WriteString("<blockquote>\n")
+			r.renderBlocks(b, quoted, false)
+			b.WriteString("</blockquote>\n")
+			continue
+		}
+
+		if reListItem.MatchString(line) {
+			flush()
+			i = r.renderList(b, lines, i)
+			continue
+		}
+
+		if len(para) == 0 && indentOf(line) >= 4 {
+			var code []string
+			for ; i < len(lines); i++ {
+				if strings.TrimSpace(lines[i]) != "" && indentOf(lines[i]) < 4 {
+					break
+				}
+				code = append(code, stripIndent(lines[i], 4))
+			}
+			for len(code) > 0 && strings.TrimSpace(code[len(code)-1]) == "" {
+				code = code[:len(code)-1]
+			}
+			b.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "\n</code></pre>\n")
+			continue
+		}
+
+		if len(para) == 0 && strings.Contains(line, "|") && i+1 < len(lines) && reTableDelim.MatchString(lines[i+1]) {
+			i = r.renderTable(b, lines, i)
+			continue
+		}
+
+		para = append(para, strings.TrimLeft(line, " \t"))
+		i++
+	}
+	flush()
+}
+
+// renderFence renders the fenced code block opening at lines[i] and
+// returns the index of the line after it.
+func (r *markdownRenderer) renderFence(b *strings.Builder, lines []string, i int, m []string) int {
+	indent, fence, lang := len(m[1]), m[2], m[3]
+	var code []string
+	i++
+	for ; i < len(lines); i++ {
+		l := strings.TrimLeft(lines[i], " ")
+		if strings.HasPrefix(l, fence) && strings.TrimSpace(strings.TrimLeft(l, fence[:1])) == "" {
+			i++
+			break
+		}
+		code = append(code, stripIndent(lines[i], indent))
+	}
+
+	b.WriteString("<pre><code")
+	if lang != "" {
+		b.WriteString(` class="language-` + html.EscapeString(lang) + `"`)
+	}
+	b.WriteString(">")
+	if len(code) > 0 {
+		b.WriteString(html.EscapeString(strings.Join(code, "\n")) + "\n")
+	}
+	b.WriteString("</code></pre>\n")
+	return i
+}
+
+// renderList renders the list starting at lines[i] and returns the index
+// of the line after it. Items continue over lines indented past the marker;
+// a blank line between items makes the list loose.
+func (r *markdownRenderer) renderList(b *strings.Builder, lines []string, i int) int {
+	first := reListItem.FindStringSubmatch(lines[i])
+	ordered := first[2][0] >= '0' && first[2][0] <= '9'
+	delim := first[2][len(first[2])-1]
+
+	type item struct{ lines []string }
+	var items []item
+	loose := false
+
+	for i < len(lines) {
+		m := reListItem.FindStringSubmatch(lines[i])
+		if m == nil {
+			break
+		}
+		isOrdered := m[2][0] >= '0' && m[2][0] <= '9'
+		if isOrdered != ordered || m[2][len(m[2])-1] != delim {
+			break
+		}
+
+		contentIndent := len(m[0])
+		if m[3] == "" || len(m[3]) > 4 {
+			contentIndent = len(m[1]) + len(m[2]) + 1
+		}
+		it := item{lines: []string{strings.TrimLeft(lines[i][min(len(m[0]), len(lines[i])):], " \t")}}
+		i++
+
+		for i < len(lines) {
+			l := lines[i]
+			if strings.TrimSpace(l) == "" {
+				// A blank line continues the item only if indented content
+				// follows; between items it makes the list loose.
+				j := i
+				for j < len(lines) && strings.TrimSpace(lines[j]) == "" {
+					j++
+				}
+				if j < len(lines) && indentOf(lines[j]) >= contentIndent {
+					for ; i < j; i++ {
+						it.lines = append(it.lines, "")
+					}
+					loose = true
+					continue
+				}
+				if j < len(lines) {
+					if next := reListItem.FindStringSubmatch(lines[j]); next != nil && len(next[1]) == len(m[1]) {
+						loose = true
+						i = j
+					}
+				}
+				break
+			}
+			if indentOf(l) >= contentIndent {
+				it.lines = append(it.lines, stripIndent(l, contentIndent))
+				i++
+				continue
+			}
+			if reListItem.MatchString(l) || reFence.MatchString(l) || reATXHeading.MatchString(l) ||
+				reThematic.MatchString(l) || strings.HasPrefix(strings.TrimLeft(l, " "), ">") {
+				break
+			}
+			// Lazy continuation of the item's paragraph.
+			it.lines = append(it.lines, strings.TrimLeft(l, " \t"))
+			i++
+		}
+		items = append(items, it)
+	}
+
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+		if start, _ := strconv.Atoi(first[2][:len(first[2])-1]); start != 1 {
+			b.WriteString(`<ol start="` + strconv.Itoa(start) + `">` + "\n")
+		} else {
+			b.WriteString("<ol>\n")
+		}
+	} else {
+		b.WriteString("<ul>\n")
+	}
+
+	for _, it := range items {
+		b.WriteString("<li>")
+		if tm := reTaskItem.FindStringSubmatch(it.lines[0]); tm != nil {
+			if tm[1] == " " {
+				b.WriteString(`<input type="checkbox" disabled> `)
+			} else {
+				b.WriteString(`<input type="checkbox" checked disabled> `)
+			}
+			it.lines[0] = it.lines[0][len(tm[0]):]
+		}
+		if loose {
+			b.WriteString("\n")
+		}
+		r.renderBlocks(b, it.lines, !loose)
+		b.WriteString("</li>\n")
+	}
+	b.WriteString("</" + tag + ">\n")
+	return i
+}
+
+// renderTable renders the table whose header is lines[i] and returns the
+// index of the line after it.
+func (r *markdownRenderer) renderTable(b *strings.Builder, lines []string, i int) int {
+	header := splitTableRow(lines[i])
+	delims := splitTableRow(lines[i+1])
+	aligns := make([]string, len(header))
+	for j := range aligns {
+		if j >= len(delims) {
+			break
+		}
+		d := strings.TrimSpace(delims[j])
+		switch {
+		case strings.HasPrefix(d, ":") && strings.HasSuffix(d, ":"):
+			aligns[j] = "center"
+		case strings.HasSuffix(d, ":"):
+			aligns[j] = "right"
+		case strings.HasPrefix(d, ":"):
+			aligns[j] = "left"
+		}
+	}
+
+	cell := func(tag, text string, j int) {
+		b.WriteString("<" + tag)
+		if aligns[j] != "" {
+			b.WriteString(` style="text-align: ` + aligns[j] + `"`)
+		}
+		b.WriteString(">" + r.renderInline(strings.TrimSpace(text)) + "</" + tag + ">")
+	}
+
+	b.WriteString("<table>\n<thead>\n<tr>")
+	for j, h := range header {
+		cell("th", h, j)
+	}
+	b.WriteString("</tr>\n</thead>\n<tbody>\n")
+
+	i += 2
+	for ; i < len(lines) && strings.TrimSpace(lines[i]) != "" && strings.Contains(lines[i], "|"); i++ {
+		row := splitTableRow(lines[i])
+		b.WriteString("<tr>")
+		for j := range header {
+			text := ""
+			if j < len(row) {
+				text = row[j]
+			}
+			cell("td", text, j)
+		}
+		b.WriteString("</tr>\n")
+	}
+	b.WriteString("</tbody>\n</table>\n")
+	return i
+}
+
+// splitTableRow splits a table row on unescaped pipes outside code spans,
+// dropping the optional leading and trailing pipe.
+func splitTableRow(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+
+	var cells []string
+	var cur strings.Builder
+	inCode := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case c == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cur.WriteByte('|')
+			i++
+		case c == '`':
+			inCode = !inCode
+			cur.WriteByte(c)
+		case c == '|' && !inCode:
+			cells = append(cells, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	return append(cells, cur.String())
+}
+
+// renderInline renders the inline content of a block.
+func (r *markdownRenderer) renderInline(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		rest := s[i:]
+
+		switch {
+		case c == '\\' && i+1 < len(s) && s[i+1] == '\n':
+			b.WriteString("<br>\n")
+			i += 2
+			continue
+		case c == '\\' && i+1 < len(s) && strings.IndexByte("\\`*_{}[]()#+-.!|~<>\"'", s[i+1]) >= 0:
+			b.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+			continue
+
+		case c == '`':
+			n := len(rest) - len(strings.TrimLeft(rest, "`"))
+			fence := rest[:n]
+			if end := strings.Index(rest[n:], fence); end >= 0 && (n+end+n >= len(rest) || rest[n+end+n] != '`') {
+				code := strings.ReplaceAll(rest[n:n+end], "\n", " ")
+				if len(code) > 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.TrimSpace(code) != "" {
+					code = code[1 : len(code)-1]
+				}
+				b.WriteString("<code>" + html.EscapeString(code) + "</code>")
+				i += n + end + n
+				continue
+			}
+			b.WriteString(fence)
+			i += n
+			continue
+
+		case strings.HasPrefix(rest, "![[") || strings.HasPrefix(rest, "[["):
+			embed := c == '!'
+			start := 2
+			if embed {
+				start = 3
+			}
+			if end := strings.Index(rest[start:], "]]"); end > 0 && !strings.Contains(rest[start:start+end], "\n") {
+				target := rest[start : start+end]
+				if r.wikiLink != nil {
+					b.WriteString(r.wikiLink(target, embed))
+				} else {
+					b.WriteString(html.EscapeString(rest[:start+end+2]))
+				}
+				i += start + end + 2
+				continue
+			}
+
+		case c == '!' && strings.HasPrefix(rest, "!["):
+			if text, dest, n, ok := parseInlineLink(rest[1:]); ok {
+				b.WriteString(`<img src="` + html.EscapeString(safeURL(dest)) + `" alt="` + html.EscapeString(text) + `">`)
+				i += 1 + n
+				continue
+			}
+
+		case c == '[':
+			if text, dest, n, ok := parseInlineLink(rest); ok {
+				b.WriteString(`<a href="` + html.EscapeString(safeURL(dest)) + `">` + r.renderInline(text) + "</a>")
+				i += n
+				continue
+			}
+
+		case c == '<':
+			if m := reAutolink.FindStringSubmatch(rest); m != nil {
+				b.WriteString(`<a href="` + html.EscapeString(m[1]) + `">` + html.EscapeString(m[1]) + "</a>")
+				i += len(m[0])
+				continue
+			}
+
+		case c == '*' || c == '_' || c == '~':
+			if out, n, ok := r.renderEmphasis(s, i); ok {
+				b.WriteString(out)
+				i += n
+				continue
+			}
+			n := len(rest) - len(strings.TrimLeft(rest, string(c)))
+			b.WriteString(rest[:n])
+			i += n
+			continue
+
+		case c == '\n':
+			// Two trailing spaces make a hard line break.
+			if strings.HasSuffix(b.String(), "  ") {
+				trimmed := strings.TrimRight(b.String(), " ")
+				b.Reset()
+				b.WriteString(trimmed + "<br>")
+			}
+			b.WriteByte('\n')
+			i++
+			continue
+		}
+
+		b.WriteString(html.EscapeString(s[i : i+1]))
+		i++
+	}
+	return b.String()
+}
+
+// renderEmphasis renders *em*, **strong**, ***both***, the _underscore_
+// forms and ~~strikethrough~~ starting at s[i]. Underscores only count at
+// word boundaries, so snake_case stays intact.
+func (r *markdownRenderer) renderEmphasis(s string, i int) (string, int, bool) {
+	c := s[i]
+	rest := s[i:]
+	n := len(rest) - len(strings.TrimLeft(rest, string(c)))
+	if c == '~' && n != 2 {
+		return "", 0, false
+	}
+	n = min(n, 3)
+	delim := rest[:n]
+
+	if n >= len(rest) || rest[n] == ' ' || rest[n] == '\n' {
+		return "", 0, false
+	}
+	if c == '_' && i > 0 && isWordByte(s[i-1]) {
+		return "", 0, false
+	}
+
+	for from := n; from < len(rest); {
+		end := strings.Index(rest[from:], delim)
+		if end < 0 {
+			return "", 0, false
+		}
+		end += from
+		after := end + n
+		if rest[end-1] == ' ' || (after < len(rest) && rest[after] == c) ||
+			(c == '_' && after < len(rest) && isWordByte(rest[after])) {
+			from = end + 1
+			continue
+		}
+
+		inner := r.renderInline(rest[n:end])
+		switch {
+		case c == '~':
+			return "<del>" + inner + "</del>", after, true
+		case n == 1:
+			return "<em>" + inner + "</em>", after, true
+		case n == 2:
+			return "<strong>" + inner + "</strong>", after, true
+		default:
+			return "<em><strong>" + inner + "</strong></em>", after, true
+		}
+	}
+	return "", 0, false
+}
+
+// parseInlineLink parses [text](dest "title") at the start of s and returns
+// the link text, destination and the number of bytes consumed.
+func parseInlineLink(s string) (text, dest string, n int, ok bool) {
+	depth := 0
+	closeText := -1
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				closeText = i
+			}
+		}
+		if closeText >= 0 {
+			break
+		}
+	}
+	if closeText < 0 || closeText+1 >= len(s) || s[closeText+1] != '(' {
+		return "", "", 0, false
+	}
+
+	end := strings.IndexByte(s[closeText+2:], ')')
+	if end < 0 {
+		return "", "", 0, false
+	}
+	inner := strings.TrimSpace(s[closeText+2 : closeText+2+end])
+	if strings.HasPrefix(inner, "<") {
+		if e := strings.IndexByte(inner, '>'); e > 0 {
+			inner = inner[1:e]
+		}
+	} else if sp := strings.IndexAny(inner, " \t"); sp >= 0 {
+		inner = inner[:sp]
+	}
+	return s[1:closeText], inner, closeText + 2 + end + 1, true
+}
+
+// safeURL returns url unless it uses a scheme other than http, https or
+// mailto, in which case it is neutralized.
+func safeURL(url string) string {
+	if reSafeURL.MatchString(url) {
+		return url
+	}
+	return "#"
+}
+
+// headingID returns the anchor id for a heading, derived from its text the
+// way most markdown tools do: lowercased words joined by dashes.
+func headingID(text string) string {
+	return strings.Trim(reHeadingSlug.ReplaceAllString(strings.ToLower(text), "-"), "-")
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// indentOf returns the width of the leading whitespace of line, counting
+// tabs as four columns.
+func indentOf(line string) int {
+	n := 0
+	for _, c := range line {
+		switch c {
+		case ' ':
+			n++
+		case '\t':
+			n += 4 - n%4
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+// stripIndent removes up to n columns of leading whitespace from line.
+func stripIndent(line string, n int) string {
+	col := 0
+	for i, c := range line {
+		if col >= n {
+			return line[i:]
+		}
+		switch c {
+		case ' ':
+			col++
+		case '\t':
+			col += 4 - col%4
+		default:
+			return line[i:]
+		}
+	}
+	return ""
+}
+
+// mapProse returns src with fn applied to every run of text outside fenced
+// code blocks and code spans; code is passed through unchanged. Each run is
+// at most one line.
+func mapProse(src string, fn func(text string) string) string {
+	lines := strings.Split(src, "\n")
+	code := fencedLines(lines)
+	for i, line := range lines {
+		if !code[i] {
+			lines[i] = mapOutsideCodeSpans(line, fn)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// fencedLines reports for each line whether it belongs to a fenced code
+// block, counting the fences themselves.
+func fencedLines(lines []string) []bool {
+	code := make([]bool, len(lines))
+	fence := ""
+	for i, line := range lines {
+		if fence != "" {
+			code[i] = true
+			l := strings.TrimLeft(line, " ")
+			if strings.HasPrefix(l, fence) && strings.TrimSpace(strings.TrimLeft(l, fence[:1])) == "" {
+				fence = ""
+			}
+			continue
+		}
+		if m := reFence.FindStringSubmatch(line); m != nil {
+			code[i] = true
+			fence = m[2]
+		}
+	}
+	return code
+}
+
+// mapOutsideCodeSpans applies fn to the parts of line outside code spans.
+func mapOutsideCodeSpans(line string, fn func(text string) string) string {
+	var b strings.Builder
+	start := 0
+	for i := 0; i < len(line); {
+		if line[i] != '`' {
+			i++
+			continue
+		}
+		n := len(line[i:]) - len(strings.TrimLeft(line[i:], "`"))
+		fence := line[i : i+n]
+		end := strings.Index(line[i+n:], fence)
+		if end < 0 {
+			i += n
+			continue
+		}
+		b.WriteString(fn(line[start:i]))
+		closeAt := i + n + end + n
+		b.WriteString(line[i:closeAt])
+		i, start = closeAt, closeAt
+	}
+	b.WriteString(fn(line[start:]))
+	return b.String()
+}
@@ -0,0 +1,54 @@
+package gonotes
+
+import (
+	"html"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	r := &markdownRenderer{
+		wikiLink: func(target string, embed bool) string {
+			if embed {
+				return "<embed:" + html.EscapeString(target) + ">"
+			}
+			return "<link:" + html.EscapeString(target) + ">"
+		},
+	}
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"heading", "## Big *idea* ##", "<h2 id=\"big-idea\">Big <em>idea</em></h2>\n"},
+		{"paragraph", "one\ntwo\n\nthree", "<p>one\ntwo</p>\n<p>three</p>\n"},
+		{"emphasis", "**bold** and _it_ and snake_case_name and ~~gone~~",
+			"<p><strong>bold</strong> and <em>it</em> and snake_case_name and <del>gone</del></p>\n"},
+		{"escaping", "a < b & <script>", "<p>a &lt; b &amp; &lt;script&gt;</p>\n"},
+		{"code span keeps links", "`[[x]]` and [[y]]", "<p><code>[[x]]</code> and <link:y></p>\n"},
+		{"embed", "![[a/b.png]]", "<p><embed:a/b.png></p>\n"},
+		{"fenced code", "```go\nx := 1 < 2\n```", "<pre><code class=\"language-go\">x := 1 &lt; 2\n</code></pre>\n"},
+		{"link", "[site](https://example.com \"t\")", "<p><a href=\"https://example.com\">site</a></p>\n"},
+		{"unsafe link", "[x](javascript:void)", "<p><a href=\"#\">x</a></p>\n"},
+		{"image", "![alt](img.png)", "<p><img src=\"img.png\" alt=\"alt\"></p>\n"},
+		{"autolink", "<https://example.com>", "<p><a href=\"https://example.com\">https://example.com</a></p>\n"},
+		{"tight list", "- a\n- b\n  - c\n", "<ul>\n<li>a</li>\n<li>b<ul>\n<li>c</li>\n</ul>\n</li>\n</ul>\n"},
+		{"ordered list", "3. a\n4. b", "<ol start=\"3\">\n<li>a</li>\n<li>b</li>\n</ol>\n"},
+		{"task list", "- [x] done\n- [ ] todo",
+			"<ul>\n<li><input type=\"checkbox\" checked disabled> done</li>\n<li><input type=\"checkbox\" disabled> todo</li>\n</ul>\n"},
+		{"blockquote", "> quoted\n> text", "<blockquote>\n<p>quoted\ntext</p>\n</blockquote>\n"},
+		{"thematic break", "a\n\n---\n\nb", "<p>a</p>\n<hr>\n<p>b</p>\n"},
+		{"table", "| a | b |\n|---|--:|\n| 1 | [[n]] |",
+			"<table>\n<thead>\n<tr><th>a</th><th style=\"text-align: right\">b</th></tr>\n</thead>\n<tbody>\n<tr><td>1</td><td style=\"text-align: right\"><link:n></td></tr>\n</tbody>\n</table>\n"},
+		{"hard break", "a  \nb", "<p>a<br>\nb</p>\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if diff := cmp.Diff(tt.want, r.render(tt.in)); diff != "" {
+				t.Errorf("render() diff (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -13,20 +13,45 @@ import (
 
 var reWikiLink = regexp.MustCompile(`\[\[([^\]]+)\]\]`)
 
+// reHashtag matches an inline #tag at the start of a line or after
+// whitespace. Tags may be nested with slashes, like #go/generics.
+var reHashtag = regexp.MustCompile(`(?:^|\s)#([\pL\pN_\-/]+)`)
+
 const frontmatterSep = "---"
 
 // dateLayout is the Go reference time format used for note dates.
 const dateLayout = "2006-01-02 15:04:05"
 
+// dateLayouts are the date formats ParseDate accepts besides the configured
+// layout, most specific first. They cover RFC 3339 and the YAML timestamp
+// forms; fractional seconds are accepted after any seconds field.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 Z07:00",
+	"2006-01-02 15:04:05 -07",
+	dateLayout,
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
 // Note represents a single markdown note. The Frontmatter field is the
 // source of truth; the other fields are derived from it by deriveFields.
 type Note struct {
-	Frontmatter   *Frontmatter
-	ID            string
-	Date          time.Time
-	Title         string
-	Slug          string
-	Tags          []string
+	Frontmatter *Frontmatter
+	ID          string
+	Date        time.Time
+	Title       string
+	Slug        string
+	Tags        []string
+	// Aliases are other names [[links]] can use for the note, besides
+	// its ID and title.
+	Aliases []string
+	// BodyTags are the inline #tags in Body, which are also in Tags. They
+	// are only parsed when Config.InlineTags is set.
+	BodyTags      []string
 	Body          string
 	InternalLinks []string
 	IgnoreLinks   []string
@@ -41,6 +66,11 @@ func NewNote() *Note {
 // ReadNote parses a note from r. The id is set on the returned Note but is
 // not expected to come from the file content itself.
 func ReadNote(id string, r io.Reader) (*Note, error) {
+	return readNote(id, r, nil)
+}
+
+// readNote is ReadNote with the slug and date rules of cfg.
+func readNote(id string, r io.Reader, cfg *Config) (*Note, error) {
 	fm, body, err := splitFrontmatterBody(r)
 	if err != nil {
 		return nil, fmt.Errorf("read note: %w", err)
@@ -58,47 +88,90 @@ func ReadNote(id string, r io.Reader) (*Note, error) {
 		}
 	}
 
-	note.deriveFields()
+	note.deriveFields(cfg)
 	return note, nil
 }
 
-// deriveFields populates the computed fields (Title, Slug, Tags, Date,
-// InternalLinks, IgnoreLinks) from Frontmatter and Body.
-func (n *Note) deriveFields() {
+// deriveFields populates the computed fields (Title, Slug, Tags, Aliases,
+// Date, InternalLinks, IgnoreLinks) from Frontmatter and Body, using the
+// slug and date rules of cfg.
+func (n *Note) deriveFields(cfg *Config) {
+	cfg = cfg.orDefault()
+
 	if title, ok := n.Frontmatter.Get("title"); ok {
 		n.Title = title
-		n.Slug = slugify(title)
+		n.Slug = Slugify(title, cfg.slugOptions())
 	} else {
 		n.Title = ""
 		n.Slug = ""
 	}
 
-	if tags, ok := n.Frontmatter.Get("tags"); ok {
-		n.Tags = ParseTags(tags)
-	} else {
-		n.Tags = nil
+	n.Tags = listField(n.Frontmatter, "tags")
+	n.BodyTags = nil
+	if cfg.InlineTags {
+		n.BodyTags = parseHashtags(n.Body)
+		n.Tags = dedupStrings(append(n.Tags, n.BodyTags...))
 	}
 
+	n.Aliases = listField(n.Frontmatter, "aliases")
+
 	if dateStr, ok := n.Frontmatter.Get("date"); ok {
-		t, err := time.Parse(dateLayout, dateStr)
-		if err != nil {
-			n.Date = time.Time{}
-		} else {
-			n.Date = t
-		}
+		n.Date, _ = parseNoteDate(dateStr, cfg)
 	} else {
 		n.Date = time.Time{}
 	}
 
-	if ignoreLinks, ok := n.Frontmatter.Get("ignore-links"); ok {
-		n.IgnoreLinks = ParseTags(ignoreLinks)
-	} else {
-		n.IgnoreLinks = nil
-	}
+	n.IgnoreLinks = listField(n.Frontmatter, "ignore-links")
 
 	n.InternalLinks = parseInternalLinks(n.Body)
 }
 
+// ParseDate parses a note date. Besides the default layout it accepts plain
+// dates, RFC 3339 timestamps and YAML timestamps. A date with an offset
+// keeps it; a date without one is a wall-clock time and is returned in UTC.
+// Either way the calendar day as written is what t.Format shows, and that
+// day is used for the date view.
+func ParseDate(s string) (time.Time, error) {
+	return parseNoteDate(s, nil)
+}
+
+// parseNoteDate is ParseDate trying the configured layout first.
+func parseNoteDate(s string, cfg *Config) (time.Time, error) {
+	cfg = cfg.orDefault()
+	s = strings.TrimSpace(s)
+	// YAML allows a lowercase t between date and time.
+	if len(s) > 10 && s[10] == 't' {
+		s = s[:10] + "T" + s[11:]
+	}
+	for _, layout := range append([]string{cfg.DateLayout}, dateLayouts...) {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date %q", s)
+}
+
+// dateError reports why a note's date field cannot be parsed, or nil if it
+// parses or is not set.
+func dateError(n *Note, cfg *Config) error {
+	v := n.Frontmatter.valueNode("date")
+	if v == nil || isNull(v) {
+		return nil
+	}
+	if v.Kind != yaml.ScalarNode {
+		return fmt.Errorf("date is not a single value")
+	}
+	_, err := parseNoteDate(v.Value, cfg)
+	return err
+}
+
+// wallClock returns t's date and time of day as written, in UTC, so that
+// times with different offsets compare by their calendar values.
+func wallClock(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
 func splitFrontmatterBody(r io.Reader) (fm string, body string, err error) {
 	scanner := bufio.NewScanner(r)
 
@@ -131,6 +204,28 @@ func splitFrontmatterBody(r io.Reader) (fm string, body string, err error) {
 	return fm, body, nil
 }
 
+// listField returns the values of a multi-value field such as tags, which
+// may be written as a YAML list or as a comma- or space-separated string.
+// Values are split and deduplicated like ParseTags.
+func listField(fm *Frontmatter, key string) []string {
+	values, _ := fm.GetList(key)
+	return ParseTags(strings.Join(values, ","))
+}
+
+// setListField writes a multi-value field, keeping a YAML list a list and
+// otherwise writing a comma-separated string. Empty values remove the field.
+func setListField(fm *Frontmatter, key string, values []string) {
+	if len(values) == 0 {
+		fm.Unset(key)
+		return
+	}
+	if n := fm.valueNode(key); n != nil && n.Kind == yaml.SequenceNode {
+		fm.SetList(key, values)
+		return
+	}
+	fm.Set(key, FormatTags(values))
+}
+
 // ParseTags splits a tag string by commas and/or spaces, trims whitespace,
 // and deduplicates. Returns nil for empty input.
 func ParseTags(s string) []string {
@@ -157,18 +252,44 @@ func dedupStrings(ss []string) []string {
 	return out
 }
 
+// parseInternalLinks returns the [[link]] texts in body, in order. Links in
+// code spans and fenced code are ignored, as rebuilds do not rewrite them.
 func parseInternalLinks(body string) []string {
-	matches := reWikiLink.FindAllStringSubmatch(body, -1)
-	if len(matches) == 0 {
-		return nil
-	}
-	links := make([]string, len(matches))
-	for i, m := range matches {
-		links[i] = m[1]
-	}
+	var links []string
+	mapProse(body, func(text string) string {
+		for _, m := range reWikiLink.FindAllStringSubmatch(text, -1) {
+			links = append(links, m[1])
+		}
+		return text
+	})
 	return links
 }
 
+// parseHashtags returns the inline #tags in body, in order of first
+// appearance. Tags in code spans, fenced code and heading lines are
+// ignored, as are URL fragments and purely numeric words such as #1.
+func parseHashtags(body string) []string {
+	lines := strings.Split(body, "\n")
+	for i, line := range lines {
+		if reATXHeading.MatchString(line) {
+			lines[i] = ""
+		}
+	}
+
+	var tags []string
+	mapProse(strings.Join(lines, "\n"), func(text string) string {
+		for _, m := range reHashtag.FindAllStringSubmatch(text, -1) {
+			tag := strings.Trim(m[1], "/")
+			if strings.Trim(tag, "0123456789") == "" {
+				continue
+			}
+			tags = append(tags, tag)
+		}
+		return text
+	})
+	return dedupStrings(tags)
+}
+
 func (n *Note) Markdown() string {
 	var b strings.Builder
 
@@ -206,11 +327,18 @@ type FrontmatterField struct {
 // applies the options, and returns the prepared note. Options mutate the
 // frontmatter; derived fields are populated once at the end via deriveFields.
 func Prepare(r io.Reader, opts PrepareOptions) (*Note, error) {
+	return prepare(r, opts, nil)
+}
+
+// prepare is Prepare with the date layout and slug rules of cfg.
+func prepare(r io.Reader, opts PrepareOptions, cfg *Config) (*Note, error) {
+	cfg = cfg.orDefault()
+
 	var note *Note
 	var err error
 
 	if r != nil {
-		note, err = ReadNote("", r)
+		note, err = readNote("", r, cfg)
 		if err != nil {
 			return nil, fmt.Errorf("prepare: %w", err)
 		}
@@ -223,14 +351,8 @@ func Prepare(r io.Reader, opts PrepareOptions) (*Note, error) {
 	}
 
 	if len(opts.Tags) > 0 {
-		existing, _ := note.Frontmatter.Get("tags")
-		merged := append(ParseTags(existing), opts.Tags...)
-		merged = dedupStrings(merged)
-		if len(merged) == 0 {
-			note.Frontmatter.Unset("tags")
-		} else {
-			note.Frontmatter.Set("tags", FormatTags(merged))
-		}
+		merged := append(listField(note.Frontmatter, "tags"), opts.Tags...)
+		setListField(note.Frontmatter, "tags", dedupStrings(merged))
 	}
 
 	if _, ok := note.Frontmatter.Get("date"); !ok {
@@ -238,14 +360,16 @@ func Prepare(r io.Reader, opts PrepareOptions) (*Note, error) {
 		if opts.Now != nil {
 			now = opts.Now
 		}
-		note.Frontmatter.Set("date", now().Local().Format(dateLayout))
+		// The time is written in its own zone, which for the CLI is local
+		// time, so the date matches the ID prefix CreateNote picks.
+		note.Frontmatter.Set("date", now().Format(cfg.DateLayout))
 	}
 
 	for _, f := range opts.ExtraFrontmatter {
 		note.Frontmatter.Set(f.Key, f.Value)
 	}
 
-	note.deriveFields()
+	note.deriveFields(cfg)
 	return note, nil
 }
 
@@ -180,6 +180,31 @@ Body without title in frontmatter.`,
 			input:   "---\n\t bad yaml: [unterminated\n---\n",
 			wantErr: true,
 		},
+		{
+			name: "tags flow list",
+			id:   "20260328-15",
+			input: `---
+tags: [go, tools/cli]
+---`,
+			want: &Note{
+				ID:   "20260328-15",
+				Tags: []string{"go", "tools/cli"},
+			},
+		},
+		{
+			name: "tags block list",
+			id:   "20260328-16",
+			input: `---
+tags:
+  - go
+  - tools/cli
+  - go
+---`,
+			want: &Note{
+				ID:   "20260328-16",
+				Tags: []string{"go", "tools/cli"},
+			},
+		},
 		{
 			name: "single tag no comma",
 			id:   "20260328-13",
@@ -453,6 +478,50 @@ Body.`))
 	}
 }
 
+func TestParseDate(t *testing.T) {
+	plus2 := time.FixedZone("", 2*60*60)
+	minus5 := time.FixedZone("", -5*60*60)
+
+	tests := []struct {
+		input   string
+		want    time.Time
+		wantDay string
+	}{
+		{"2026-03-28 14:30:00", time.Date(2026, 3, 28, 14, 30, 0, 0, time.UTC), "2026-03-28"},
+		{"2026-03-28", time.Date(2026, 3, 28, 0, 0, 0, 0, time.UTC), "2026-03-28"},
+		{"2026-03-28 14:30", time.Date(2026, 3, 28, 14, 30, 0, 0, time.UTC), "2026-03-28"},
+		{"2026-03-28T14:30:00", time.Date(2026, 3, 28, 14, 30, 0, 0, time.UTC), "2026-03-28"},
+		{"2026-03-28T23:30:00+02:00", time.Date(2026, 3, 28, 23, 30, 0, 0, plus2), "2026-03-28"},
+		{"2026-03-28T01:30:00.5-05:00", time.Date(2026, 3, 28, 1, 30, 0, 5e8, minus5), "2026-03-28"},
+		{"2026-03-28t01:30:00Z", time.Date(2026, 3, 28, 1, 30, 0, 0, time.UTC), "2026-03-28"},
+		{"2026-03-28 01:30:00 -05", time.Date(2026, 3, 28, 1, 30, 0, 0, minus5), "2026-03-28"},
+		{"2026-03-28 01:30:00.10 -05:00", time.Date(2026, 3, 28, 1, 30, 0, 1e8, minus5), "2026-03-28"},
+		{" 2026-03-28 ", time.Date(2026, 3, 28, 0, 0, 0, 0, time.UTC), "2026-03-28"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseDate(tt.input)
+			if err != nil {
+				t.Fatalf("ParseDate() err = %q", err)
+			}
+			// Comparing the RFC 3339 form checks the offset too.
+			if g, w := got.Format(time.RFC3339Nano), tt.want.Format(time.RFC3339Nano); g != w {
+				t.Errorf("ParseDate() = %s, want %s", g, w)
+			}
+			if day := got.Format("2006-01-02"); day != tt.wantDay {
+				t.Errorf("ParseDate() day = %s, want %s", day, tt.wantDay)
+			}
+		})
+	}
+
+	for _, input := range []string{"", "not-a-date", "28.03.2026", "2026-13-01"} {
+		if _, err := ParseDate(input); err == nil {
+			t.Errorf("ParseDate(%q) err = <nil>, want error", input)
+		}
+	}
+}
+
 func TestReadNoteDateZero(t *testing.T) {
 	note, err := ReadNote("20260328-1", strings.NewReader(`---
 title: No Date
@@ -546,3 +615,44 @@ func TestDedupStrings(t *testing.T) {
 		})
 	}
 }
+
+func TestParseHashtags(t *testing.T) {
+	body := "# Heading\n## Plans #q3\n\n#go/generics and #rust, not a#b or #1 or [x](#frag) or https://x.dev/#id.\n" +
+		"`#code` but #after-code\n```\n#fenced\n```\n#go/generics again, #nested/ trims."
+	want := []string{"go/generics", "rust", "after-code", "nested"}
+	if diff := cmp.Diff(want, parseHashtags(body)); diff != "" {
+		t.Errorf("parseHashtags() diff (-want, +got):\n%s", diff)
+	}
+}
+
+func TestReadNoteInlineTags(t *testing.T) {
+	input := `---
+tags: work, go
+---
+
+Notes on #go and #go/generics.`
+
+	cfg := DefaultConfig()
+	note, err := readNote("test", strings.NewReader(input), cfg)
+	if err != nil {
+		t.Fatalf("readNote() err = %q", err)
+	}
+	if diff := cmp.Diff([]string{"work", "go"}, note.Tags); diff != "" {
+		t.Errorf("Tags without inline_tags (-want +got):\n%s", diff)
+	}
+	if note.BodyTags != nil {
+		t.Errorf("BodyTags = %v, want nil", note.BodyTags)
+	}
+
+	cfg.InlineTags = true
+	note, err = readNote("test", strings.NewReader(input), cfg)
+	if err != nil {
+		t.Fatalf("readNote() err = %q", err)
+	}
+	if diff := cmp.Diff([]string{"work", "go", "go/generics"}, note.Tags); diff != "" {
+		t.Errorf("Tags mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"go", "go/generics"}, note.BodyTags); diff != "" {
+		t.Errorf("BodyTags mismatch (-want +got):\n%s", diff)
+	}
+}
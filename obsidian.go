@@ -0,0 +1,532 @@
+package gonotes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// reObsidianLink matches an Obsidian [[link]] or ![[embed]].
+var reObsidianLink = regexp.MustCompile(`(!?)\[\[([^\]\n]+)\]\]`)
+
+// ImportedNote is a note an import creates.
+type ImportedNote struct {
+	// Source is the note's path relative to the imported vault.
+	Source string `json:"source"`
+	// Filename is the note's filename in the id dir.
+	Filename string   `json:"filename"`
+	Title    string   `json:"title"`
+	Tags     []string `json:"tags"`
+	// DateFromMtime is set when the note had no usable date and the file's
+	// modification time was used instead.
+	DateFromMtime bool `json:"date_from_mtime"`
+
+	note *Note
+}
+
+// ImportedFile is an attachment an import copies into the files dir.
+type ImportedFile struct {
+	Source string `json:"source"`
+	// Dest is the copy's path relative to the files dir.
+	Dest string `json:"dest"`
+}
+
+// ObsidianImport is the plan for importing an Obsidian vault, as returned
+// by PlanObsidianImport. Nothing is written until ExecuteObsidianImport.
+type ObsidianImport struct {
+	Source      string         `json:"source"`
+	Notes       []ImportedNote `json:"notes"`
+	Attachments []ImportedFile `json:"attachments"`
+	// UnresolvedLinks are links left as they were because they name no
+	// note or attachment in the imported vault. SourceID is the new ID.
+	UnresolvedLinks []BrokenLink `json:"unresolved_links"`
+	// Unreferenced lists attachments that no note links to; they are not
+	// copied.
+	Unreferenced []string    `json:"unreferenced"`
+	Errors       []ScanError `json:"errors"`
+}
+
+// MarshalJSON encodes the plan with empty lists rather than null.
+func (imp ObsidianImport) MarshalJSON() ([]byte, error) {
+	type plan ObsidianImport
+	out := plan(imp)
+	out.Notes = nonNil(out.Notes)
+	for i := range out.Notes {
+		out.Notes[i].Tags = nonNil(out.Notes[i].Tags)
+	}
+	out.Attachments = nonNil(out.Attachments)
+	out.UnresolvedLinks = nonNil(out.UnresolvedLinks)
+	out.Unreferenced = nonNil(out.Unreferenced)
+	out.Errors = nonNil(out.Errors)
+	return json.Marshal(out)
+}
+
+func (imp *ObsidianImport) String() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Notes (%d):\n", len(imp.Notes))
+	for _, n := range imp.Notes {
+		fmt.Fprintf(&b, "  %s -> %s", n.Source, n.Filename)
+		if n.DateFromMtime {
+			b.WriteString(" (dated by mtime)")
+		}
+		b.WriteByte('\n')
+	}
+
+	if len(imp.Attachments) > 0 {
+		fmt.Fprintf(&b, "Attachments (%d):\n", len(imp.Attachments))
+		for _, f := range imp.Attachments {
+			fmt.Fprintf(&b, "  %s -> %s\n", f.Source, f.Dest)
+		}
+	}
+
+	if len(imp.UnresolvedLinks) > 0 {
+		fmt.Fprintf(&b, "Unresolved links (%d):\n", len(imp.UnresolvedLinks))
+		for _, bl := range imp.UnresolvedLinks {
+			fmt.Fprintf(&b, "  %s -> %s\n", bl.SourceID, bl.TargetID)
+		}
+	}
+
+	if len(imp.Unreferenced) > 0 {
+		fmt.Fprintf(&b, "Unreferenced attachments, not copied (%d):\n", len(imp.Unreferenced))
+		for _, name := range imp.Unreferenced {
+			fmt.Fprintf(&b, "  %s\n", name)
+		}
+	}
+
+	if len(imp.Errors) > 0 {
+		fmt.Fprintf(&b, "Errors (%d):\n", len(imp.Errors))
+		for _, e := range imp.Errors {
+			fmt.Fprintf(&b, "  %s: %s\n", e.Filename, e.Message)
+		}
+	}
+
+	return b.String()
+}
+
+// obsidianNote is a markdown file found in an Obsidian vault.
+type obsidianNote struct {
+	rel    string // slash-separated path relative to the vault
+	note   *Note
+	mtimed bool
+	id     string
+	stem   string // new filename without .md
+	folder string // attachment folder name, relative to the files dir
+}
+
+// PlanObsidianImport plans the import of the Obsidian vault at srcDir into
+// the vault at baseDir. Every markdown file becomes a note with an ID
+// derived from its frontmatter date or, failing that, its modification
+// time. Its title is the frontmatter title or the filename. Inline #tags
+// and frontmatter tags are merged into the tags field. [[Note Name]] links,
+// with or without a path, heading or alias, become links to the new note
+// ID; links to other files copy the file into a files/<id>-<slug>/ folder
+// of the first note that links to it and point there. Hidden files and
+// directories, such as .obsidian, are skipped.
+func PlanObsidianImport(baseDir string, cfg *Config, srcDir string) (*ObsidianImport, error) {
+	cfg = cfg.orDefault()
+	if err := checkImportSource(baseDir, cfg, srcDir); err != nil {
+		return nil, fmt.Errorf("plan obsidian import: %w", err)
+	}
+
+	imp := &ObsidianImport{Source: srcDir}
+	var notes []*obsidianNote
+	attachments := map[string]string{} // rel path -> lowercase rel path
+
+	err := filepath.WalkDir(srcDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != srcDir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(srcDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		if !strings.EqualFold(path.Ext(rel), ".md") {
+			attachments[rel] = strings.ToLower(rel)
+			return nil
+		}
+
+		n, err := readObsidianNote(p, rel, cfg)
+		if err != nil {
+			imp.Errors = append(imp.Errors, ScanError{Filename: rel, Message: err.Error()})
+			return nil
+		}
+		if n.mtimed {
+			if date, ok := n.note.Frontmatter.Get("date"); ok && date != "" {
+				imp.Errors = append(imp.Errors, ScanError{
+					Filename: rel,
+					Message:  fmt.Sprintf("unrecognized date %q; using file modification time", date),
+				})
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			n.note.Frontmatter.Set("date", info.ModTime().Format(cfg.DateLayout))
+			n.note.Date = info.ModTime()
+		}
+		notes = append(notes, n)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("plan obsidian import: %w", err)
+	}
+
+	if err := assignImportIDs(baseDir, cfg, notes); err != nil {
+		return nil, fmt.Errorf("plan obsidian import: %w", err)
+	}
+
+	r := newObsidianResolver(notes, attachments)
+	copied := map[string]string{} // source rel path -> dest rel path
+	for _, n := range notes {
+		body := mapProse(n.note.Body, func(text string) string {
+			return reObsidianLink.ReplaceAllStringFunc(text, func(link string) string {
+				m := reObsidianLink.FindStringSubmatch(link)
+				out, ok := r.convert(n, m[1] == "!", m[2], copied)
+				if !ok {
+					imp.UnresolvedLinks = append(imp.UnresolvedLinks, BrokenLink{SourceID: n.id, TargetID: m[2]})
+				}
+				return out
+			})
+		})
+		n.note.Body = body
+		n.note.deriveFields(cfg)
+		n.note.ID = n.id
+
+		imp.Notes = append(imp.Notes, ImportedNote{
+			Source:        n.rel,
+			Filename:      n.stem + ".md",
+			Title:         n.note.Title,
+			Tags:          n.note.Tags,
+			DateFromMtime: n.mtimed,
+			note:          n.note,
+		})
+	}
+
+	for src, dest := range copied {
+		imp.Attachments = append(imp.Attachments, ImportedFile{Source: src, Dest: dest})
+	}
+	sort.Slice(imp.Attachments, func(i, j int) bool { return imp.Attachments[i].Dest < imp.Attachments[j].Dest })
+	for rel := range attachments {
+		if _, ok := copied[rel]; !ok {
+			imp.Unreferenced = append(imp.Unreferenced, rel)
+		}
+	}
+	sort.Strings(imp.Unreferenced)
+
+	return imp, nil
+}
+
+// checkImportSource refuses to import a directory that holds the vault's
+// own notes, which would import them a second time.
+func checkImportSource(baseDir string, cfg *Config, srcDir string) error {
+	info, err := os.Stat(srcDir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", srcDir)
+	}
+
+	absSrc, err := filepath.Abs(srcDir)
+	if err != nil {
+		return err
+	}
+	absBase, err := filepath.Abs(baseDir)
+	if err != nil {
+		return err
+	}
+	for _, dir := range []string{filepath.Join(absBase, "notes"), filepath.Join(absBase, cfg.IDDir)} {
+		if rel, err := filepath.Rel(absSrc, dir); err == nil && (rel == "." || filepath.IsLocal(rel)) {
+			return fmt.Errorf("%s contains the vault's notes", srcDir)
+		}
+		if rel, err := filepath.Rel(dir, absSrc); err == nil && filepath.IsLocal(rel) {
+			return fmt.Errorf("%s is inside the vault's notes", srcDir)
+		}
+	}
+	return nil
+}
+
+// readObsidianNote reads a markdown file from an Obsidian vault and fills
+// in the title and tags fields. A note without a parseable date is marked
+// for dating by modification time.
+func readObsidianNote(p, rel string, cfg *Config) (*obsidianNote, error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	content := strings.ReplaceAll(string(data), "\r\n", "\n")
+
+	var note *Note
+	if strings.HasPrefix(content, frontmatterSep+"\n") {
+		note, err = readNote("", strings.NewReader(content), cfg)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// Without frontmatter a --- line is a thematic break, not a
+		// separator, so the whole file is body.
+		note = NewNote()
+		note.Body = "\n" + content
+	}
+
+	if title, _ := note.Frontmatter.Get("title"); title == "" {
+		note.Frontmatter.Set("title", strings.TrimSuffix(path.Base(rel), path.Ext(rel)))
+	}
+
+	var tags []string
+	for _, tag := range listField(note.Frontmatter, "tags") {
+		if tag = strings.TrimLeft(tag, "#"); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	tags = dedupStrings(append(tags, parseHashtags(note.Body)...))
+	setListField(note.Frontmatter, "tags", tags)
+
+	note.deriveFields(cfg)
+	return &obsidianNote{rel: rel, note: note, mtimed: note.Date.IsZero()}, nil
+}
+
+// assignImportIDs gives each note an ID for its date, numbered after the
+// highest ID already used that day by a note or a folder, oldest note
+// first.
+func assignImportIDs(baseDir string, cfg *Config, notes []*obsidianNote) error {
+	sort.SliceStable(notes, func(i, j int) bool {
+		if !notes[i].note.Date.Equal(notes[j].note.Date) {
+			return notes[i].note.Date.Before(notes[j].note.Date)
+		}
+		return notes[i].rel < notes[j].rel
+	})
+
+	next := map[string]int{}
+	for _, n := range notes {
+		prefix := idPrefix(n.note.Date)
+		if _, ok := next[prefix]; !ok {
+			maxNote, err := MaxNumFromDir(cfg.idDir(baseDir), n.note.Date)
+			if err != nil {
+				return err
+			}
+			maxFolder, err := MaxNumFromDir(cfg.filesDir(baseDir), n.note.Date)
+			if err != nil {
+				return err
+			}
+			next[prefix] = max(maxNote, maxFolder)
+		}
+		next[prefix]++
+		n.id = fmtID(prefix, next[prefix])
+		n.stem = idName(n.id, n.note.Slug)
+		n.folder = FolderName(n.id, n.note.Slug)
+	}
+	return nil
+}
+
+// obsidianResolver resolves link targets the way Obsidian does: by path
+// relative to the vault, by a path suffix, or by filename alone, all
+// case-insensitively and with or without the .md extension.
+type obsidianResolver struct {
+	notes       []*obsidianNote
+	attachments map[string]string
+}
+
+func newObsidianResolver(notes []*obsidianNote, attachments map[string]string) *obsidianResolver {
+	return &obsidianResolver{notes: notes, attachments: attachments}
+}
+
+// findNote returns the note name refers to. When several notes share a
+// filename, the one in the source note's folder wins, then the one with
+// the shortest path.
+func (r *obsidianResolver) findNote(from *obsidianNote, name string) *obsidianNote {
+	want := strings.ToLower(strings.TrimSuffix(name, ".md"))
+	var best *obsidianNote
+	for _, n := range r.notes {
+		rel := strings.ToLower(strings.TrimSuffix(n.rel, path.Ext(n.rel)))
+		if rel != want && !strings.HasSuffix(rel, "/"+want) {
+			continue
+		}
+		if best == nil || closerNote(from, n, best) {
+			best = n
+		}
+	}
+	return best
+}
+
+// closerNote reports whether a is a better match than b for a link from
+// note from.
+func closerNote(from, a, b *obsidianNote) bool {
+	aSame := path.Dir(a.rel) == path.Dir(from.rel)
+	bSame := path.Dir(b.rel) == path.Dir(from.rel)
+	if aSame != bSame {
+		return aSame
+	}
+	if len(a.rel) != len(b.rel) {
+		return len(a.rel) < len(b.rel)
+	}
+	return a.rel < b.rel
+}
+
+// findAttachment returns the vault-relative path of the attachment name
+// refers to.
+func (r *obsidianResolver) findAttachment(name string) (string, bool) {
+	want := strings.ToLower(name)
+	best := ""
+	for rel, lower := range r.attachments {
+		if lower != want && !strings.HasSuffix(lower, "/"+want) {
+			continue
+		}
+		if best == "" || len(rel) < len(best) || len(rel) == len(best) && rel < best {
+			best = rel
+		}
+	}
+	return best, best != ""
+}
+
+// convert rewrites one Obsidian link found in note n. Links to notes
+// become [[id-slug]] links that keep the heading, block and alias as
+// written. Links to attachments are recorded in copied and point at the
+// copy. Unresolvable links are returned unchanged with ok false.
+func (r *obsidianResolver) convert(n *obsidianNote, embed bool, inner string, copied map[string]string) (out string, ok bool) {
+	original := "[[" + inner + "]]"
+	if embed {
+		original = "!" + original
+	}
+
+	name := strings.TrimSpace(ParseWikiLink(inner).Target)
+	if name == "" {
+		// A link to a heading in the same note.
+		return original, true
+	}
+
+	if dst := r.findNote(n, name); dst != nil {
+		link := "[[" + withLinkTarget(inner, dst.stem) + "]]"
+		if embed {
+			link = "!" + link
+		}
+		return link, true
+	}
+
+	src, found := r.findAttachment(name)
+	if !found {
+		return original, false
+	}
+	dest, done := copied[src]
+	if !done {
+		dest = n.folder + "/" + uniqueAttachmentName(copied, n.folder, path.Base(src))
+		copied[src] = dest
+	}
+	link := "[[" + withLinkTarget(inner, dest) + "]]"
+	if embed {
+		link = "!" + link
+	}
+	return link, true
+}
+
+// uniqueAttachmentName returns name, or name with a numeric suffix when
+// another attachment already uses it in folder.
+func uniqueAttachmentName(copied map[string]string, folder, name string) string {
+	taken := map[string]bool{}
+	for _, dest := range copied {
+		taken[dest] = true
+	}
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	candidate := name
+	for i := 2; taken[folder+"/"+candidate]; i++ {
+		candidate = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+	return candidate
+}
+
+// ExecuteObsidianImport writes the notes and copies the attachments of imp
+// into the vault at baseDir and creates the notes' symlinks. It fails
+// without writing anything if a planned ID or attachment was taken since
+// planning, for example by a note created in between, and removes what it
+// wrote if a later step fails.
+func ExecuteObsidianImport(baseDir string, cfg *Config, imp *ObsidianImport) error {
+	cfg = cfg.orDefault()
+	idDir := cfg.idDir(baseDir)
+	filesDir := cfg.filesDir(baseDir)
+
+	unlock, err := lockVault(baseDir)
+	if err != nil {
+		return fmt.Errorf("execute obsidian import: %w", err)
+	}
+	defer unlock()
+
+	used, err := usedIDs(idDir, filesDir)
+	if err != nil {
+		return fmt.Errorf("execute obsidian import: %w", err)
+	}
+	for _, n := range imp.Notes {
+		if _, taken := used[n.note.ID]; taken {
+			return fmt.Errorf("execute obsidian import: %s: ID %s is taken since planning: %w", n.Source, n.note.ID, fs.ErrExist)
+		}
+	}
+	for _, f := range imp.Attachments {
+		if _, err := os.Lstat(filepath.Join(filesDir, filepath.FromSlash(f.Dest))); !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("execute obsidian import: attachment %s: %w", f.Dest, fs.ErrExist)
+		}
+	}
+
+	// Everything written is new, so undoing the import removes it again.
+	var written, folders, names []string
+	fail := func(err error) error {
+		removeNoteSymlinks(baseDir, nil, names...)
+		for i := len(written) - 1; i >= 0; i-- {
+			os.Remove(written[i])
+		}
+		for i := len(folders) - 1; i >= 0; i-- {
+			os.Remove(folders[i])
+		}
+		return fmt.Errorf("execute obsidian import: %w", err)
+	}
+
+	if err := os.MkdirAll(idDir, 0o755); err != nil {
+		return fail(err)
+	}
+	for _, n := range imp.Notes {
+		path := filepath.Join(idDir, n.Filename)
+		if err := writeNewFile(path, []byte(n.note.Markdown()), 0o644); err != nil {
+			return fail(fmt.Errorf("%s: %w", n.Source, err))
+		}
+		written = append(written, path)
+		names = append(names, n.Filename)
+	}
+
+	for _, f := range imp.Attachments {
+		dst := filepath.Join(filesDir, filepath.FromSlash(f.Dest))
+		if _, err := os.Lstat(filepath.Dir(dst)); errors.Is(err, fs.ErrNotExist) {
+			folders = append(folders, filepath.Dir(dst))
+		}
+		written = append(written, dst)
+		src := filepath.Join(imp.Source, filepath.FromSlash(f.Source))
+		if err := copyFile(src, dst); err != nil {
+			return fail(fmt.Errorf("%s: %w", f.Source, err))
+		}
+	}
+
+	for _, n := range imp.Notes {
+		if err := NotePlan(n.note, cfg).CreateLinks(baseDir); err != nil {
+			return fail(err)
+		}
+	}
+
+	return nil
+}
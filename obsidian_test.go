@@ -0,0 +1,185 @@
+package gonotes
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestObsidianImport(t *testing.T) {
+	src := t.TempDir()
+	writeTestNote(t, src, "Project Plan.md", `---
+date: 2026-03-28
+tags: [work, "#planning"]
+aliases: [plan]
+---
+
+Goals for #q2 and #work. See [[Meeting Notes|the meeting]] and [[Meeting Notes#Actions]].
+Diagram: ![[diagram.png|300]], [[assets/diagram.png|full size]] and [[missing page]].
+`+"`[[Meeting Notes]] #notatag`"+`
+
+`+"```"+`
+#include [[Meeting Notes]]
+`+"```")
+	writeTestNote(t, filepath.Join(src, "daily"), "Meeting Notes.md", `# Meeting
+
+## Actions
+
+Back to [[Project Plan]] and [[daily/Meeting Notes]].
+
+---
+
+Heading #1 is not a tag.`)
+	writeTestNote(t, filepath.Join(src, "assets"), "diagram.png", "png")
+	writeTestNote(t, src, "unused.pdf", "pdf")
+	writeTestNote(t, filepath.Join(src, ".obsidian"), "app.md", "skipped")
+
+	mtime := time.Date(2026, 3, 27, 10, 0, 0, 0, time.Local)
+	if err := os.Chtimes(filepath.Join(src, "daily", "Meeting Notes.md"), mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+
+	baseDir := t.TempDir()
+	// An existing note on the same day is numbered around.
+	writeTestNote(t, filepath.Join(baseDir, "notes", "by", "id"), "20260328-1-existing.md", "---\ntitle: Existing\n---\n")
+
+	imp, err := PlanObsidianImport(baseDir, nil, src)
+	if err != nil {
+		t.Fatalf("PlanObsidianImport() err = %q", err)
+	}
+
+	want := &ObsidianImport{
+		Source: src,
+		Notes: []ImportedNote{
+			{Source: "daily/Meeting Notes.md", Filename: "20260327-1-meeting-notes.md", Title: "Meeting Notes", DateFromMtime: true},
+			{Source: "Project Plan.md", Filename: "20260328-2-project-plan.md", Title: "Project Plan", Tags: []string{"work", "planning", "q2"}},
+		},
+		Attachments:     []ImportedFile{{Source: "assets/diagram.png", Dest: "20260328-2-project-plan/diagram.png"}},
+		UnresolvedLinks: []BrokenLink{{SourceID: "20260328-2", TargetID: "missing page"}},
+		Unreferenced:    []string{"unused.pdf"},
+	}
+	if diff := cmp.Diff(want, imp, cmpopts.IgnoreUnexported(ImportedNote{})); diff != "" {
+		t.Errorf("PlanObsidianImport() diff (-want, +got):\n%s", diff)
+	}
+
+	// Nothing is written by planning.
+	if _, err := os.Stat(filepath.Join(baseDir, "files")); !os.IsNotExist(err) {
+		t.Errorf("files dir exists after planning: %v", err)
+	}
+
+	if err := ExecuteObsidianImport(baseDir, nil, imp); err != nil {
+		t.Fatalf("ExecuteObsidianImport() err = %q", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(baseDir, "notes", "by", "id", "20260328-2-project-plan.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"tags: [work, planning, q2]",
+		"title: Project Plan",
+		"See [[20260327-1-meeting-notes|the meeting]] and [[20260327-1-meeting-notes#Actions]].",
+		"Diagram: ![[20260328-2-project-plan/diagram.png|300]], [[20260328-2-project-plan/diagram.png|full size]] and [[missing page]].",
+		"`[[Meeting Notes]] #notatag`",
+		"#include [[Meeting Notes]]",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("imported note missing %q:\n%s", want, data)
+		}
+	}
+
+	data, err = os.ReadFile(filepath.Join(baseDir, "notes", "by", "id", "20260327-1-meeting-notes.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"date: 2026-03-27 10:00:00",
+		"Back to [[20260328-2-project-plan]] and [[20260327-1-meeting-notes]].",
+		"---\n\nHeading #1",
+	} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("imported note missing %q:\n%s", want, data)
+		}
+	}
+
+	if data, err := os.ReadFile(filepath.Join(baseDir, "files", "20260328-2-project-plan", "diagram.png")); err != nil || string(data) != "png" {
+		t.Errorf("attachment not copied: %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(baseDir, "notes", "by", "tags", "q2", "20260328-2-project-plan.md")); err != nil {
+		t.Errorf("tag symlink missing: %v", err)
+	}
+
+	report, err := ScanNotes(baseDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Links in code are left as they were; ScanNotes still sees them, and
+	// resolves them by title.
+	wantBroken := []BrokenLink{
+		{SourceID: "20260328-2", TargetID: "missing page"},
+	}
+	if diff := cmp.Diff(wantBroken, report.BrokenLinks); diff != "" {
+		t.Errorf("ScanNotes() broken links diff (-want, +got):\n%s", diff)
+	}
+	if len(report.Renames) > 0 || len(report.LinkRewrites) > 0 || len(report.Errors) > 0 {
+		t.Errorf("ScanNotes() report = %s, want only broken links", report)
+	}
+
+	t.Run("second execute fails", func(t *testing.T) {
+		if err := ExecuteObsidianImport(baseDir, nil, imp); err == nil {
+			t.Error("ExecuteObsidianImport() err = <nil>, want error")
+		}
+	})
+
+	t.Run("ID taken since planning", func(t *testing.T) {
+		baseDir := t.TempDir()
+		imp, err := PlanObsidianImport(baseDir, nil, src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Another note takes the first planned ID under a different name.
+		writeTestNote(t, filepath.Join(baseDir, "notes", "by", "id"), "20260327-1-other.md", "---\ntitle: Other\n---\n")
+
+		if err := ExecuteObsidianImport(baseDir, nil, imp); err == nil {
+			t.Fatal("ExecuteObsidianImport() err = <nil>, want error")
+		}
+		entries, _ := os.ReadDir(filepath.Join(baseDir, "notes", "by", "id"))
+		if len(entries) != 1 {
+			t.Errorf("notes after failed import = %v, want only the other note", entries)
+		}
+	})
+
+	t.Run("failure removes what was written", func(t *testing.T) {
+		src := t.TempDir()
+		writeTestNote(t, src, "A.md", "See ![[pic.png]].")
+		writeTestNote(t, src, "pic.png", "png")
+		baseDir := t.TempDir()
+		imp, err := PlanObsidianImport(baseDir, nil, src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Remove(filepath.Join(src, "pic.png")); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := ExecuteObsidianImport(baseDir, nil, imp); err == nil {
+			t.Fatal("ExecuteObsidianImport() err = <nil>, want error")
+		}
+		for _, dir := range []string{filepath.Join("notes", "by", "id"), "files"} {
+			if entries, _ := os.ReadDir(filepath.Join(baseDir, dir)); len(entries) > 0 {
+				t.Errorf("%s after failed import = %v, want empty", dir, entries)
+			}
+		}
+	})
+
+	t.Run("refuses the vault itself", func(t *testing.T) {
+		if _, err := PlanObsidianImport(baseDir, nil, baseDir); err == nil {
+			t.Error("PlanObsidianImport() err = <nil>, want error")
+		}
+	})
+}
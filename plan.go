@@ -5,12 +5,11 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
-	"time"
 )
 
 type Link struct {
-	Path   string
-	Target string
+	Path   string `json:"path"`
+	Target string `json:"target"`
 }
 
 type Plan struct {
@@ -39,46 +38,41 @@ func (p *Plan) CreateLinks(baseDir string) error {
 	return nil
 }
 
-func NotePlan(note *Note) *Plan {
+func NotePlan(note *Note, cfg *Config) *Plan {
 	filename := NoteFilename(note.ID, note.Slug)
 	return &Plan{
-		Links: linkEntries(note, filename),
+		Links: linkEntries(note, filename, cfg.orDefault()),
 	}
 }
 
-// linkEntries returns the symlinks for a note. The note must have had
-// deriveFields called before this (linkEntries reads note.Tags and
-// note.Frontmatter directly).
-func linkEntries(note *Note, filename string) []Link {
+// linkEntries returns the symlinks for a note in the views enabled by cfg.
+// The note must have had deriveFields called before this (linkEntries reads
+// note.Date and note.Tags directly).
+func linkEntries(note *Note, filename string, cfg *Config) []Link {
 	var links []Link
 	seen := map[string]struct{}{}
+	notePath := filepath.Join(cfg.IDDir, filename)
 
-	if dateStr, ok := note.Frontmatter.Get("date"); ok {
-		t, err := time.Parse(dateLayout, dateStr)
-		if err == nil {
-			datePath := filepath.Join("notes", "by", "date", t.Format("2006-01-02"), filename)
-			seen[datePath] = struct{}{}
-			links = append(links, Link{
-				Path:   datePath,
-				Target: filepath.Join("..", "..", "id", filename),
-			})
+	add := func(path string) {
+		if _, ok := seen[path]; ok {
+			return
 		}
+		target, err := filepath.Rel(filepath.Dir(path), notePath)
+		if err != nil {
+			return
+		}
+		seen[path] = struct{}{}
+		links = append(links, Link{Path: path, Target: target})
+	}
+
+	if cfg.viewEnabled(ViewDate) && !note.Date.IsZero() {
+		add(filepath.Join("notes", "by", "date", note.Date.Format("2006-01-02"), filename))
 	}
 
-	for _, tag := range note.Tags {
-		parts := strings.Split(tag, "/")
-		nestedPath := filepath.Join(append([]string{"notes", "by", "tags"}, append(parts, filename)...)...)
-		if _, ok := seen[nestedPath]; !ok {
-			nestedUp := make([]string, 1+len(parts))
-			for i := range nestedUp {
-				nestedUp[i] = ".."
-			}
-			nestedTarget := filepath.Join(append(nestedUp, "id", filename)...)
-			seen[nestedPath] = struct{}{}
-			links = append(links, Link{
-				Path:   nestedPath,
-				Target: nestedTarget,
-			})
+	if cfg.viewEnabled(ViewTags) {
+		for _, tag := range note.Tags {
+			parts := strings.Split(tag, "/")
+			add(filepath.Join(append([]string{"notes", "by", "tags"}, append(parts, filename)...)...))
 		}
 	}
 
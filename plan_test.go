@@ -21,7 +21,7 @@ Body.`))
 		t.Fatal(err)
 	}
 
-	plan := NotePlan(note)
+	plan := NotePlan(note, nil)
 
 	gotPaths := make([]string, len(plan.Links))
 	for i, l := range plan.Links {
@@ -39,6 +39,25 @@ Body.`))
 	}
 }
 
+func TestNotePlanDateOffset(t *testing.T) {
+	// Late evening at +02:00 is the next day in UTC; the view uses the day
+	// as written.
+	note, err := ReadNote("20260328-1", strings.NewReader(`---
+title: Late
+date: 2026-03-28T23:30:00+02:00
+---`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan := NotePlan(note, nil)
+
+	want := filepath.Join("notes", "by", "date", "2026-03-28", "20260328-1-late.md")
+	if len(plan.Links) != 1 || plan.Links[0].Path != want {
+		t.Errorf("links = %v, want single date link %s", plan.Links, want)
+	}
+}
+
 func TestNotePlanNoDate(t *testing.T) {
 	note, err := ReadNote("20260328-1", strings.NewReader(`---
 title: No Date
@@ -48,7 +67,7 @@ tags: foo
 		t.Fatal(err)
 	}
 
-	plan := NotePlan(note)
+	plan := NotePlan(note, nil)
 
 	datePfx := filepath.Join("notes", "by", "date")
 	for _, l := range plan.Links {
@@ -67,7 +86,7 @@ date: 2026-03-28 14:30:00
 		t.Fatal(err)
 	}
 
-	plan := NotePlan(note)
+	plan := NotePlan(note, nil)
 
 	if len(plan.Links) != 1 {
 		t.Errorf("expected 1 link (date only), got %d", len(plan.Links))
@@ -87,7 +106,7 @@ tags: welcome/here, welcome/there, foo/bar/x, foo/bar/y
 		t.Fatal(err)
 	}
 
-	plan := NotePlan(note)
+	plan := NotePlan(note, nil)
 
 	seen := map[string]bool{}
 	for _, l := range plan.Links {
@@ -278,6 +278,30 @@ More content.`
 	}
 }
 
+func TestPrepareTagListStyle(t *testing.T) {
+	input := `---
+tags: [old-tag]
+date: 2026-01-01 00:00:00
+---
+`
+
+	note, err := Prepare(strings.NewReader(input), PrepareOptions{
+		Tags: []string{"new-tag"},
+		Now:  fixedNow,
+	})
+	if err != nil {
+		t.Fatalf("Prepare() err = %q", err)
+	}
+
+	wantTags := []string{"old-tag", "new-tag"}
+	if diff := cmp.Diff(wantTags, note.Tags); diff != "" {
+		t.Errorf("Tags diff:\n%s", diff)
+	}
+	if want := "tags: [old-tag, new-tag]\n"; !strings.Contains(note.Markdown(), want) {
+		t.Errorf("Markdown() = %q, want it to contain %q", note.Markdown(), want)
+	}
+}
+
 func TestPrepareExtraFrontmatter(t *testing.T) {
 	tests := []struct {
 		name   string
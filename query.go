@@ -0,0 +1,428 @@
+package gonotes
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Query is a parsed note filter. See ParseQuery for the syntax.
+type Query struct {
+	root queryExpr
+}
+
+type queryExpr interface {
+	match(n *Note) bool
+}
+
+type andExpr []queryExpr
+
+func (e andExpr) match(n *Note) bool {
+	for _, sub := range e {
+		if !sub.match(n) {
+			return false
+		}
+	}
+	return true
+}
+
+type orExpr []queryExpr
+
+func (e orExpr) match(n *Note) bool {
+	for _, sub := range e {
+		if sub.match(n) {
+			return true
+		}
+	}
+	return false
+}
+
+type notExpr struct {
+	expr queryExpr
+}
+
+func (e notExpr) match(n *Note) bool {
+	return !e.expr.match(n)
+}
+
+type matchFunc func(n *Note) bool
+
+func (f matchFunc) match(n *Note) bool {
+	return f(n)
+}
+
+// ParseQuery parses a note query. A query is a list of terms that must all
+// match; terms can be combined with OR, negated with NOT or a leading '-',
+// and grouped with parentheses. A term is either a bare word, matched
+// case-insensitively against title and body, or a field:value predicate:
+//
+//	tag:programming/go   note has the tag or a tag below it
+//	date:>=2026-01-01    date comparison with =, >, >=, <, <=
+//	title:~^go           regular expression match on any field
+//	author:Alice         case-insensitive equality on a frontmatter field
+//	links-to:20260328-1  note links to the given ID or file
+//	id:20260328-1        note has the given ID
+//
+// Values containing spaces or parentheses must be double-quoted, as in
+// title:"my note" or title:~"(go|rust)". An empty query matches every note.
+func ParseQuery(s string) (*Query, error) {
+	tokens, err := lexQuery(s)
+	if err != nil {
+		return nil, fmt.Errorf("parse query: %w", err)
+	}
+	p := &queryParser{tokens: tokens}
+	if len(tokens) == 0 {
+		return &Query{root: andExpr(nil)}, nil
+	}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, fmt.Errorf("parse query: %w", err)
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("parse query: unexpected %q", p.tokens[p.pos])
+	}
+	return &Query{root: expr}, nil
+}
+
+// Match reports whether n satisfies the query.
+func (q *Query) Match(n *Note) bool {
+	return q.root.match(n)
+}
+
+// lexQuery splits s into parentheses and words. Double quotes group
+// characters, including spaces, into a single word and are removed.
+func lexQuery(s string) ([]string, error) {
+	var tokens []string
+	var cur strings.Builder
+	inWord, inQuote := false, false
+
+	flush := func() {
+		if inWord {
+			tokens = append(tokens, cur.String())
+			cur.Reset()
+			inWord = false
+		}
+	}
+
+	for _, r := range s {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			inWord = true
+		case inQuote:
+			cur.WriteRune(r)
+		case unicode.IsSpace(r):
+			flush()
+		case r == '(' || r == ')':
+			flush()
+			tokens = append(tokens, string(r))
+		default:
+			cur.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inQuote {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	flush()
+	return tokens, nil
+}
+
+type queryParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *queryParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *queryParser) parseOr() (queryExpr, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	exprs := orExpr{first}
+	for p.peek() == "OR" {
+		p.pos++
+		next, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, next)
+	}
+	if len(exprs) == 1 {
+		return first, nil
+	}
+	return exprs, nil
+}
+
+func (p *queryParser) parseAnd() (queryExpr, error) {
+	var exprs andExpr
+	for {
+		tok := p.peek()
+		if tok == "" || tok == ")" || tok == "OR" {
+			break
+		}
+		if tok == "AND" {
+			p.pos++
+			continue
+		}
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, e)
+	}
+	if len(exprs) == 0 {
+		if tok := p.peek(); tok != "" {
+			return nil, fmt.Errorf("unexpected %q", tok)
+		}
+		return nil, fmt.Errorf("unexpected end of query")
+	}
+	if len(exprs) == 1 {
+		return exprs[0], nil
+	}
+	return exprs, nil
+}
+
+func (p *queryParser) parseUnary() (queryExpr, error) {
+	tok := p.peek()
+	switch {
+	case tok == "":
+		return nil, fmt.Errorf("unexpected end of query")
+	case tok == ")" || tok == "OR" || tok == "AND":
+		// Only reached after NOT.
+		return nil, fmt.Errorf("unexpected %q", tok)
+	case tok == "NOT":
+		p.pos++
+		e, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{e}, nil
+	case tok == "(":
+		p.pos++
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("missing closing parenthesis")
+		}
+		p.pos++
+		return e, nil
+	case strings.HasPrefix(tok, "-") && len(tok) > 1:
+		p.pos++
+		e, err := parsePredicate(tok[1:])
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{e}, nil
+	default:
+		p.pos++
+		return parsePredicate(tok)
+	}
+}
+
+// parsePredicate parses a single bare word or field:value term.
+func parsePredicate(tok string) (queryExpr, error) {
+	field, value, ok := strings.Cut(tok, ":")
+	if !ok || field == "" {
+		word := strings.ToLower(tok)
+		return matchFunc(func(n *Note) bool {
+			return strings.Contains(strings.ToLower(n.Title), word) ||
+				strings.Contains(strings.ToLower(n.Body), word)
+		}), nil
+	}
+
+	field = strings.ToLower(field)
+
+	if re, ok := strings.CutPrefix(value, "~"); ok {
+		rx, err := regexp.Compile(re)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", field, err)
+		}
+		return matchFunc(func(n *Note) bool {
+			for _, v := range noteFieldValues(n, field) {
+				if rx.MatchString(v) {
+					return true
+				}
+			}
+			return false
+		}), nil
+	}
+
+	switch field {
+	case "tag", "tags":
+		return matchFunc(func(n *Note) bool {
+			for _, t := range n.Tags {
+				if t == value || strings.HasPrefix(t, value+"/") {
+					return true
+				}
+			}
+			return false
+		}), nil
+	case "date":
+		return parseDatePredicate(value)
+	case "links-to":
+		want := linkTargetID(value)
+		return matchFunc(func(n *Note) bool {
+			for _, l := range n.InternalLinks {
+				if linkTargetID(l) == want {
+					return true
+				}
+			}
+			return false
+		}), nil
+	}
+
+	return matchFunc(func(n *Note) bool {
+		for _, v := range noteFieldValues(n, field) {
+			if strings.EqualFold(v, value) {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+// noteFieldValues returns the values of field for n as strings. Derived
+// fields take precedence over raw frontmatter.
+func noteFieldValues(n *Note, field string) []string {
+	switch field {
+	case "id":
+		return []string{n.ID}
+	case "title":
+		return []string{n.Title}
+	case "tag", "tags":
+		return n.Tags
+	case "body":
+		return []string{n.Body}
+	case "links-to":
+		return n.InternalLinks
+	}
+	values, _ := n.Frontmatter.GetList(field)
+	return values
+}
+
+// parseDatePredicate parses values like ">=2026-01-01". A date-only value
+// covers the whole day, so date:<=2026-01-01 includes notes from that day.
+// Note dates are compared by the day and time written in the note,
+// regardless of their offset, matching the date view.
+func parseDatePredicate(value string) (queryExpr, error) {
+	op := "="
+	for _, candidate := range []string{">=", "<=", ">", "<", "="} {
+		if rest, ok := strings.CutPrefix(value, candidate); ok {
+			op, value = candidate, rest
+			break
+		}
+	}
+
+	var lo, hi time.Time
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		lo, hi = t, t.AddDate(0, 0, 1)
+	} else if t, err := time.Parse(dateLayout, value); err == nil {
+		lo, hi = t, t.Add(time.Second)
+	} else {
+		return nil, fmt.Errorf("date: cannot parse %q", value)
+	}
+
+	return matchFunc(func(n *Note) bool {
+		if n.Date.IsZero() {
+			return false
+		}
+		d := wallClock(n.Date)
+		switch op {
+		case ">":
+			return !d.Before(hi)
+		case ">=":
+			return !d.Before(lo)
+		case "<":
+			return d.Before(lo)
+		case "<=":
+			return d.Before(hi)
+		default:
+			return !d.Before(lo) && d.Before(hi)
+		}
+	}), nil
+}
+
+// ListedNote is a note matched by ListNotes with the filename it has on disk.
+type ListedNote struct {
+	*Note
+	Filename string
+	// Path is the note's path relative to the vault root.
+	Path string
+}
+
+type ListOptions struct {
+	Query   *Query
+	SortBy  string // "id" (default), "date" or "title"
+	Reverse bool
+	Limit   int
+}
+
+// ListNotes returns the notes in the vault at baseDir that match opts.Query,
+// sorted and limited as requested.
+func ListNotes(baseDir string, cfg *Config, opts ListOptions) ([]ListedNote, []ScanError, error) {
+	cfg = cfg.orDefault()
+
+	files, errs, err := readNoteFiles(cfg.idDir(baseDir), cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list notes: %w", err)
+	}
+
+	var less func(a, b *Note) bool
+	switch opts.SortBy {
+	case "", "id":
+		less = func(a, b *Note) bool { return compareIDs(a.ID, b.ID) < 0 }
+	case "date":
+		// Like the date: predicate, compare the dates as written.
+		less = func(a, b *Note) bool { return wallClock(a.Date).Before(wallClock(b.Date)) }
+	case "title":
+		less = func(a, b *Note) bool { return strings.ToLower(a.Title) < strings.ToLower(b.Title) }
+	default:
+		return nil, nil, fmt.Errorf("list notes: unknown sort field %q", opts.SortBy)
+	}
+
+	var out []ListedNote
+	for i := range files {
+		nf := &files[i]
+		if opts.Query != nil && !opts.Query.Match(&nf.Note) {
+			continue
+		}
+		out = append(out, ListedNote{
+			Note:     &nf.Note,
+			Filename: nf.Filename,
+			Path:     filepath.Join(cfg.IDDir, nf.Filename),
+		})
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := out[i].Note, out[j].Note
+		if opts.Reverse {
+			a, b = b, a
+		}
+		if less(a, b) {
+			return true
+		}
+		if less(b, a) {
+			return false
+		}
+		return compareIDs(a.ID, b.ID) < 0
+	})
+
+	if opts.Limit > 0 && len(out) > opts.Limit {
+		out = out[:opts.Limit]
+	}
+
+	return out, errs, nil
+}
@@ -0,0 +1,191 @@
+package gonotes
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestQueryMatch(t *testing.T) {
+	notes := map[string]string{
+		"20260101-1": `---
+title: Go Generics
+date: 2026-01-01 09:00:00
+tags: programming/go, tools
+author: Alice
+---
+
+Type parameters, see [[20260102-1]].`,
+		"20260102-1": `---
+title: Rust Traits
+date: 2026-01-02 10:00:00
+tags: programming/rust
+author: Bob
+---
+
+Traits.`,
+		"20260201-1": `---
+title: Groceries
+date: 2026-02-01T23:30:00-05:00
+---
+
+Milk.`,
+	}
+
+	parsed := map[string]*Note{}
+	for id, content := range notes {
+		n, err := ReadNote(id, strings.NewReader(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed[id] = n
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"20260101-1", "20260102-1", "20260201-1"}},
+		{"tag:programming", []string{"20260101-1", "20260102-1"}},
+		{"tag:programming/go", []string{"20260101-1"}},
+		{"tag:program", nil},
+		{"date:>=2026-01-02", []string{"20260102-1", "20260201-1"}},
+		{"date:>2026-01-02", []string{"20260201-1"}},
+		{"date:<=2026-01-02", []string{"20260101-1", "20260102-1"}},
+		{"date:<2026-01-02", []string{"20260101-1"}},
+		{"date:2026-01-02", []string{"20260102-1"}},
+		{"date:2026-02-01", []string{"20260201-1"}},
+		{"date:>=2026-02-02", nil},
+		{`date:"2026-01-02 10:00:00"`, []string{"20260102-1"}},
+		{"title:~^G", []string{"20260101-1", "20260201-1"}},
+		{"author:alice", []string{"20260101-1"}},
+		{"links-to:20260102-1", []string{"20260101-1"}},
+		{"links-to:20260102-1-rust-traits", []string{"20260101-1"}},
+		{"tag:programming -author:Bob", []string{"20260101-1"}},
+		{"tag:programming AND NOT author:Bob", []string{"20260101-1"}},
+		{"author:Bob OR title:groceries", []string{"20260102-1", "20260201-1"}},
+		{"(author:Bob OR author:Alice) date:>=2026-01-02", []string{"20260102-1"}},
+		{"milk", []string{"20260201-1"}},
+		{"id:20260201-1", []string{"20260201-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := ParseQuery(tt.query)
+			if err != nil {
+				t.Fatalf("ParseQuery(%q) err = %q", tt.query, err)
+			}
+			var got []string
+			for _, id := range []string{"20260101-1", "20260102-1", "20260201-1"} {
+				if q.Match(parsed[id]) {
+					got = append(got, id)
+				}
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("matches diff (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParseQueryErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"(tag:go", "missing closing parenthesis"},
+		{"tag:go)", `unexpected ")"`},
+		{`title:"open`, "unterminated quote"},
+		{`title:~"("`, "title:"},
+		{"date:>=yesterday", `cannot parse "yesterday"`},
+		{"tag:go OR", "unexpected end of query"},
+		{"tag:go NOT", "unexpected end of query"},
+		{"(NOT) tag:go", `unexpected ")"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := ParseQuery(tt.query)
+			if err == nil {
+				t.Fatalf("ParseQuery(%q) err = <nil>, want error", tt.query)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ParseQuery(%q) err = %q, want substring %q", tt.query, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestListNotes(t *testing.T) {
+	baseDir := t.TempDir()
+	idDir := filepath.Join(baseDir, "notes", "by", "id")
+
+	writeTestNote(t, idDir, "20260101-1-b.md", "---\ntitle: B\ndate: 2026-01-03 10:00:00\ntags: go\n---\n")
+	writeTestNote(t, idDir, "20260101-2-a.md", "---\ntitle: A\ndate: 2026-01-02 10:00:00\ntags: go\n---\n")
+	writeTestNote(t, idDir, "20260101-3-c.md", "---\ntitle: C\ndate: 2026-01-01 10:00:00\n---\n")
+	writeTestNote(t, idDir, "20260101-10-d.md", "---\ntitle: D\ndate: 2026-01-04 10:00:00\n---\n")
+
+	q, err := ParseQuery("tag:go")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		opts ListOptions
+		want []string
+	}{
+		{"default sort", ListOptions{}, []string{"20260101-1-b.md", "20260101-2-a.md", "20260101-3-c.md", "20260101-10-d.md"}},
+		{"reversed", ListOptions{Reverse: true}, []string{"20260101-10-d.md", "20260101-3-c.md", "20260101-2-a.md", "20260101-1-b.md"}},
+		{"by title", ListOptions{SortBy: "title"}, []string{"20260101-2-a.md", "20260101-1-b.md", "20260101-3-c.md", "20260101-10-d.md"}},
+		{"by date reversed", ListOptions{SortBy: "date", Reverse: true}, []string{"20260101-10-d.md", "20260101-1-b.md", "20260101-2-a.md", "20260101-3-c.md"}},
+		{"by date limited", ListOptions{SortBy: "date", Limit: 2}, []string{"20260101-3-c.md", "20260101-2-a.md"}},
+		{"query", ListOptions{Query: q, SortBy: "date"}, []string{"20260101-2-a.md", "20260101-1-b.md"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errs, err := ListNotes(baseDir, nil, tt.opts)
+			if err != nil {
+				t.Fatalf("ListNotes() err = %q", err)
+			}
+			if len(errs) != 0 {
+				t.Errorf("unexpected errors: %v", errs)
+			}
+			names := make([]string, len(got))
+			for i, ln := range got {
+				names[i] = ln.Filename
+			}
+			if diff := cmp.Diff(tt.want, names); diff != "" {
+				t.Errorf("filenames diff (-want, +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, _, err := ListNotes(baseDir, nil, ListOptions{SortBy: "size"}); err == nil {
+		t.Error("ListNotes(SortBy: size) err = <nil>, want error")
+	}
+
+	t.Run("by date as written", func(t *testing.T) {
+		// Sorted by the wall-clock dates the date: predicate compares, not
+		// by instant: 11:00+14:00 is earlier than 10:00Z.
+		baseDir := t.TempDir()
+		idDir := filepath.Join(baseDir, "notes", "by", "id")
+		writeTestNote(t, idDir, "20260102-1-early.md", "---\ntitle: Early\ndate: 2026-01-02T10:00:00Z\n---\n")
+		writeTestNote(t, idDir, "20260102-2-late.md", "---\ntitle: Late\ndate: 2026-01-02T11:00:00+14:00\n---\n")
+
+		got, _, err := ListNotes(baseDir, nil, ListOptions{SortBy: "date"})
+		if err != nil {
+			t.Fatalf("ListNotes() err = %q", err)
+		}
+		var names []string
+		for _, ln := range got {
+			names = append(names, ln.Filename)
+		}
+		if diff := cmp.Diff([]string{"20260102-1-early.md", "20260102-2-late.md"}, names); diff != "" {
+			t.Errorf("filenames diff (-want, +got):\n%s", diff)
+		}
+	})
+}
@@ -3,9 +3,12 @@ package gonotes
 import (
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
 )
 
 // noteFile is a parsed note together with its original filename on disk.
@@ -16,7 +19,7 @@ type noteFile struct {
 
 // readNoteFile reads a single note file by name from dir.
 // On error it appends to errs and returns nil.
-func readNoteFile(dir, name string, errs *[]ScanError) *noteFile {
+func readNoteFile(dir, name string, cfg *Config, errs *[]ScanError) *noteFile {
 	id, _ := IDFromFilename(name)
 	path := filepath.Join(dir, name)
 
@@ -30,7 +33,7 @@ func readNoteFile(dir, name string, errs *[]ScanError) *noteFile {
 	}
 	defer f.Close()
 
-	note, err := ReadNote(id, f)
+	note, err := readNote(id, f, cfg)
 	if err != nil {
 		*errs = append(*errs, ScanError{
 			Filename: name,
@@ -41,39 +44,84 @@ func readNoteFile(dir, name string, errs *[]ScanError) *noteFile {
 	return &noteFile{Note: *note, Filename: name}
 }
 
-// readNoteFiles reads all .md files from dir and parses them.
-// It returns the parsed noteFiles and any per-file errors.
-func readNoteFiles(dir string) ([]noteFile, []ScanError, error) {
+// noteEntries lists the .md file entries in dir in directory order.
+func noteEntries(dir string) ([]fs.DirEntry, error) {
 	f, err := os.Open(dir)
 	if err != nil {
-		return nil, nil, fmt.Errorf("open notes dir: %w", err)
+		return nil, fmt.Errorf("open notes dir: %w", err)
 	}
 	defer f.Close()
 
-	var files []noteFile
-	var errs []ScanError
-
+	var out []fs.DirEntry
 	for {
 		entries, err := f.ReadDir(readDirBatch)
 		for _, e := range entries {
 			if e.IsDir() {
 				continue
 			}
-			name := e.Name()
-			if !strings.HasSuffix(name, ".md") {
+			if !strings.HasSuffix(e.Name(), ".md") {
 				continue
 			}
-			nf := readNoteFile(dir, name, &errs)
-			if nf != nil {
-				files = append(files, *nf)
-			}
+			out = append(out, e)
 		}
 		if err != nil {
 			if err == io.EOF {
 				break
 			}
-			return nil, nil, fmt.Errorf("read dir: %w", err)
+			return nil, fmt.Errorf("read dir: %w", err)
+		}
+	}
+
+	return out, nil
+}
+
+// readNoteFiles reads all .md files from dir and parses them.
+// It returns the parsed noteFiles and any per-file errors.
+func readNoteFiles(dir string, cfg *Config) ([]noteFile, []ScanError, error) {
+	return readNoteFilesN(dir, cfg, runtime.GOMAXPROCS(0))
+}
+
+// readNoteFilesN is readNoteFiles with at most workers files parsed
+// concurrently. Results and errors are returned in directory order
+// regardless of which worker finished first.
+func readNoteFilesN(dir string, cfg *Config, workers int) ([]noteFile, []ScanError, error) {
+	entries, err := noteEntries(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	type result struct {
+		nf   *noteFile
+		errs []ScanError
+	}
+	results := make([]result, len(entries))
+
+	workers = max(1, min(workers, len(entries)))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				r := &results[i]
+				r.nf = readNoteFile(dir, entries[i].Name(), cfg, &r.errs)
+			}
+		}()
+	}
+	for i := range entries {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	var files []noteFile
+	var errs []ScanError
+	for _, r := range results {
+		if r.nf != nil {
+			files = append(files, *r.nf)
 		}
+		errs = append(errs, r.errs...)
 	}
 
 	return files, errs, nil
@@ -81,8 +129,8 @@ func readNoteFiles(dir string) ([]noteFile, []ScanError, error) {
 
 // readNotesFromDir reads all .md files from dir and returns just the Notes.
 // Used by callers that don't need the original filenames.
-func readNotesFromDir(dir string) ([]Note, []ScanError, error) {
-	files, errs, err := readNoteFiles(dir)
+func readNotesFromDir(dir string, cfg *Config) ([]Note, []ScanError, error) {
+	files, errs, err := readNoteFiles(dir, cfg)
 	if err != nil {
 		return nil, nil, err
 	}
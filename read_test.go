@@ -1,8 +1,11 @@
 package gonotes
 
 import (
+	"fmt"
+	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -33,7 +36,7 @@ date: 2026-03-28 16:00:00
 
 No title note.`)
 
-	notes, errs, err := readNotesFromDir(idDir)
+	notes, errs, err := readNotesFromDir(idDir, nil)
 	if err != nil {
 		t.Fatalf("readNotesFromDir() err = %q", err)
 	}
@@ -55,3 +58,73 @@ No title note.`)
 		t.Errorf("titles diff (-want, +got):\n%s", diff)
 	}
 }
+
+func TestReadNoteFilesConcurrentOrder(t *testing.T) {
+	idDir := filepath.Join(t.TempDir(), "notes", "by", "id")
+	generateTestVault(t, idDir, 200)
+	writeTestNote(t, idDir, "20260101-9999-bad.md", "---\ntitle: [unclosed\n---\n")
+
+	wantFiles, wantErrs, err := readNoteFilesN(idDir, nil, 1)
+	if err != nil {
+		t.Fatalf("readNoteFilesN(1) err = %q", err)
+	}
+	if len(wantErrs) != 1 {
+		t.Errorf("got %d errors, want 1: %v", len(wantErrs), wantErrs)
+	}
+
+	for _, workers := range []int{2, 8, 1000} {
+		gotFiles, gotErrs, err := readNoteFilesN(idDir, nil, workers)
+		if err != nil {
+			t.Fatalf("readNoteFilesN(%d) err = %q", workers, err)
+		}
+		if diff := cmp.Diff(wantErrs, gotErrs); diff != "" {
+			t.Errorf("readNoteFilesN(%d) errors diff (-want, +got):\n%s", workers, diff)
+		}
+		if len(gotFiles) != len(wantFiles) {
+			t.Fatalf("readNoteFilesN(%d) got %d files, want %d", workers, len(gotFiles), len(wantFiles))
+		}
+		for i := range wantFiles {
+			if gotFiles[i].Filename != wantFiles[i].Filename || gotFiles[i].Title != wantFiles[i].Title {
+				t.Errorf("readNoteFilesN(%d)[%d] = %s, want %s", workers, i, gotFiles[i].Filename, wantFiles[i].Filename)
+			}
+		}
+	}
+}
+
+func BenchmarkReadNoteFiles(b *testing.B) {
+	idDir := filepath.Join(b.TempDir(), "notes", "by", "id")
+	generateTestVault(b, idDir, 5000)
+
+	for _, workers := range []int{1, 4, 16} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for b.Loop() {
+				if _, _, err := readNoteFilesN(idDir, nil, workers); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// generateTestVault writes n notes with frontmatter, tags and links to dir.
+func generateTestVault(tb testing.TB, dir string, n int) {
+	tb.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		tb.Fatal(err)
+	}
+	body := strings.Repeat("Lorem ipsum dolor sit amet, consectetur adipiscing elit.\n", 40)
+	for i := 1; i <= n; i++ {
+		content := fmt.Sprintf(`---
+title: Note %d
+date: 2026-01-01 10:00:00
+tags: bench/tag%d, bench/all
+---
+
+See [[20260101-%d]].
+%s`, i, i%20, i%n+1, body)
+		name := fmt.Sprintf("20260101-%d-note-%d.md", i, i)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			tb.Fatal(err)
+		}
+	}
+}
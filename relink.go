@@ -0,0 +1,213 @@
+package gonotes
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FindNote returns the filename in idDir of the note identified by query.
+// An exact ID match wins; otherwise query is matched as a filename prefix
+// and must select exactly one note.
+func FindNote(idDir, query string) (string, error) {
+	entries, err := noteEntries(idDir)
+	if err != nil {
+		return "", fmt.Errorf("find note: %w", err)
+	}
+
+	query = strings.TrimSuffix(query, ".md")
+
+	var matches []string
+	for _, e := range entries {
+		name := e.Name()
+		if id, _ := IDFromFilename(name); id == query {
+			return name, nil
+		}
+		if strings.HasPrefix(name, query) {
+			matches = append(matches, name)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("find note: no note matches %q", query)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("find note: %q is ambiguous: %s", query, strings.Join(matches, ", "))
+	}
+}
+
+// RelinkResult describes what RelinkNote changed for a single note.
+type RelinkResult struct {
+	Note    *Note
+	OldName string
+	NewName string
+	Added   []Link
+	Removed []string
+}
+
+func (r *RelinkResult) String() string {
+	var b strings.Builder
+	if r.OldName != r.NewName {
+		fmt.Fprintf(&b, "rename: %s -> %s\n", r.OldName, r.NewName)
+	}
+	for _, p := range r.Removed {
+		fmt.Fprintf(&b, "unlink: %s\n", p)
+	}
+	b.WriteString((&Plan{Links: r.Added}).String())
+	return b.String()
+}
+
+// RelinkNote re-reads the note stored as name in the notes directory,
+// renames it if its title no longer matches the filename, or gives it an ID
+// if the filename has none, and replaces its symlinks under notes/by/date
+// and notes/by/tags. Symlinks of other notes are left alone.
+func RelinkNote(baseDir string, cfg *Config, name string) (*RelinkResult, error) {
+	cfg = cfg.orDefault()
+	idDir := cfg.idDir(baseDir)
+
+	var errs []ScanError
+	nf := readNoteFile(idDir, name, cfg, &errs)
+	if nf == nil {
+		return nil, fmt.Errorf("relink note: %s: %s", errs[0].Filename, errs[0].Message)
+	}
+
+	newName := name
+	if id, parsed := IDFromFilename(name); parsed {
+		newName = NoteFilename(id, nf.Slug)
+		if newName != name {
+			newPath := filepath.Join(idDir, newName)
+			if _, err := os.Lstat(newPath); err == nil {
+				return nil, fmt.Errorf("relink note: rename %s -> %s: target exists", name, newName)
+			}
+			if err := os.Rename(filepath.Join(idDir, name), newPath); err != nil {
+				return nil, fmt.Errorf("relink note: %w", err)
+			}
+		}
+	} else {
+		// A note without an ID gets the next one for its date or, failing
+		// that, the day it was last modified.
+		day := nf.Date
+		if day.IsZero() {
+			info, err := os.Stat(filepath.Join(idDir, name))
+			if err != nil {
+				return nil, fmt.Errorf("relink note: %w", err)
+			}
+			day = info.ModTime()
+		}
+		err := allocateID(baseDir, []string{idDir}, day, func(id string) error {
+			nf.ID = id
+			newName = NoteFilename(id, nf.Slug)
+			return renameNoReplace(filepath.Join(idDir, name), filepath.Join(idDir, newName))
+		})
+		if err != nil {
+			return nil, fmt.Errorf("relink note: %w", err)
+		}
+	}
+
+	desired := linkEntries(&nf.Note, newName, cfg)
+	keep := make(map[string]string, len(desired))
+	for _, l := range desired {
+		keep[l.Path] = l.Target
+	}
+
+	removed, err := removeNoteSymlinks(baseDir, keep, name, newName)
+	if err != nil {
+		return nil, fmt.Errorf("relink note: %w", err)
+	}
+
+	plan := &Plan{}
+	for _, l := range desired {
+		if _, err := os.Lstat(filepath.Join(baseDir, l.Path)); err == nil {
+			continue
+		}
+		plan.Links = append(plan.Links, l)
+	}
+	if err := plan.CreateLinks(baseDir); err != nil {
+		return nil, fmt.Errorf("relink note: %w", err)
+	}
+
+	return &RelinkResult{
+		Note:    &nf.Note,
+		OldName: name,
+		NewName: newName,
+		Added:   plan.Links,
+		Removed: removed,
+	}, nil
+}
+
+// removeNoteSymlinks removes every symlink under notes/by/date and
+// notes/by/tags named after one of names, except those whose path and
+// target match an entry in keep, then prunes directories left empty.
+// Paths in keep and in the result are relative to baseDir.
+func removeNoteSymlinks(baseDir string, keep map[string]string, names ...string) ([]string, error) {
+	byDir := filepath.Join(baseDir, "notes", "by")
+
+	want := make(map[string]struct{}, len(names))
+	for _, n := range names {
+		want[n] = struct{}{}
+	}
+
+	var removed []string
+	for _, view := range []string{"date", "tags"} {
+		root := filepath.Join(byDir, view)
+		var dirs []string
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) && path == root {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if d.IsDir() {
+				if path != root {
+					dirs = append(dirs, path)
+				}
+				return nil
+			}
+			if d.Type()&fs.ModeSymlink == 0 {
+				return nil
+			}
+			if _, ok := want[d.Name()]; !ok {
+				return nil
+			}
+			rel, err := filepath.Rel(baseDir, path)
+			if err != nil {
+				return err
+			}
+			if target, ok := keep[rel]; ok {
+				if cur, err := os.Readlink(path); err == nil && cur == target {
+					return nil
+				}
+			}
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+			removed = append(removed, rel)
+			return nil
+		})
+		if err != nil {
+			return removed, fmt.Errorf("remove symlinks: %w", err)
+		}
+		pruneEmptyDirs(dirs)
+	}
+
+	return removed, nil
+}
+
+// pruneEmptyDirs removes the directories in dirs that are empty, deepest
+// first. dirs must be in WalkDir order. Errors are ignored since a
+// non-empty directory is expected to fail removal.
+func pruneEmptyDirs(dirs []string) {
+	for i := len(dirs) - 1; i >= 0; i-- {
+		entries, err := os.ReadDir(dirs[i])
+		if err != nil || len(entries) > 0 {
+			continue
+		}
+		_ = os.Remove(dirs[i])
+	}
+}
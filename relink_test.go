@@ -0,0 +1,153 @@
+package gonotes
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFindNote(t *testing.T) {
+	idDir := filepath.Join(t.TempDir(), "notes", "by", "id")
+
+	writeTestNote(t, idDir, "20260328-1-hello.md", "Body.")
+	writeTestNote(t, idDir, "20260328-10-world.md", "Body.")
+	writeTestNote(t, idDir, "20260328-2-other.md", "Body.")
+
+	tests := []struct {
+		query   string
+		want    string
+		wantErr string
+	}{
+		{query: "20260328-1", want: "20260328-1-hello.md"},
+		{query: "20260328-10", want: "20260328-10-world.md"},
+		{query: "20260328-2-oth", want: "20260328-2-other.md"},
+		{query: "20260328-1-hello.md", want: "20260328-1-hello.md"},
+		{query: "20260328-", wantErr: "ambiguous"},
+		{query: "20260329", wantErr: "no note matches"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := FindNote(idDir, tt.query)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("FindNote(%q) err = %v, want substring %q", tt.query, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FindNote(%q) err = %q", tt.query, err)
+			}
+			if got != tt.want {
+				t.Errorf("FindNote(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRelinkNote(t *testing.T) {
+	baseDir := t.TempDir()
+	idDir := filepath.Join(baseDir, "notes", "by", "id")
+
+	writeTestNote(t, idDir, "20260328-1-hello.md", `---
+title: Hello
+date: 2026-03-28 14:30:00
+tags: foo/bar, keep
+---
+
+Body.`)
+
+	writeTestNote(t, idDir, "20260328-2-other.md", `---
+title: Other
+date: 2026-03-28 15:00:00
+tags: foo/bar
+---
+
+Body.`)
+
+	if err := RebuildSymlinks(baseDir, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestNote(t, idDir, "20260328-1-hello.md", `---
+title: Hello Again
+date: 2026-03-29 09:00:00
+tags: keep, new
+---
+
+Body.`)
+
+	res, err := RelinkNote(baseDir, nil, "20260328-1-hello.md")
+	if err != nil {
+		t.Fatalf("RelinkNote() err = %q", err)
+	}
+
+	if res.NewName != "20260328-1-hello-again.md" {
+		t.Errorf("NewName = %q, want %q", res.NewName, "20260328-1-hello-again.md")
+	}
+	if _, err := os.Stat(filepath.Join(idDir, "20260328-1-hello-again.md")); err != nil {
+		t.Errorf("renamed note missing: %v", err)
+	}
+
+	got, err := snapshotNoteSymlinks(baseDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"notes/by/date/2026-03-28/20260328-2-other.md":       "../../id/20260328-2-other.md",
+		"notes/by/date/2026-03-29/20260328-1-hello-again.md": "../../id/20260328-1-hello-again.md",
+		"notes/by/tags/foo/bar/20260328-2-other.md":          "../../../id/20260328-2-other.md",
+		"notes/by/tags/keep/20260328-1-hello-again.md":       "../../id/20260328-1-hello-again.md",
+		"notes/by/tags/new/20260328-1-hello-again.md":        "../../id/20260328-1-hello-again.md",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("symlinks diff (-want, +got):\n%s", diff)
+	}
+}
+
+func TestRelinkNoteUnchangedKeepsLinks(t *testing.T) {
+	baseDir := t.TempDir()
+	idDir := filepath.Join(baseDir, "notes", "by", "id")
+
+	writeTestNote(t, idDir, "20260328-1-hello.md", `---
+title: Hello
+date: 2026-03-28 14:30:00
+tags: foo
+---
+
+Body.`)
+
+	if err := RebuildSymlinks(baseDir, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := RelinkNote(baseDir, nil, "20260328-1-hello.md")
+	if err != nil {
+		t.Fatalf("RelinkNote() err = %q", err)
+	}
+	if len(res.Added) != 0 || len(res.Removed) != 0 {
+		t.Errorf("RelinkNote() added %v, removed %v, want no changes", res.Added, res.Removed)
+	}
+}
+
+func TestRelinkNotePrunesEmptyDirs(t *testing.T) {
+	baseDir := t.TempDir()
+	idDir := filepath.Join(baseDir, "notes", "by", "id")
+
+	writeTestNote(t, idDir, "20260328-1-hello.md", "---\ntitle: Hello\ntags: a/b/c\n---\n")
+	if err := RebuildSymlinks(baseDir, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestNote(t, idDir, "20260328-1-hello.md", "---\ntitle: Hello\n---\n")
+	if _, err := RelinkNote(baseDir, nil, "20260328-1-hello.md"); err != nil {
+		t.Fatalf("RelinkNote() err = %q", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(baseDir, "notes", "by", "tags", "a")); !os.IsNotExist(err) {
+		t.Errorf("empty tag directory not pruned: %v", err)
+	}
+}
@@ -2,29 +2,73 @@ package gonotes
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
 )
 
 type TagChange struct {
-	ID      string
-	Path    string
-	OldTags []string
-	NewTags []string
+	ID      string   `json:"id"`
+	Path    string   `json:"path"`
+	OldTags []string `json:"old_tags"`
+	NewTags []string `json:"new_tags"`
+	// KeptTags are inline body tags that are missing from notes/by/tags
+	// but kept in NewTags, because they cannot be removed from the body.
+	KeptTags []string `json:"kept_tags"`
+}
+
+// MarshalJSON encodes the change with empty tag lists rather than null.
+func (tc TagChange) MarshalJSON() ([]byte, error) {
+	type change TagChange
+	out := change(tc)
+	out.OldTags = nonNil(out.OldTags)
+	out.NewTags = nonNil(out.NewTags)
+	out.KeptTags = nonNil(out.KeptTags)
+	return json.Marshal(out)
 }
 
 func (tc TagChange) String() string {
-	return fmt.Sprintf("%s: %v -> %v", tc.ID, tc.OldTags, tc.NewTags)
+	s := fmt.Sprintf("%s: %v -> %v", tc.ID, tc.OldTags, tc.NewTags)
+	if len(tc.KeptTags) > 0 {
+		s += fmt.Sprintf(" (inline tags kept: %v)", tc.KeptTags)
+	}
+	return s
+}
+
+// KeptInlineTags lists the inline body tags of an otherwise unchanged note
+// that are missing from notes/by/tags but kept, because the body is never
+// rewritten.
+type KeptInlineTags struct {
+	ID   string   `json:"id"`
+	Tags []string `json:"tags"`
+}
+
+func (k KeptInlineTags) String() string {
+	return fmt.Sprintf("%s: %v", k.ID, k.Tags)
 }
 
 type ReverseRebuildReport struct {
-	Changes   []TagChange
-	Unchanged int
-	Errors    []ScanError
+	Changes []TagChange `json:"changes"`
+	// Kept lists the notes counted as unchanged although some of their
+	// inline tags have no symlink.
+	Kept      []KeptInlineTags `json:"kept"`
+	Unchanged int              `json:"unchanged"`
+	Errors    []ScanError      `json:"errors"`
+}
+
+// MarshalJSON encodes the report with empty lists rather than null.
+func (r ReverseRebuildReport) MarshalJSON() ([]byte, error) {
+	type report ReverseRebuildReport
+	out := report(r)
+	out.Changes = nonNil(out.Changes)
+	out.Kept = nonNil(out.Kept)
+	out.Errors = nonNil(out.Errors)
+	return json.Marshal(out)
 }
 
 func (r *ReverseRebuildReport) String() string {
@@ -37,6 +81,13 @@ func (r *ReverseRebuildReport) String() string {
 		}
 	}
 
+	if len(r.Kept) > 0 {
+		fmt.Fprintf(&b, "Inline tags kept (%d):\n", len(r.Kept))
+		for _, k := range r.Kept {
+			fmt.Fprintf(&b, "  %s\n", k.String())
+		}
+	}
+
 	if r.Unchanged > 0 {
 		fmt.Fprintf(&b, "Unchanged: %d\n", r.Unchanged)
 	}
@@ -107,6 +158,13 @@ func ScanTagsFromFS(baseDir string) (map[string][]string, error) {
 	return result, nil
 }
 
+// sortedTags returns a sorted copy of tags.
+func sortedTags(tags []string) []string {
+	out := slices.Clone(tags)
+	sort.Strings(out)
+	return out
+}
+
 func tagsEqual(a, b []string) bool {
 	if len(a) != len(b) {
 		return false
@@ -119,15 +177,27 @@ func tagsEqual(a, b []string) bool {
 	return true
 }
 
-func ReverseRebuild(baseDir string) (*ReverseRebuildReport, error) {
+// ReverseRebuild compares each note's tags with the notes/by/tags symlinks
+// and reports the tag changes that would make the notes match. A note that
+// would only keep inline tags whose symlinks are gone is not a change: it
+// counts as unchanged and is listed in Kept. It fails when cfg disables the
+// tags view: rebuild removes that view, so an empty tree would otherwise
+// read as every tag being deleted.
+func ReverseRebuild(baseDir string, cfg *Config) (*ReverseRebuildReport, error) {
+	cfg = cfg.orDefault()
+
+	if !cfg.viewEnabled(ViewTags) {
+		return nil, fmt.Errorf("reverse rebuild: the %q view is disabled in %s", ViewTags, ConfigFilename)
+	}
+
 	fsTags, err := ScanTagsFromFS(baseDir)
 	if err != nil {
 		return nil, fmt.Errorf("reverse rebuild: %w", err)
 	}
 
-	idDir := filepath.Join(baseDir, "notes", "by", "id")
+	idDir := cfg.idDir(baseDir)
 
-	notes, readErrs, err := readNotesFromDir(idDir)
+	notes, readErrs, err := readNotesFromDir(idDir, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("reverse rebuild: %w", err)
 	}
@@ -145,51 +215,81 @@ func ReverseRebuild(baseDir string) (*ReverseRebuildReport, error) {
 		fromFS := fsTags[id]
 		newTags := reconcileTags(note.Tags, fromFS)
 
-		if tagsEqual(note.Tags, newTags) {
+		// Inline tags live in the body, which is never rewritten, so they
+		// stay even when their symlink directory was removed.
+		var kept []string
+		for _, t := range note.BodyTags {
+			if !slices.Contains(newTags, t) {
+				kept = append(kept, t)
+			}
+		}
+		newTags = append(newTags, kept...)
+
+		if tagsEqual(note.Tags, newTags) && len(kept) == 0 {
+			report.Unchanged++
+			continue
+		}
+		if len(kept) > 0 && tagsEqual(sortedTags(note.Tags), sortedTags(newTags)) {
 			report.Unchanged++
+			report.Kept = append(report.Kept, KeptInlineTags{ID: id, Tags: kept})
 			continue
 		}
 
 		report.Changes = append(report.Changes, TagChange{
-			ID:      id,
-			Path:    filepath.Join(idDir, NoteFilename(note.ID, note.Slug)),
-			OldTags: note.Tags,
-			NewTags: newTags,
+			ID:       id,
+			Path:     filepath.Join(idDir, NoteFilename(note.ID, note.Slug)),
+			OldTags:  note.Tags,
+			NewTags:  newTags,
+			KeptTags: kept,
 		})
 	}
 
 	sort.Slice(report.Changes, func(i, j int) bool {
 		return report.Changes[i].ID < report.Changes[j].ID
 	})
+	sort.Slice(report.Kept, func(i, j int) bool {
+		return report.Kept[i].ID < report.Kept[j].ID
+	})
 
 	return report, nil
 }
 
-func ExecuteReverseRebuild(baseDir string, changes []TagChange) error {
+func ExecuteReverseRebuild(baseDir string, cfg *Config, changes []TagChange) error {
+	cfg = cfg.orDefault()
+
 	for _, tc := range changes {
 		data, err := os.ReadFile(tc.Path)
 		if err != nil {
 			return fmt.Errorf("reverse rebuild: read %s: %w", tc.Path, err)
 		}
 
-		note, err := ReadNote(tc.ID, bytes.NewReader(data))
+		note, err := readNote(tc.ID, bytes.NewReader(data), cfg)
 		if err != nil {
 			return fmt.Errorf("reverse rebuild: parse %s: %w", tc.Path, err)
 		}
 
-		if len(tc.NewTags) == 0 {
-			note.Frontmatter.Unset("tags")
-		} else {
-			note.Frontmatter.Set("tags", FormatTags(tc.NewTags))
+		// Only frontmatter tags are written back: a tag that comes from
+		// the body alone is not duplicated into the frontmatter.
+		oldTags := listField(note.Frontmatter, "tags")
+		var fmTags []string
+		for _, t := range tc.NewTags {
+			if slices.Contains(oldTags, t) || !slices.Contains(note.BodyTags, t) {
+				fmTags = append(fmTags, t)
+			}
 		}
-		note.deriveFields()
+		if tagsEqual(oldTags, fmTags) {
+			continue
+		}
+
+		setListField(note.Frontmatter, "tags", fmTags)
+		note.deriveFields(cfg)
 
-		if err := os.WriteFile(tc.Path, []byte(note.Markdown()), 0o644); err != nil {
+		if err := writeFileAtomic(tc.Path, []byte(note.Markdown()), 0o644); err != nil {
 			return fmt.Errorf("reverse rebuild: write %s: %w", tc.Path, err)
 		}
 	}
 
-	return RebuildSymlinks(baseDir)
+	return RebuildSymlinks(baseDir, cfg)
 }
 
 // reconcileTags merges existing note tags with tags discovered from the
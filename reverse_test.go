@@ -1,6 +1,7 @@
 package gonotes
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"sort"
@@ -30,7 +31,7 @@ tags: other
 
 Body.`)
 
-	if err := RebuildSymlinks(baseDir); err != nil {
+	if err := RebuildSymlinks(baseDir, nil); err != nil {
 		t.Fatalf("RebuildSymlinks() err = %q", err)
 	}
 
@@ -63,7 +64,7 @@ tags: foo/bar, foo/bar/zar
 
 Body.`)
 
-	if err := RebuildSymlinks(baseDir); err != nil {
+	if err := RebuildSymlinks(baseDir, nil); err != nil {
 		t.Fatalf("RebuildSymlinks() err = %q", err)
 	}
 
@@ -140,7 +141,7 @@ tags: other
 
 Body.`)
 
-	if err := RebuildSymlinks(baseDir); err != nil {
+	if err := RebuildSymlinks(baseDir, nil); err != nil {
 		t.Fatalf("RebuildSymlinks() err = %q", err)
 	}
 
@@ -154,7 +155,7 @@ Body.`)
 		t.Fatal(err)
 	}
 
-	report, err := ReverseRebuild(baseDir)
+	report, err := ReverseRebuild(baseDir, nil)
 	if err != nil {
 		t.Fatalf("ReverseRebuild() err = %q", err)
 	}
@@ -196,14 +197,14 @@ tags: foo/bar, plain
 
 Body.`)
 
-	if err := RebuildSymlinks(baseDir); err != nil {
+	if err := RebuildSymlinks(baseDir, nil); err != nil {
 		t.Fatalf("RebuildSymlinks() err = %q", err)
 	}
 
 	tagsDir := filepath.Join(baseDir, "notes", "by", "tags")
 	os.RemoveAll(filepath.Join(tagsDir, "plain"))
 
-	report, err := ReverseRebuild(baseDir)
+	report, err := ReverseRebuild(baseDir, nil)
 	if err != nil {
 		t.Fatalf("ReverseRebuild() err = %q", err)
 	}
@@ -234,13 +235,13 @@ tags: foo, bar
 
 Body.`)
 
-	if err := RebuildSymlinks(baseDir); err != nil {
+	if err := RebuildSymlinks(baseDir, nil); err != nil {
 		t.Fatalf("RebuildSymlinks() err = %q", err)
 	}
 
 	os.RemoveAll(filepath.Join(baseDir, "notes", "by", "tags"))
 
-	report, err := ReverseRebuild(baseDir)
+	report, err := ReverseRebuild(baseDir, nil)
 	if err != nil {
 		t.Fatalf("ReverseRebuild() err = %q", err)
 	}
@@ -267,7 +268,7 @@ tags: foo/bar
 
 Body.`)
 
-	if err := RebuildSymlinks(baseDir); err != nil {
+	if err := RebuildSymlinks(baseDir, nil); err != nil {
 		t.Fatalf("RebuildSymlinks() err = %q", err)
 	}
 
@@ -280,12 +281,12 @@ Body.`)
 		t.Fatal(err)
 	}
 
-	report, err := ReverseRebuild(baseDir)
+	report, err := ReverseRebuild(baseDir, nil)
 	if err != nil {
 		t.Fatalf("ReverseRebuild() err = %q", err)
 	}
 
-	if err := ExecuteReverseRebuild(baseDir, report.Changes); err != nil {
+	if err := ExecuteReverseRebuild(baseDir, nil, report.Changes); err != nil {
 		t.Fatalf("ExecuteReverseRebuild() err = %q", err)
 	}
 
@@ -314,18 +315,18 @@ tags: foo
 
 Body.`)
 
-	if err := RebuildSymlinks(baseDir); err != nil {
+	if err := RebuildSymlinks(baseDir, nil); err != nil {
 		t.Fatalf("RebuildSymlinks() err = %q", err)
 	}
 
 	os.RemoveAll(filepath.Join(baseDir, "notes", "by", "tags"))
 
-	report, err := ReverseRebuild(baseDir)
+	report, err := ReverseRebuild(baseDir, nil)
 	if err != nil {
 		t.Fatalf("ReverseRebuild() err = %q", err)
 	}
 
-	if err := ExecuteReverseRebuild(baseDir, report.Changes); err != nil {
+	if err := ExecuteReverseRebuild(baseDir, nil, report.Changes); err != nil {
 		t.Fatalf("ExecuteReverseRebuild() err = %q", err)
 	}
 
@@ -339,6 +340,102 @@ Body.`)
 	}
 }
 
+func TestReverseRebuildInlineTags(t *testing.T) {
+	baseDir := t.TempDir()
+	idDir := filepath.Join(baseDir, "notes", "by", "id")
+	cfg := DefaultConfig()
+	cfg.InlineTags = true
+
+	writeTestNote(t, idDir, "20260328-1-hello.md", `---
+title: Hello
+date: 2026-03-28 14:30:00
+tags: foo, bar
+---
+
+Working on #go/generics and #foo.`)
+
+	if err := RebuildSymlinks(baseDir, cfg); err != nil {
+		t.Fatalf("RebuildSymlinks() err = %q", err)
+	}
+
+	tagsDir := filepath.Join(baseDir, "notes", "by", "tags")
+	for _, tag := range []string{"bar", "foo", filepath.Join("go", "generics")} {
+		if err := os.Remove(filepath.Join(tagsDir, tag, "20260328-1-hello.md")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	newDir := filepath.Join(tagsDir, "new")
+	if err := os.MkdirAll(newDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(newDir, "20260328-1-hello.md"), []byte(""), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := ReverseRebuild(baseDir, cfg)
+	if err != nil {
+		t.Fatalf("ReverseRebuild() err = %q", err)
+	}
+
+	want := []TagChange{{
+		ID:       "20260328-1",
+		Path:     filepath.Join(idDir, "20260328-1-hello.md"),
+		OldTags:  []string{"foo", "bar", "go/generics"},
+		NewTags:  []string{"new", "go/generics", "foo"},
+		KeptTags: []string{"go/generics", "foo"},
+	}}
+	if diff := cmp.Diff(want, report.Changes); diff != "" {
+		t.Errorf("ReverseRebuild() changes diff (-want, +got):\n%s", diff)
+	}
+
+	if err := ExecuteReverseRebuild(baseDir, cfg, report.Changes); err != nil {
+		t.Fatalf("ExecuteReverseRebuild() err = %q", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(idDir, "20260328-1-hello.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// foo is still written inline, so it stays in the frontmatter too;
+	// go/generics only lives in the body and is not copied over.
+	if !strings.Contains(string(content), "tags: new, foo\n") {
+		t.Errorf("frontmatter tags not updated:\n%s", content)
+	}
+	if !strings.Contains(string(content), "Working on #go/generics and #foo.") {
+		t.Errorf("body changed:\n%s", content)
+	}
+	if _, err := os.Lstat(filepath.Join(tagsDir, "go", "generics", "20260328-1-hello.md")); err != nil {
+		t.Errorf("inline tag symlink not restored: %v", err)
+	}
+}
+
+func TestReverseRebuildOnlyKeptInlineTags(t *testing.T) {
+	baseDir := t.TempDir()
+	idDir := filepath.Join(baseDir, "notes", "by", "id")
+	cfg := DefaultConfig()
+	cfg.InlineTags = true
+
+	writeTestNote(t, idDir, "20260328-1-hello.md", "---\ntitle: Hello\ntags: foo\n---\n\nWorking on #go and #foo.")
+	if err := RebuildSymlinks(baseDir, cfg); err != nil {
+		t.Fatalf("RebuildSymlinks() err = %q", err)
+	}
+	if err := os.Remove(filepath.Join(baseDir, "notes", "by", "tags", "go", "20260328-1-hello.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := ReverseRebuild(baseDir, cfg)
+	if err != nil {
+		t.Fatalf("ReverseRebuild() err = %q", err)
+	}
+	want := &ReverseRebuildReport{
+		Kept:      []KeptInlineTags{{ID: "20260328-1", Tags: []string{"go"}}},
+		Unchanged: 1,
+	}
+	if diff := cmp.Diff(want, report); diff != "" {
+		t.Errorf("ReverseRebuild() diff (-want, +got):\n%s", diff)
+	}
+}
+
 func TestScanTagsFromFSFileInTagsRoot(t *testing.T) {
 	baseDir := t.TempDir()
 	idDir := filepath.Join(baseDir, "notes", "by", "id")
@@ -351,7 +448,7 @@ tags: foo
 
 Body.`)
 
-	if err := RebuildSymlinks(baseDir); err != nil {
+	if err := RebuildSymlinks(baseDir, nil); err != nil {
 		t.Fatalf("RebuildSymlinks() err = %q", err)
 	}
 
@@ -383,7 +480,7 @@ tags: foo, bar
 
 Body.`)
 
-	if err := RebuildSymlinks(baseDir); err != nil {
+	if err := RebuildSymlinks(baseDir, nil); err != nil {
 		t.Fatalf("RebuildSymlinks() err = %q", err)
 	}
 
@@ -394,7 +491,7 @@ Body.`)
 		t.Fatal(err)
 	}
 
-	report, err := ReverseRebuild(baseDir)
+	report, err := ReverseRebuild(baseDir, nil)
 	if err != nil {
 		t.Fatalf("ReverseRebuild() err = %q", err)
 	}
@@ -481,3 +578,44 @@ func TestReconcileTags(t *testing.T) {
 		})
 	}
 }
+
+func TestReverseRebuildReportJSON(t *testing.T) {
+	report := ReverseRebuildReport{
+		Changes: []TagChange{{ID: "20260328-1", Path: "p.md", NewTags: []string{"go"}}},
+	}
+
+	got, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("Marshal() err = %q", err)
+	}
+
+	want := `{"changes":[{"id":"20260328-1","path":"p.md","old_tags":[],"new_tags":["go"],"kept_tags":[]}],"kept":[],"unchanged":0,"errors":[]}`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("Marshal() diff (-want, +got):\n%s", diff)
+	}
+}
+
+func TestReverseRebuildTagsViewDisabled(t *testing.T) {
+	baseDir := t.TempDir()
+	idDir := filepath.Join(baseDir, "notes", "by", "id")
+	writeTestNote(t, baseDir, ConfigFilename, "views: [date]\n")
+	writeTestNote(t, idDir, "20260328-1-hello.md", `---
+title: Hello
+date: 2026-03-28 14:30:00
+tags: go, tools
+---
+
+Body.`)
+
+	cfg, err := LoadConfig(baseDir)
+	if err != nil {
+		t.Fatalf("LoadConfig() err = %q", err)
+	}
+	if err := RebuildSymlinks(baseDir, cfg); err != nil {
+		t.Fatalf("RebuildSymlinks() err = %q", err)
+	}
+
+	if report, err := ReverseRebuild(baseDir, cfg); err == nil {
+		t.Errorf("ReverseRebuild() = %s, want error", report)
+	}
+}
@@ -1,7 +1,10 @@
 package gonotes
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"sort"
@@ -17,25 +20,65 @@ func matchesAny(target string, patterns []string) bool {
 	return false
 }
 
+// BrokenLink is a [[link]] whose target does not exist, or whose target
+// has no such heading or block. TargetID is the link without its display
+// text.
 type BrokenLink struct {
-	SourceID string
-	TargetID string
+	SourceID string `json:"source_id"`
+	TargetID string `json:"target_id"`
 }
 
 type Rename struct {
-	OldName string
-	NewName string
+	OldName string `json:"old_name"`
+	NewName string `json:"new_name"`
+}
+
+// LinkRewrite is a [[link]] in a note that still uses a stale filename
+// for its target, typically because the target note was retitled.
+type LinkRewrite struct {
+	SourceID  string `json:"source_id"`
+	Filename  string `json:"filename"`
+	OldTarget string `json:"old_target"`
+	NewTarget string `json:"new_target"`
 }
 
 type ScanError struct {
-	Filename string
-	Message  string
+	Filename string `json:"filename"`
+	Message  string `json:"message"`
 }
 
 type RebuildReport struct {
-	BrokenLinks []BrokenLink
-	Renames     []Rename
-	Errors      []ScanError
+	BrokenLinks  []BrokenLink  `json:"broken_links"`
+	Renames      []Rename      `json:"renames"`
+	LinkRewrites []LinkRewrite `json:"link_rewrites"`
+	// TitleLinks are [[links]] that name a note by title or alias. They
+	// resolve as they are; rewriting them to ID links is optional.
+	TitleLinks []LinkRewrite `json:"title_links"`
+	Errors     []ScanError   `json:"errors"`
+
+	// Graph holds the links between the scanned notes.
+	Graph *LinkGraph `json:"-"`
+}
+
+// MarshalJSON encodes the report with empty lists rather than null, so
+// every field is always present. The link graph is left out.
+func (r RebuildReport) MarshalJSON() ([]byte, error) {
+	type report RebuildReport
+	out := report(r)
+	out.BrokenLinks = nonNil(out.BrokenLinks)
+	out.Renames = nonNil(out.Renames)
+	out.LinkRewrites = nonNil(out.LinkRewrites)
+	out.TitleLinks = nonNil(out.TitleLinks)
+	out.Errors = nonNil(out.Errors)
+	return json.Marshal(out)
+}
+
+// nonNil returns s, or an empty slice if s is nil, so it encodes as [].
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
 }
 
 func (r *RebuildReport) String() string {
@@ -55,6 +98,20 @@ func (r *RebuildReport) String() string {
 		}
 	}
 
+	if len(r.LinkRewrites) > 0 {
+		fmt.Fprintf(&b, "Link rewrites (%d):\n", len(r.LinkRewrites))
+		for _, lr := range r.LinkRewrites {
+			fmt.Fprintf(&b, "  %s: [[%s]] -> [[%s]]\n", lr.SourceID, lr.OldTarget, lr.NewTarget)
+		}
+	}
+
+	if len(r.TitleLinks) > 0 {
+		fmt.Fprintf(&b, "Title links (%d):\n", len(r.TitleLinks))
+		for _, lr := range r.TitleLinks {
+			fmt.Fprintf(&b, "  %s: [[%s]] -> [[%s]]\n", lr.SourceID, lr.OldTarget, lr.NewTarget)
+		}
+	}
+
 	if len(r.Errors) > 0 {
 		fmt.Fprintf(&b, "Errors (%d):\n", len(r.Errors))
 		for _, e := range r.Errors {
@@ -62,18 +119,19 @@ func (r *RebuildReport) String() string {
 		}
 	}
 
-	if len(r.BrokenLinks) == 0 && len(r.Renames) == 0 && len(r.Errors) == 0 {
+	if len(r.BrokenLinks) == 0 && len(r.Renames) == 0 && len(r.LinkRewrites) == 0 && len(r.TitleLinks) == 0 && len(r.Errors) == 0 {
 		b.WriteString("No issues found.\n")
 	}
 
 	return b.String()
 }
 
-func ScanNotes(baseDir string) (*RebuildReport, error) {
-	idDir := filepath.Join(baseDir, "notes", "by", "id")
-	filesDir := filepath.Join(baseDir, "files")
+func ScanNotes(baseDir string, cfg *Config) (*RebuildReport, error) {
+	cfg = cfg.orDefault()
+	idDir := cfg.idDir(baseDir)
+	filesDir := cfg.filesDir(baseDir)
 
-	files, readErrs, err := readNoteFiles(idDir)
+	files, readErrs, err := readNoteFiles(idDir, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("scan notes: %w", err)
 	}
@@ -90,12 +148,24 @@ func ScanNotes(baseDir string) (*RebuildReport, error) {
 	var scanErrors []ScanError
 	maxNums := map[string]int{}
 	idSet := make(map[string]struct{})
+	anchors := map[string]noteAnchors{}
+	names := noteNames{}
+	graph := newLinkGraph()
 
 	for i := range files {
 		nf := &files[i]
 		name := nf.Filename
 		id, parsed := IDFromFilename(name)
 
+		// The note is still scanned, but it has no date symlink until its
+		// date is fixed.
+		if err := dateError(&nf.Note, cfg); err != nil {
+			scanErrors = append(scanErrors, ScanError{
+				Filename: name,
+				Message:  err.Error(),
+			})
+		}
+
 		correctName := name
 		if parsed {
 			correctName = NoteFilename(id, nf.Slug)
@@ -128,6 +198,9 @@ func ScanNotes(baseDir string) (*RebuildReport, error) {
 			continue
 		}
 		idSet[id] = struct{}{}
+		anchors[id] = parseAnchors(nf.Body)
+		names.add(id, &nf.Note)
+		graph.addNote(id, nf.Title, nf.Body)
 
 		infos = append(infos, noteInfo{
 			id:            id,
@@ -144,31 +217,87 @@ func ScanNotes(baseDir string) (*RebuildReport, error) {
 		return infos[i].id < infos[j].id
 	})
 
+	graph.resolveNames(idSet, names)
+	graph.sort()
+
 	report := &RebuildReport{
 		Errors: scanErrors,
+		Graph:  graph,
 	}
 
+	// Stems of the names notes will have after renaming, keyed by ID and by
+	// their current stem, used to spot links that spell out a stale name.
+	correctStems := make(map[string]string, len(infos))
+	renamedStems := map[string]string{}
 	for _, n := range infos {
-		for _, target := range n.internalLinks {
-			if matchesAny(target, n.ignoreLinks) {
+		stem := strings.TrimSuffix(n.correctName, ".md")
+		correctStems[n.id] = stem
+		if n.currentName != n.correctName {
+			renamedStems[strings.TrimSuffix(n.currentName, ".md")] = stem
+		}
+	}
+
+	for _, n := range infos {
+		// Rewrites and ambiguous links are reported once per note.
+		seen := map[string]struct{}{}
+		for _, raw := range n.internalLinks {
+			if linkIgnored(raw, n.ignoreLinks) {
 				continue
 			}
-			targetID := target
-			if !strings.Contains(target, "/") {
-				if m := reIDPrefix.FindStringSubmatch(target); m != nil {
-					targetID = m[1] + "-" + m[2]
-				}
+			_, done := seen[raw]
+			seen[raw] = struct{}{}
+
+			link := ParseWikiLink(raw)
+			newTarget, stale := staleLinkTarget(link.Target, correctStems, renamedStems)
+			if stale && !done {
+				report.LinkRewrites = append(report.LinkRewrites, LinkRewrite{
+					SourceID:  n.id,
+					Filename:  n.currentName,
+					OldTarget: raw,
+					NewTarget: withLinkTarget(raw, newTarget),
+				})
 			}
-			if _, exists := idSet[targetID]; exists {
-				continue
+
+			targetID := n.id
+			if link.Target != "" {
+				targetID = linkTargetID(raw)
 			}
-			filePath := filepath.Join(filesDir, target)
-			if _, err := os.Stat(filePath); err == nil {
-				continue
+			resolved := linkResolves(raw, idSet, filesDir)
+			if !resolved && !stale {
+				// Titles and aliases are only tried for links that name
+				// no note ID, filename or file.
+				switch ids := names.lookup(link.Target); {
+				case len(ids) == 1:
+					targetID, resolved = ids[0], true
+					if !done {
+						report.TitleLinks = append(report.TitleLinks, LinkRewrite{
+							SourceID:  n.id,
+							Filename:  n.currentName,
+							OldTarget: raw,
+							NewTarget: idLinkTarget(raw, targetID),
+						})
+					}
+				case len(ids) > 1:
+					if !done {
+						ids = append([]string(nil), ids...)
+						sort.Strings(ids)
+						report.Errors = append(report.Errors, ScanError{
+							Filename: n.currentName,
+							Message:  fmt.Sprintf("ambiguous link [[%s]]: matches %s", raw, strings.Join(ids, ", ")),
+						})
+					}
+					continue
+				}
+			}
+			if resolved {
+				// Headings and blocks are only checked in notes.
+				if a, isNote := anchors[targetID]; !isNote || a.has(link) {
+					continue
+				}
 			}
 			report.BrokenLinks = append(report.BrokenLinks, BrokenLink{
 				SourceID: n.id,
-				TargetID: target,
+				TargetID: brokenLinkTarget(raw),
 			})
 		}
 
@@ -183,6 +312,100 @@ func ScanNotes(baseDir string) (*RebuildReport, error) {
 	return report, nil
 }
 
+// linkResolves reports whether the target of the [[link]] text raw names a
+// note in ids or a file under filesDir. A link into the note itself always
+// resolves; its heading or block is not checked.
+func linkResolves(raw string, ids map[string]struct{}, filesDir string) bool {
+	target := ParseWikiLink(raw).Target
+	if target == "" {
+		return true
+	}
+	if _, exists := ids[linkTargetID(target)]; exists {
+		return true
+	}
+	_, err := os.Stat(filepath.Join(filesDir, target))
+	return err == nil
+}
+
+// staleLinkTarget reports the target a link should use instead of target,
+// the target part of the link only.
+// A link is stale when it names a file that is about to be renamed, or when
+// it spells out a note ID with a slug that no longer matches the note's
+// title. Bare ID links never go stale.
+func staleLinkTarget(target string, correctStems, renamedStems map[string]string) (string, bool) {
+	if strings.Contains(target, "/") {
+		return "", false
+	}
+	id := linkTargetID(target)
+	if id == target && reIDPrefix.MatchString(target) {
+		return "", false
+	}
+	if stem, ok := renamedStems[target]; ok {
+		return stem, true
+	}
+	if id == target {
+		return "", false
+	}
+	stem, ok := correctStems[id]
+	if !ok || stem == target {
+		return "", false
+	}
+	return stem, true
+}
+
+// ExecuteLinkRewrites replaces stale [[link]] targets in the notes in idDir.
+// Only the link text changes; the rest of each file is preserved byte for
+// byte. Rewrites refer to the current filenames, so they must be applied
+// before ExecuteRenames. It returns the filenames of the notes it changed.
+func ExecuteLinkRewrites(idDir string, rewrites []LinkRewrite) ([]string, error) {
+	order, byFile := groupLinkRewrites(rewrites)
+
+	var touched []string
+	for _, name := range order {
+		path := filepath.Join(idDir, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return touched, fmt.Errorf("rewrite links: %w", err)
+		}
+
+		content := applyLinkRewrites(string(data), byFile[name])
+		if content == string(data) {
+			continue
+		}
+
+		if err := writeFileAtomic(path, []byte(content), 0o644); err != nil {
+			return touched, fmt.Errorf("rewrite links: %w", err)
+		}
+		touched = append(touched, name)
+	}
+
+	return touched, nil
+}
+
+// groupLinkRewrites groups rewrites by filename, keeping first-seen order.
+func groupLinkRewrites(rewrites []LinkRewrite) ([]string, map[string][]LinkRewrite) {
+	var order []string
+	byFile := map[string][]LinkRewrite{}
+	for _, lr := range rewrites {
+		if _, ok := byFile[lr.Filename]; !ok {
+			order = append(order, lr.Filename)
+		}
+		byFile[lr.Filename] = append(byFile[lr.Filename], lr)
+	}
+	return order, byFile
+}
+
+// applyLinkRewrites replaces the link text of each rewrite in content,
+// leaving code blocks and code spans alone.
+func applyLinkRewrites(content string, rewrites []LinkRewrite) string {
+	return mapProse(content, func(text string) string {
+		for _, lr := range rewrites {
+			text = strings.ReplaceAll(text, "[["+lr.OldTarget+"]]", "[["+lr.NewTarget+"]]")
+		}
+		return text
+	})
+}
+
 func ExecuteRenames(idDir string, renames []Rename) error {
 	for _, rn := range renames {
 		oldPath := filepath.Join(idDir, rn.OldName)
@@ -194,30 +417,149 @@ func ExecuteRenames(idDir string, renames []Rename) error {
 	return nil
 }
 
-func RebuildSymlinks(baseDir string) error {
-	byDir := filepath.Join(baseDir, "notes", "by")
-	idDir := filepath.Join(byDir, "id")
+// SymlinkChanges lists what SyncSymlinks changed. Removed holds paths
+// relative to the vault root.
+type SymlinkChanges struct {
+	Added      []Link   `json:"added"`
+	Retargeted []Link   `json:"retargeted"`
+	Removed    []string `json:"removed"`
+}
 
-	for _, dir := range []string{"date", "tags"} {
-		p := filepath.Join(byDir, dir)
-		if err := os.RemoveAll(p); err != nil {
-			return fmt.Errorf("rebuild symlinks: remove %s: %w", dir, err)
-		}
+// MarshalJSON encodes the changes with empty lists rather than null.
+func (c SymlinkChanges) MarshalJSON() ([]byte, error) {
+	type changes SymlinkChanges
+	out := changes(c)
+	out.Added = nonNil(out.Added)
+	out.Retargeted = nonNil(out.Retargeted)
+	out.Removed = nonNil(out.Removed)
+	return json.Marshal(out)
+}
+
+func (c *SymlinkChanges) String() string {
+	var b strings.Builder
+	for _, p := range c.Removed {
+		fmt.Fprintf(&b, "unlink: %s\n", p)
+	}
+	for _, l := range c.Retargeted {
+		fmt.Fprintf(&b, "relink: %s -> %s\n", l.Path, l.Target)
 	}
+	b.WriteString((&Plan{Links: c.Added}).String())
+	return b.String()
+}
+
+// RebuildSymlinks brings notes/by/date and notes/by/tags in line with the
+// notes in the configured notes directory. See SyncSymlinks.
+func RebuildSymlinks(baseDir string, cfg *Config) error {
+	_, err := SyncSymlinks(baseDir, cfg)
+	return err
+}
+
+// SyncSymlinks computes the symlinks every note in the configured notes
+// directory should have and compares them with what is on disk under
+// notes/by/date and notes/by/tags. Only the differences are applied: missing
+// links are added, links pointing elsewhere are retargeted in place, and
+// anything else is removed, including everything in a view that cfg
+// disables. Directories left empty are pruned. Links that are already
+// correct are not touched, so an interrupted run leaves the views usable.
+func SyncSymlinks(baseDir string, cfg *Config) (*SymlinkChanges, error) {
+	cfg = cfg.orDefault()
+	byDir := filepath.Join(baseDir, "notes", "by")
 
-	files, _, err := readNoteFiles(idDir)
+	files, _, err := readNoteFiles(cfg.idDir(baseDir), cfg)
 	if err != nil {
-		return fmt.Errorf("rebuild symlinks: %w", err)
+		return nil, fmt.Errorf("rebuild symlinks: %w", err)
 	}
 
+	desired := map[string]string{}
+	var order []Link
 	for i := range files {
 		nf := &files[i]
-		links := linkEntries(&nf.Note, nf.Filename)
-		plan := &Plan{Links: links}
-		if err := plan.CreateLinks(baseDir); err != nil {
-			return fmt.Errorf("rebuild symlinks: %w", err)
+		for _, l := range linkEntries(&nf.Note, nf.Filename, cfg) {
+			if _, ok := desired[l.Path]; ok {
+				continue
+			}
+			desired[l.Path] = l.Target
+			order = append(order, l)
+		}
+	}
+
+	changes := &SymlinkChanges{}
+	present := make(map[string]struct{}, len(desired))
+
+	for _, view := range []string{ViewDate, ViewTags} {
+		root := filepath.Join(byDir, view)
+		var dirs []string
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) && path == root {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if d.IsDir() {
+				if path != root {
+					dirs = append(dirs, path)
+				}
+				return nil
+			}
+
+			rel, err := filepath.Rel(baseDir, path)
+			if err != nil {
+				return err
+			}
+
+			target, ok := desired[rel]
+			if ok && d.Type()&fs.ModeSymlink != 0 {
+				present[rel] = struct{}{}
+				if cur, err := os.Readlink(path); err == nil && cur == target {
+					return nil
+				}
+				if err := replaceSymlink(target, path); err != nil {
+					return err
+				}
+				changes.Retargeted = append(changes.Retargeted, Link{Path: rel, Target: target})
+				return nil
+			}
+
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+			changes.Removed = append(changes.Removed, rel)
+			return nil
+		})
+		if err != nil {
+			return changes, fmt.Errorf("rebuild symlinks: %w", err)
+		}
+		pruneEmptyDirs(dirs)
+	}
+
+	var missing []Link
+	for _, l := range order {
+		if _, ok := present[l.Path]; !ok {
+			missing = append(missing, l)
 		}
 	}
 
+	plan := &Plan{Links: missing}
+	if err := plan.CreateLinks(baseDir); err != nil {
+		return changes, fmt.Errorf("rebuild symlinks: %w", err)
+	}
+	changes.Added = missing
+
+	return changes, nil
+}
+
+// replaceSymlink atomically points the symlink at path to target by
+// creating the new link next to it and renaming it into place.
+func replaceSymlink(target, path string) error {
+	tmp := path + ".gonotes-tmp"
+	_ = os.Remove(tmp)
+	if err := os.Symlink(target, tmp); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
 	return nil
 }
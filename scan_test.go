@@ -1,10 +1,13 @@
 package gonotes
 
 import (
+	"encoding/json"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestScanNotes(t *testing.T) {
@@ -31,7 +34,7 @@ date: 2026-03-28 16:00:00
 
 Plain note.`)
 
-	report, err := ScanNotes(baseDir)
+	report, err := ScanNotes(baseDir, nil)
 	if err != nil {
 		t.Fatalf("ScanNotes() err = %q", err)
 	}
@@ -65,7 +68,7 @@ title: Hello
 
 No links.`)
 
-	report, err := ScanNotes(baseDir)
+	report, err := ScanNotes(baseDir, nil)
 	if err != nil {
 		t.Fatalf("ScanNotes() err = %q", err)
 	}
@@ -78,6 +81,34 @@ No links.`)
 	}
 }
 
+func TestScanNotesUnparseableDate(t *testing.T) {
+	baseDir := t.TempDir()
+	idDir := filepath.Join(baseDir, "notes", "by", "id")
+
+	writeTestNote(t, idDir, "20260328-1-bad.md", `---
+title: Bad
+date: 28/03/2026
+---`)
+	writeTestNote(t, idDir, "20260328-2-good.md", `---
+title: Good
+date: 2026-03-28T10:00:00+02:00
+---`)
+	writeTestNote(t, idDir, "20260328-3-empty.md", `---
+title: Empty
+date:
+---`)
+
+	report, err := ScanNotes(baseDir, nil)
+	if err != nil {
+		t.Fatalf("ScanNotes() err = %q", err)
+	}
+
+	want := []ScanError{{Filename: "20260328-1-bad.md", Message: `unrecognized date "28/03/2026"`}}
+	if diff := cmp.Diff(want, report.Errors); diff != "" {
+		t.Errorf("Errors diff (-want, +got):\n%s", diff)
+	}
+}
+
 func TestScanNotesFileLinks(t *testing.T) {
 	baseDir := t.TempDir()
 	idDir := filepath.Join(baseDir, "notes", "by", "id")
@@ -98,7 +129,7 @@ date: 2026-04-03 10:00:00
 
 See [[20260403-1-contract-pdfs/doc1.pdf]] and [[20260403-1-contract-pdfs/bad.pdf]].`)
 
-	report, err := ScanNotes(baseDir)
+	report, err := ScanNotes(baseDir, nil)
 	if err != nil {
 		t.Fatalf("ScanNotes() err = %q", err)
 	}
@@ -126,7 +157,7 @@ date: 2026-04-03 10:00:00
 
 See [[20260403-1-docs/readme.txt]].`)
 
-	report, err := ScanNotes(baseDir)
+	report, err := ScanNotes(baseDir, nil)
 	if err != nil {
 		t.Fatalf("ScanNotes() err = %q", err)
 	}
@@ -163,7 +194,7 @@ date: 2026-04-03 11:00:00
 
 Hello.`)
 
-	report, err := ScanNotes(baseDir)
+	report, err := ScanNotes(baseDir, nil)
 	if err != nil {
 		t.Fatalf("ScanNotes() err = %q", err)
 	}
@@ -185,7 +216,7 @@ ignore-links: 20260403-99
 
 See [[20260403-99]] and [[20260403-88]].`)
 
-	report, err := ScanNotes(baseDir)
+	report, err := ScanNotes(baseDir, nil)
 	if err != nil {
 		t.Fatalf("ScanNotes() err = %q", err)
 	}
@@ -210,7 +241,7 @@ ignore-links: some-folder/*
 
 See [[some-folder/doc.pdf]] and [[other-folder/doc.pdf]].`)
 
-	report, err := ScanNotes(baseDir)
+	report, err := ScanNotes(baseDir, nil)
 	if err != nil {
 		t.Fatalf("ScanNotes() err = %q", err)
 	}
@@ -235,7 +266,7 @@ ignore-links: 20260403-99, 20260403-88
 
 See [[20260403-99]] and [[20260403-88]].`)
 
-	report, err := ScanNotes(baseDir)
+	report, err := ScanNotes(baseDir, nil)
 	if err != nil {
 		t.Fatalf("ScanNotes() err = %q", err)
 	}
@@ -271,7 +302,7 @@ date: 2026-03-28 14:30:00
 
 See [[2026-02-12-2233-05-pacman-cheatsheet]] and [[9999-99-99]].`)
 
-	report, err := ScanNotes(baseDir)
+	report, err := ScanNotes(baseDir, nil)
 	if err != nil {
 		t.Fatalf("ScanNotes() err = %q", err)
 	}
@@ -318,7 +349,7 @@ title: Readme
 
 Some info.`)
 
-	report, err := ScanNotes(baseDir)
+	report, err := ScanNotes(baseDir, nil)
 	if err != nil {
 		t.Fatalf("ScanNotes() err = %q", err)
 	}
@@ -350,7 +381,7 @@ date: 2026-03-28 14:30:00
 
 No title here.`)
 
-	report, err := ScanNotes(baseDir)
+	report, err := ScanNotes(baseDir, nil)
 	if err != nil {
 		t.Fatalf("ScanNotes() err = %q", err)
 	}
@@ -421,7 +452,7 @@ Body.`)
 		t.Fatal(err)
 	}
 
-	if err := RebuildSymlinks(baseDir); err != nil {
+	if err := RebuildSymlinks(baseDir, nil); err != nil {
 		t.Fatalf("RebuildSymlinks() err = %q", err)
 	}
 
@@ -464,11 +495,11 @@ date: 2026-03-28 14:30:00
 tags: test
 ---`)
 
-	if err := RebuildSymlinks(baseDir); err != nil {
+	if err := RebuildSymlinks(baseDir, nil); err != nil {
 		t.Fatalf("first RebuildSymlinks() err = %q", err)
 	}
 
-	if err := RebuildSymlinks(baseDir); err != nil {
+	if err := RebuildSymlinks(baseDir, nil); err != nil {
 		t.Fatalf("second RebuildSymlinks() err = %q", err)
 	}
 
@@ -503,7 +534,7 @@ tags: meta
 
 Info.`)
 
-	if err := RebuildSymlinks(baseDir); err != nil {
+	if err := RebuildSymlinks(baseDir, nil); err != nil {
 		t.Fatalf("RebuildSymlinks() err = %q", err)
 	}
 
@@ -560,6 +591,19 @@ func TestRebuildReportString(t *testing.T) {
 		}
 	})
 
+	t.Run("with title links", func(t *testing.T) {
+		r := &RebuildReport{
+			TitleLinks: []LinkRewrite{{SourceID: "a", OldTarget: "My Note", NewTarget: "b|My Note"}},
+		}
+		got := r.String()
+		if !strings.Contains(got, "Title links (1):\n  a: [[My Note]] -> [[b|My Note]]\n") {
+			t.Errorf("String() missing title link entry, got:\n%s", got)
+		}
+		if strings.Contains(got, "No issues found") {
+			t.Errorf("String() should not say 'No issues found' when there are title links")
+		}
+	})
+
 	t.Run("with errors", func(t *testing.T) {
 		r := &RebuildReport{
 			Errors: []ScanError{{Filename: "readme.md", Message: "cannot determine note ID (no parseable ID and no date)"}},
@@ -588,7 +632,7 @@ date: 2026-03-28 14:30:00
 
 Content.`)
 
-	report, err := ScanNotes(baseDir)
+	report, err := ScanNotes(baseDir, nil)
 	if err != nil {
 		t.Fatalf("ScanNotes() err = %q", err)
 	}
@@ -624,7 +668,7 @@ date: 2026-03-28 14:30:00
 
 Second note.`)
 
-	report, err := ScanNotes(baseDir)
+	report, err := ScanNotes(baseDir, nil)
 	if err != nil {
 		t.Fatalf("ScanNotes() err = %q", err)
 	}
@@ -664,7 +708,7 @@ title: No Date Note
 
 Content without date.`)
 
-	report, err := ScanNotes(baseDir)
+	report, err := ScanNotes(baseDir, nil)
 	if err != nil {
 		t.Fatalf("ScanNotes() err = %q", err)
 	}
@@ -710,7 +754,7 @@ title: No Date
 
 Tips.`)
 
-	report, err := ScanNotes(baseDir)
+	report, err := ScanNotes(baseDir, nil)
 	if err != nil {
 		t.Fatalf("ScanNotes() err = %q", err)
 	}
@@ -754,7 +798,7 @@ date: 2026-03-28 15:00:00
 
 Bar content.`)
 
-	report, err := ScanNotes(baseDir)
+	report, err := ScanNotes(baseDir, nil)
 	if err != nil {
 		t.Fatalf("ScanNotes() err = %q", err)
 	}
@@ -798,7 +842,7 @@ date: 2026-03-28 12:00:00
 
 Ccc.`)
 
-	report, err := ScanNotes(baseDir)
+	report, err := ScanNotes(baseDir, nil)
 	if err != nil {
 		t.Fatalf("ScanNotes() err = %q", err)
 	}
@@ -817,4 +861,397 @@ Ccc.`)
 			t.Errorf("error message should mention the ID, got: %s", e.Message)
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestScanNotesLinkRewrites(t *testing.T) {
+	baseDir := t.TempDir()
+	idDir := filepath.Join(baseDir, "notes", "by", "id")
+
+	writeTestNote(t, idDir, "20260328-1-source.md", `---
+title: Source
+---
+
+See [[20260328-2-old-title]], [[20260328-2]] and [[20260328-2-old-title]].
+Also [[20260328-3-stale]] and [[legacy]].`)
+
+	writeTestNote(t, idDir, "20260328-2-old-title.md", `---
+title: New Title
+---
+
+Body.`)
+
+	writeTestNote(t, idDir, "20260328-3-current.md", `---
+title: Current
+---
+
+Body.`)
+
+	writeTestNote(t, idDir, "legacy.md", `---
+title: Legacy
+date: 2026-03-29 10:00:00
+---
+
+Body.`)
+
+	report, err := ScanNotes(baseDir, nil)
+	if err != nil {
+		t.Fatalf("ScanNotes() err = %q", err)
+	}
+
+	want := []LinkRewrite{
+		{SourceID: "20260328-1", Filename: "20260328-1-source.md", OldTarget: "20260328-2-old-title", NewTarget: "20260328-2-new-title"},
+		{SourceID: "20260328-1", Filename: "20260328-1-source.md", OldTarget: "20260328-3-stale", NewTarget: "20260328-3-current"},
+		{SourceID: "20260328-1", Filename: "20260328-1-source.md", OldTarget: "legacy", NewTarget: "20260329-1-legacy"},
+	}
+	if diff := cmp.Diff(want, report.LinkRewrites); diff != "" {
+		t.Errorf("LinkRewrites diff (-want, +got):\n%s", diff)
+	}
+}
+
+func TestExecuteLinkRewrites(t *testing.T) {
+	baseDir := t.TempDir()
+	idDir := filepath.Join(baseDir, "notes", "by", "id")
+
+	content := "---\ntitle: Source\nodd:   spacing\n---\n\nSee [[20260328-2-old-title]] and [[20260328-2-old-title]].\nKeep [[20260328-2]].\n"
+	code := "\n```\n[[20260328-2-old-title]]\n```\n\nCode `[[20260328-2-old-title]]` stays.\n"
+	writeTestNote(t, idDir, "20260328-1-source.md", content+code)
+	writeTestNote(t, idDir, "20260328-2-old-title.md", "---\ntitle: New Title\n---\n")
+
+	report, err := ScanNotes(baseDir, nil)
+	if err != nil {
+		t.Fatalf("ScanNotes() err = %q", err)
+	}
+
+	touched, err := ExecuteLinkRewrites(idDir, report.LinkRewrites)
+	if err != nil {
+		t.Fatalf("ExecuteLinkRewrites() err = %q", err)
+	}
+	if diff := cmp.Diff([]string{"20260328-1-source.md"}, touched); diff != "" {
+		t.Errorf("touched diff (-want, +got):\n%s", diff)
+	}
+
+	got, err := os.ReadFile(filepath.Join(idDir, "20260328-1-source.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.ReplaceAll(content, "20260328-2-old-title", "20260328-2-new-title") + code
+	if string(got) != want {
+		t.Errorf("rewritten content = %q, want %q", got, want)
+	}
+
+	if err := ExecuteRenames(idDir, report.Renames); err != nil {
+		t.Fatalf("ExecuteRenames() err = %q", err)
+	}
+
+	report, err = ScanNotes(baseDir, nil)
+	if err != nil {
+		t.Fatalf("ScanNotes() err = %q", err)
+	}
+	if len(report.LinkRewrites) != 0 || len(report.BrokenLinks) != 0 {
+		t.Errorf("after rewrite and rename: rewrites = %v, broken = %v, want none", report.LinkRewrites, report.BrokenLinks)
+	}
+}
+
+func TestSyncSymlinksAppliesOnlyDifferences(t *testing.T) {
+	baseDir := t.TempDir()
+	idDir := filepath.Join(baseDir, "notes", "by", "id")
+	tagsDir := filepath.Join(baseDir, "notes", "by", "tags")
+
+	writeTestNote(t, idDir, "20260328-1-hello.md", `---
+title: Hello
+date: 2026-03-28 14:30:00
+tags: keep, foo/bar
+---
+
+Body.`)
+
+	if _, err := SyncSymlinks(baseDir, nil); err != nil {
+		t.Fatalf("SyncSymlinks() err = %q", err)
+	}
+
+	keepLink := filepath.Join(tagsDir, "keep", "20260328-1-hello.md")
+	before, err := os.Lstat(keepLink)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Point the date link elsewhere, add a stray link and file, and change
+	// the note's tags.
+	dateLink := filepath.Join(baseDir, "notes", "by", "date", "2026-03-28", "20260328-1-hello.md")
+	if err := os.Remove(dateLink); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../../id/wrong.md", dateLink); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(tagsDir, "stray", "deep"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../../../id/gone.md", filepath.Join(tagsDir, "stray", "deep", "gone.md")); err != nil {
+		t.Fatal(err)
+	}
+	writeTestNote(t, filepath.Join(tagsDir, "keep"), "notes.txt", "stray file")
+
+	writeTestNote(t, idDir, "20260328-1-hello.md", `---
+title: Hello
+date: 2026-03-28 14:30:00
+tags: keep, other
+---
+
+Body.`)
+
+	changes, err := SyncSymlinks(baseDir, nil)
+	if err != nil {
+		t.Fatalf("SyncSymlinks() err = %q", err)
+	}
+
+	wantChanges := &SymlinkChanges{
+		Added: []Link{
+			{Path: "notes/by/tags/other/20260328-1-hello.md", Target: "../../id/20260328-1-hello.md"},
+		},
+		Retargeted: []Link{
+			{Path: "notes/by/date/2026-03-28/20260328-1-hello.md", Target: "../../id/20260328-1-hello.md"},
+		},
+		Removed: []string{
+			"notes/by/tags/foo/bar/20260328-1-hello.md",
+			"notes/by/tags/keep/notes.txt",
+			"notes/by/tags/stray/deep/gone.md",
+		},
+	}
+	if diff := cmp.Diff(wantChanges, changes); diff != "" {
+		t.Errorf("changes diff (-want, +got):\n%s", diff)
+	}
+
+	after, err := os.Lstat(keepLink)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !os.SameFile(before, after) {
+		t.Error("unchanged symlink was recreated")
+	}
+
+	for _, dir := range []string{"foo", "stray"} {
+		if _, err := os.Stat(filepath.Join(tagsDir, dir)); !os.IsNotExist(err) {
+			t.Errorf("empty directory %s not pruned", dir)
+		}
+	}
+
+	changes, err = SyncSymlinks(baseDir, nil)
+	if err != nil {
+		t.Fatalf("SyncSymlinks() err = %q", err)
+	}
+	if diff := cmp.Diff(&SymlinkChanges{}, changes); diff != "" {
+		t.Errorf("second sync changes diff (-want, +got):\n%s", diff)
+	}
+}
+
+func TestRebuildReportJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want string
+	}{
+		{
+			name: "empty report",
+			v:    &RebuildReport{Graph: newLinkGraph()},
+			want: `{"broken_links":[],"renames":[],"link_rewrites":[],"title_links":[],"errors":[]}`,
+		},
+		{
+			name: "report value",
+			v: RebuildReport{
+				BrokenLinks: []BrokenLink{{SourceID: "20260328-1", TargetID: "20260328-99"}},
+				Renames:     []Rename{{OldName: "a.md", NewName: "b.md"}},
+				LinkRewrites: []LinkRewrite{{
+					SourceID: "20260328-1", Filename: "20260328-1-a.md",
+					OldTarget: "20260328-2-old", NewTarget: "20260328-2-new",
+				}},
+				Errors: []ScanError{{Filename: "x.md", Message: "bad"}},
+			},
+			want: `{"broken_links":[{"source_id":"20260328-1","target_id":"20260328-99"}],` +
+				`"renames":[{"old_name":"a.md","new_name":"b.md"}],` +
+				`"link_rewrites":[{"source_id":"20260328-1","filename":"20260328-1-a.md","old_target":"20260328-2-old","new_target":"20260328-2-new"}],` +
+				`"title_links":[],` +
+				`"errors":[{"filename":"x.md","message":"bad"}]}`,
+		},
+		{
+			name: "symlink changes",
+			v:    &SymlinkChanges{Added: []Link{{Path: "p", Target: "t"}}},
+			want: `{"added":[{"path":"p","target":"t"}],"retargeted":[],"removed":[]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.v)
+			if err != nil {
+				t.Fatalf("Marshal() err = %q", err)
+			}
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Errorf("Marshal() diff (-want, +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestScanNotesStructuredLinks(t *testing.T) {
+	baseDir := t.TempDir()
+	idDir := filepath.Join(baseDir, "notes", "by", "id")
+
+	writeTestNote(t, idDir, "20260328-1-source.md", `---
+title: Source
+---
+
+## Intro
+
+See [[20260328-2|see this]], [[20260328-2#Setup]], [[20260328-2-target#setup]]
+and [[20260328-2#^b1]], back to [[#Intro]].
+Broken: [[20260328-2#Missing|missing heading]], [[20260328-2#^b2]],
+[[#Nowhere]] and [[20260328-9#Setup|gone]].`)
+
+	writeTestNote(t, idDir, "20260328-2-target.md", `---
+title: Target
+---
+
+## Setup
+
+A paragraph. ^b1`)
+
+	report, err := ScanNotes(baseDir, nil)
+	if err != nil {
+		t.Fatalf("ScanNotes() err = %q", err)
+	}
+
+	want := []BrokenLink{
+		{SourceID: "20260328-1", TargetID: "20260328-2#Missing"},
+		{SourceID: "20260328-1", TargetID: "20260328-2#^b2"},
+		{SourceID: "20260328-1", TargetID: "#Nowhere"},
+		{SourceID: "20260328-1", TargetID: "20260328-9#Setup"},
+	}
+	if diff := cmp.Diff(want, report.BrokenLinks); diff != "" {
+		t.Errorf("BrokenLinks diff (-want, +got):\n%s", diff)
+	}
+
+	if refs := report.Graph.Backlinks("20260328-1"); len(refs) != 0 {
+		t.Errorf("Backlinks(20260328-1) = %v, want none", refs)
+	}
+	if refs := report.Graph.Backlinks("20260328-2"); len(refs) != 6 {
+		t.Errorf("Backlinks(20260328-2) = %d refs, want 6", len(refs))
+	}
+}
+
+func TestScanNotesLinkRewritesKeepAnchorAndText(t *testing.T) {
+	baseDir := t.TempDir()
+	idDir := filepath.Join(baseDir, "notes", "by", "id")
+
+	content := "---\ntitle: Source\n---\n\nSee [[20260328-2-old-title#Setup|setup]] and [[20260328-2-old-title#^b1]].\n"
+	writeTestNote(t, idDir, "20260328-1-source.md", content)
+	writeTestNote(t, idDir, "20260328-2-old-title.md", "---\ntitle: New Title\n---\n\n## Setup\n\nText ^b1\n")
+
+	report, err := ScanNotes(baseDir, nil)
+	if err != nil {
+		t.Fatalf("ScanNotes() err = %q", err)
+	}
+
+	want := []LinkRewrite{
+		{SourceID: "20260328-1", Filename: "20260328-1-source.md", OldTarget: "20260328-2-old-title#Setup|setup", NewTarget: "20260328-2-new-title#Setup|setup"},
+		{SourceID: "20260328-1", Filename: "20260328-1-source.md", OldTarget: "20260328-2-old-title#^b1", NewTarget: "20260328-2-new-title#^b1"},
+	}
+	if diff := cmp.Diff(want, report.LinkRewrites); diff != "" {
+		t.Errorf("LinkRewrites diff (-want, +got):\n%s", diff)
+	}
+	if len(report.BrokenLinks) != 0 {
+		t.Errorf("BrokenLinks = %v, want none", report.BrokenLinks)
+	}
+
+	if _, err := ExecuteLinkRewrites(idDir, report.LinkRewrites); err != nil {
+		t.Fatalf("ExecuteLinkRewrites() err = %q", err)
+	}
+	got, err := os.ReadFile(filepath.Join(idDir, "20260328-1-source.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.ReplaceAll(content, "old-title", "new-title"); string(got) != want {
+		t.Errorf("rewritten content = %q, want %q", got, want)
+	}
+}
+
+func TestScanNotesTitleLinks(t *testing.T) {
+	baseDir := t.TempDir()
+	idDir := filepath.Join(baseDir, "notes", "by", "id")
+
+	content := `---
+title: Source
+---
+
+See [[my note title]], [[Project Plan#Setup|the setup]] and [[PLAN]].
+Ambiguous [[Meeting]] twice [[Meeting]], broken [[Project Plan#Missing]].`
+	writeTestNote(t, idDir, "20260328-1-source.md", content)
+
+	writeTestNote(t, idDir, "20260328-2-my-note-title.md", `---
+title: My Note Title
+---
+
+Body.`)
+
+	writeTestNote(t, idDir, "20260328-3-project-plan.md", `---
+title: Project Plan
+aliases: [plan, Meeting]
+---
+
+## Setup`)
+
+	writeTestNote(t, idDir, "20260328-4-meeting.md", `---
+title: Meeting
+---
+
+Body.`)
+
+	report, err := ScanNotes(baseDir, nil)
+	if err != nil {
+		t.Fatalf("ScanNotes() err = %q", err)
+	}
+
+	wantTitleLinks := []LinkRewrite{
+		{SourceID: "20260328-1", Filename: "20260328-1-source.md", OldTarget: "my note title", NewTarget: "20260328-2|my note title"},
+		{SourceID: "20260328-1", Filename: "20260328-1-source.md", OldTarget: "Project Plan#Setup|the setup", NewTarget: "20260328-3#Setup|the setup"},
+		{SourceID: "20260328-1", Filename: "20260328-1-source.md", OldTarget: "PLAN", NewTarget: "20260328-3|PLAN"},
+		{SourceID: "20260328-1", Filename: "20260328-1-source.md", OldTarget: "Project Plan#Missing", NewTarget: "20260328-3#Missing|Project Plan"},
+	}
+	if diff := cmp.Diff(wantTitleLinks, report.TitleLinks); diff != "" {
+		t.Errorf("TitleLinks diff (-want, +got):\n%s", diff)
+	}
+
+	wantBroken := []BrokenLink{{SourceID: "20260328-1", TargetID: "Project Plan#Missing"}}
+	if diff := cmp.Diff(wantBroken, report.BrokenLinks); diff != "" {
+		t.Errorf("BrokenLinks diff (-want, +got):\n%s", diff)
+	}
+
+	wantErrors := []ScanError{{
+		Filename: "20260328-1-source.md",
+		Message:  "ambiguous link [[Meeting]]: matches 20260328-3, 20260328-4",
+	}}
+	if diff := cmp.Diff(wantErrors, report.Errors); diff != "" {
+		t.Errorf("Errors diff (-want, +got):\n%s", diff)
+	}
+
+	if refs := report.Graph.Backlinks("20260328-3"); len(refs) != 3 {
+		t.Errorf("Backlinks(20260328-3) = %d refs, want 3", len(refs))
+	}
+
+	if _, err := ExecuteLinkRewrites(idDir, report.TitleLinks); err != nil {
+		t.Fatalf("ExecuteLinkRewrites() err = %q", err)
+	}
+	got, err := os.ReadFile(filepath.Join(idDir, "20260328-1-source.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.NewReplacer(
+		"[[my note title]]", "[[20260328-2|my note title]]",
+		"[[Project Plan#Setup|the setup]]", "[[20260328-3#Setup|the setup]]",
+		"[[PLAN]]", "[[20260328-3|PLAN]]",
+		"[[Project Plan#Missing]]", "[[20260328-3#Missing|Project Plan]]",
+	).Replace(content)
+	if string(got) != want {
+		t.Errorf("rewritten content = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,452 @@
+package gonotes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// indexVersion is bumped whenever the on-disk index format or tokenization
+// changes. An index with a different version is discarded and rebuilt.
+const indexVersion = 1
+
+// BM25 parameters. These are the commonly used defaults.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// Field weights applied to term frequencies at index time. A title match
+// counts for more than a tag match, which counts for more than the body.
+const (
+	weightTitle       = 3
+	weightTags        = 2
+	weightFrontmatter = 1
+	weightBody        = 1
+)
+
+// snippetRadius is the number of bytes of context shown on either side of
+// the first match in a search snippet.
+const snippetRadius = 60
+
+// IndexPath returns the location of the search index for the vault at baseDir.
+func IndexPath(baseDir string) string {
+	return filepath.Join(stateDir(baseDir), "index")
+}
+
+// IndexedDoc is a note as recorded in the search index.
+type IndexedDoc struct {
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	ModTime int64  `json:"mtime"`
+	Size    int64  `json:"size"`
+	Length  int    `json:"length"`
+}
+
+// Posting records how often a term occurs in a document, keyed by filename.
+type Posting struct {
+	Filename string `json:"file"`
+	Freq     int    `json:"freq"`
+}
+
+// Index is a persistent inverted index over the notes in notes/by/id.
+// Docs and Postings are keyed by note filename.
+type Index struct {
+	Version  int                    `json:"version"`
+	Docs     map[string]*IndexedDoc `json:"docs"`
+	Postings map[string][]Posting   `json:"postings"`
+}
+
+func newIndex() *Index {
+	return &Index{
+		Version:  indexVersion,
+		Docs:     map[string]*IndexedDoc{},
+		Postings: map[string][]Posting{},
+	}
+}
+
+// IndexUpdate summarizes what Index.Update changed.
+type IndexUpdate struct {
+	Added   int
+	Updated int
+	Removed int
+	Errors  []ScanError
+}
+
+// LoadIndex reads the index from path. A missing or outdated index yields
+// an empty index rather than an error.
+func LoadIndex(path string) (*Index, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return newIndex(), nil
+		}
+		return nil, fmt.Errorf("load index: %w", err)
+	}
+
+	idx := newIndex()
+	if err := json.Unmarshal(data, idx); err != nil {
+		return nil, fmt.Errorf("load index: %w", err)
+	}
+	if idx.Version != indexVersion || idx.Docs == nil || idx.Postings == nil {
+		return newIndex(), nil
+	}
+	return idx, nil
+}
+
+// Save writes the index to path, creating parent directories as needed.
+func (idx *Index) Save(path string) error {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return fmt.Errorf("save index: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("save index: %w", err)
+	}
+	if err := writeFileAtomic(path, data, 0o644); err != nil {
+		return fmt.Errorf("save index: %w", err)
+	}
+	return nil
+}
+
+// Update brings the index in line with the notes in idDir, read with the
+// rules of cfg. Notes whose modification time and size match the index are
+// not re-read.
+func (idx *Index) Update(idDir string, cfg *Config) (*IndexUpdate, error) {
+	entries, err := noteEntries(idDir)
+	if err != nil {
+		return nil, fmt.Errorf("update index: %w", err)
+	}
+
+	upd := &IndexUpdate{}
+	present := make(map[string]struct{}, len(entries))
+	var stale []string
+	var changed []*noteFile
+	var changedInfo []*IndexedDoc
+
+	for _, e := range entries {
+		name := e.Name()
+		present[name] = struct{}{}
+
+		info, err := e.Info()
+		if err != nil {
+			upd.Errors = append(upd.Errors, ScanError{
+				Filename: name,
+				Message:  fmt.Sprintf("stat: %v", err),
+			})
+			continue
+		}
+
+		doc, ok := idx.Docs[name]
+		if ok && doc.ModTime == info.ModTime().UnixNano() && doc.Size == info.Size() {
+			continue
+		}
+
+		nf := readNoteFile(idDir, name, cfg, &upd.Errors)
+		if ok {
+			stale = append(stale, name)
+		}
+		if nf == nil {
+			continue
+		}
+		if ok {
+			upd.Updated++
+		} else {
+			upd.Added++
+		}
+		changed = append(changed, nf)
+		changedInfo = append(changedInfo, &IndexedDoc{
+			ModTime: info.ModTime().UnixNano(),
+			Size:    info.Size(),
+		})
+	}
+
+	for name := range idx.Docs {
+		if _, ok := present[name]; !ok {
+			stale = append(stale, name)
+			upd.Removed++
+		}
+	}
+
+	idx.remove(stale)
+	for i, nf := range changed {
+		idx.add(nf, changedInfo[i])
+	}
+
+	return upd, nil
+}
+
+// remove drops the given filenames from the index.
+func (idx *Index) remove(names []string) {
+	if len(names) == 0 {
+		return
+	}
+	drop := make(map[string]struct{}, len(names))
+	for _, n := range names {
+		drop[n] = struct{}{}
+		delete(idx.Docs, n)
+	}
+	for term, postings := range idx.Postings {
+		kept := postings[:0]
+		for _, p := range postings {
+			if _, ok := drop[p.Filename]; !ok {
+				kept = append(kept, p)
+			}
+		}
+		if len(kept) == 0 {
+			delete(idx.Postings, term)
+		} else {
+			idx.Postings[term] = kept
+		}
+	}
+}
+
+// add indexes nf. doc must carry the file's ModTime and Size; the remaining
+// fields are filled in from the note.
+func (idx *Index) add(nf *noteFile, doc *IndexedDoc) {
+	freqs := map[string]int{}
+	addTerms := func(s string, weight int) {
+		for _, t := range tokenize(s) {
+			freqs[t] += weight
+		}
+	}
+
+	addTerms(nf.Title, weightTitle)
+	for _, tag := range nf.Tags {
+		addTerms(tag, weightTags)
+	}
+	for _, key := range nf.Frontmatter.Keys() {
+		switch key {
+		case "title", "tags", "date":
+			continue
+		}
+		values, _ := nf.Frontmatter.GetList(key)
+		for _, v := range values {
+			addTerms(v, weightFrontmatter)
+		}
+	}
+	addTerms(nf.Body, weightBody)
+
+	length := 0
+	for _, f := range freqs {
+		length += f
+	}
+
+	doc.ID = nf.ID
+	doc.Title = nf.Title
+	doc.Length = length
+	idx.Docs[nf.Filename] = doc
+
+	for term, f := range freqs {
+		idx.Postings[term] = append(idx.Postings[term], Posting{
+			Filename: nf.Filename,
+			Freq:     f,
+		})
+	}
+}
+
+// SearchResult is a single ranked match.
+type SearchResult struct {
+	ID       string  `json:"id"`
+	Title    string  `json:"title"`
+	Filename string  `json:"filename"`
+	Score    float64 `json:"score"`
+	Snippet  string  `json:"snippet"`
+}
+
+// Search ranks indexed notes against query using BM25. At most limit
+// results are returned; a limit of zero or less returns all matches.
+// Snippets are not filled in; see Snippet.
+func (idx *Index) Search(query string, limit int) []SearchResult {
+	terms := dedupStrings(tokenize(query))
+	if len(terms) == 0 || len(idx.Docs) == 0 {
+		return nil
+	}
+
+	total := 0
+	for _, d := range idx.Docs {
+		total += d.Length
+	}
+	n := float64(len(idx.Docs))
+	avgLen := float64(total) / n
+
+	scores := map[string]float64{}
+	for _, term := range terms {
+		postings := idx.Postings[term]
+		if len(postings) == 0 {
+			continue
+		}
+		df := float64(len(postings))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for _, p := range postings {
+			doc := idx.Docs[p.Filename]
+			if doc == nil {
+				continue
+			}
+			tf := float64(p.Freq)
+			norm := bm25K1 * (1 - bm25B + bm25B*float64(doc.Length)/avgLen)
+			scores[p.Filename] += idf * tf * (bm25K1 + 1) / (tf + norm)
+		}
+	}
+
+	results := make([]SearchResult, 0, len(scores))
+	for name, score := range scores {
+		doc := idx.Docs[name]
+		results = append(results, SearchResult{
+			ID:       doc.ID,
+			Title:    doc.Title,
+			Filename: name,
+			Score:    score,
+		})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Filename < results[j].Filename
+	})
+
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// SearchNotes updates the index for the vault at baseDir, saves it, and
+// returns the ranked results for query with snippets filled in. Matches in
+// snippets are wrapped in hlStart and hlEnd.
+func SearchNotes(baseDir string, cfg *Config, query string, limit int, hlStart, hlEnd string) ([]SearchResult, *IndexUpdate, error) {
+	cfg = cfg.orDefault()
+	idDir := cfg.idDir(baseDir)
+	path := IndexPath(baseDir)
+
+	idx, err := LoadIndex(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("search: %w", err)
+	}
+
+	upd, err := idx.Update(idDir, cfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("search: %w", err)
+	}
+
+	if upd.Added > 0 || upd.Updated > 0 || upd.Removed > 0 {
+		if err := idx.Save(path); err != nil {
+			return nil, nil, fmt.Errorf("search: %w", err)
+		}
+	}
+
+	results := idx.Search(query, limit)
+	terms := tokenize(query)
+	for i := range results {
+		var errs []ScanError
+		nf := readNoteFile(idDir, results[i].Filename, cfg, &errs)
+		if nf == nil {
+			continue
+		}
+		results[i].Snippet = Snippet(nf.Body, terms, hlStart, hlEnd)
+	}
+
+	return results, upd, nil
+}
+
+// Snippet returns a single-line excerpt of body around the first occurrence
+// of any of terms, with every occurrence wrapped in hlStart and hlEnd. When
+// no term occurs in body, the start of the body is returned.
+func Snippet(body string, terms []string, hlStart, hlEnd string) string {
+	text := strings.Join(strings.Fields(body), " ")
+
+	first := -1
+	for i := 0; i < len(text); i++ {
+		if matchTermAt(text, i, terms) > 0 {
+			first = i
+			break
+		}
+	}
+
+	start, end := 0, len(text)
+	if first >= 0 {
+		start = max(0, first-snippetRadius)
+		end = min(len(text), first+snippetRadius)
+	} else {
+		end = min(len(text), 2*snippetRadius)
+	}
+	start = runeStart(text, start)
+	end = runeStart(text, end)
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+
+	i := start
+	for i < end {
+		n := matchTermAt(text, i, terms)
+		if n == 0 {
+			b.WriteByte(text[i])
+			i++
+			continue
+		}
+		b.WriteString(hlStart)
+		b.WriteString(text[i : i+n])
+		b.WriteString(hlEnd)
+		i += n
+	}
+
+	if end < len(text) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
+
+// matchTermAt returns the byte length of the longest term that matches a
+// whole word of text case-insensitively at offset i, or zero if none does.
+func matchTermAt(text string, i int, terms []string) int {
+	if i > 0 {
+		if r, _ := utf8.DecodeLastRuneInString(text[:i]); isTokenRune(r) {
+			return 0
+		}
+	}
+	n := 0
+	for _, t := range terms {
+		end := i + len(t)
+		if t == "" || end > len(text) || len(t) <= n {
+			continue
+		}
+		if !strings.EqualFold(text[i:end], t) {
+			continue
+		}
+		if r, _ := utf8.DecodeRuneInString(text[end:]); end < len(text) && isTokenRune(r) {
+			continue
+		}
+		n = len(t)
+	}
+	return n
+}
+
+// runeStart moves i back to the start of the UTF-8 sequence containing it.
+func runeStart(s string, i int) int {
+	for i > 0 && i < len(s) && s[i]&0xC0 == 0x80 {
+		i--
+	}
+	return i
+}
+
+// tokenize lowercases s and splits it into runs of letters and digits.
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !isTokenRune(r)
+	})
+}
+
+func isTokenRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r)
+}
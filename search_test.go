@@ -0,0 +1,171 @@
+package gonotes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTokenize(t *testing.T) {
+	got := tokenize("Hello, World! programming/go Über-café 2026")
+	want := []string{"hello", "world", "programming", "go", "über", "café", "2026"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("tokenize() diff (-want, +got):\n%s", diff)
+	}
+}
+
+func TestSearchNotes(t *testing.T) {
+	baseDir := t.TempDir()
+	idDir := filepath.Join(baseDir, "notes", "by", "id")
+
+	writeTestNote(t, idDir, "20260328-1-generics.md", `---
+title: Go Generics
+tags: programming/go
+---
+
+Type parameters are useful.`)
+
+	writeTestNote(t, idDir, "20260328-2-cooking.md", `---
+title: Cooking
+author: Alice
+---
+
+Pasta recipes. Nothing about go here, or maybe go once.`)
+
+	writeTestNote(t, idDir, "20260328-3-other.md", `---
+title: Other
+---
+
+Unrelated.`)
+
+	results, upd, err := SearchNotes(baseDir, nil, "go", 0, "[", "]")
+	if err != nil {
+		t.Fatalf("SearchNotes() err = %q", err)
+	}
+	if upd.Added != 3 {
+		t.Errorf("Added = %d, want 3", upd.Added)
+	}
+
+	gotIDs := make([]string, len(results))
+	for i, r := range results {
+		gotIDs[i] = r.ID
+	}
+	if diff := cmp.Diff([]string{"20260328-1", "20260328-2"}, gotIDs); diff != "" {
+		t.Errorf("result IDs diff (-want, +got):\n%s", diff)
+	}
+	if len(results) > 1 {
+		if want := "Pasta recipes. Nothing about [go] here, or maybe [go] once."; results[1].Snippet != want {
+			t.Errorf("snippet = %q, want %q", results[1].Snippet, want)
+		}
+	}
+
+	results, _, err = SearchNotes(baseDir, nil, "alice", 0, "", "")
+	if err != nil {
+		t.Fatalf("SearchNotes() err = %q", err)
+	}
+	if len(results) != 1 || results[0].ID != "20260328-2" {
+		t.Errorf("frontmatter search results = %v, want only 20260328-2", results)
+	}
+
+	if _, err := os.Stat(IndexPath(baseDir)); err != nil {
+		t.Errorf("index not written: %v", err)
+	}
+}
+
+func TestIndexUpdateIncremental(t *testing.T) {
+	baseDir := t.TempDir()
+	idDir := filepath.Join(baseDir, "notes", "by", "id")
+
+	writeTestNote(t, idDir, "20260328-1-a.md", "---\ntitle: A\n---\n\nalpha")
+	writeTestNote(t, idDir, "20260328-2-b.md", "---\ntitle: B\n---\n\nbeta")
+
+	idx := newIndex()
+	upd, err := idx.Update(idDir, nil)
+	if err != nil {
+		t.Fatalf("Update() err = %q", err)
+	}
+	if upd.Added != 2 || upd.Updated != 0 || upd.Removed != 0 {
+		t.Errorf("first update = %+v, want 2 added", upd)
+	}
+
+	upd, err = idx.Update(idDir, nil)
+	if err != nil {
+		t.Fatalf("Update() err = %q", err)
+	}
+	if upd.Added != 0 || upd.Updated != 0 || upd.Removed != 0 {
+		t.Errorf("unchanged update = %+v, want no changes", upd)
+	}
+
+	writeTestNote(t, idDir, "20260328-1-a.md", "---\ntitle: A\n---\n\ngamma")
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(idDir, "20260328-1-a.md"), future, future); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(idDir, "20260328-2-b.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	upd, err = idx.Update(idDir, nil)
+	if err != nil {
+		t.Fatalf("Update() err = %q", err)
+	}
+	if upd.Added != 0 || upd.Updated != 1 || upd.Removed != 1 {
+		t.Errorf("changed update = %+v, want 1 updated, 1 removed", upd)
+	}
+
+	if got := idx.Search("alpha", 0); len(got) != 0 {
+		t.Errorf("Search(alpha) = %v, want no results", got)
+	}
+	if got := idx.Search("beta", 0); len(got) != 0 {
+		t.Errorf("Search(beta) = %v, want no results", got)
+	}
+	if got := idx.Search("gamma", 0); len(got) != 1 {
+		t.Errorf("Search(gamma) = %v, want 1 result", got)
+	}
+
+	path := IndexPath(baseDir)
+	if err := idx.Save(path); err != nil {
+		t.Fatalf("Save() err = %q", err)
+	}
+	loaded, err := LoadIndex(path)
+	if err != nil {
+		t.Fatalf("LoadIndex() err = %q", err)
+	}
+	if diff := cmp.Diff(idx, loaded); diff != "" {
+		t.Errorf("loaded index diff (-want, +got):\n%s", diff)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		terms []string
+		want  string
+	}{
+		{
+			name:  "highlights case-insensitively",
+			body:  "Go is fun, good.\nI like GO.",
+			terms: []string{"go"},
+			want:  "<Go> is fun, good. I like <GO>.",
+		},
+		{
+			name:  "no match returns start",
+			body:  "Nothing here.",
+			terms: []string{"missing"},
+			want:  "Nothing here.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Snippet(tt.body, tt.terms, "<", ">")
+			if got != tt.want {
+				t.Errorf("Snippet() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,19 +1,215 @@
 package gonotes
 
 import (
-	"regexp"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
-var (
-	reDisallowedSlugChars = regexp.MustCompile("[^a-z0-9-]")
-	reMultiDash           = regexp.MustCompile("-{2,}")
+// SlugNonLatin controls what slugify does with letters that have no Latin
+// base form, such as Cyrillic, Greek or Japanese.
+type SlugNonLatin int
+
+const (
+	// SlugTransliterate transliterates Cyrillic and Greek to ASCII and keeps
+	// letters of other scripts unchanged.
+	SlugTransliterate SlugNonLatin = iota
+	// SlugKeepLetters keeps all non-Latin letters unchanged.
+	SlugKeepLetters
+	// SlugASCII transliterates what it can and drops everything else, so
+	// slugs only ever contain [a-z0-9-].
+	SlugASCII
 )
 
+// DefaultMaxSlugLength keeps filenames well below the common 255-byte
+// limit once the ID and extension are added.
+const DefaultMaxSlugLength = 80
+
+// SlugOptions configures slugify. The zero value transliterates and uses
+// DefaultMaxSlugLength.
+type SlugOptions struct {
+	NonLatin SlugNonLatin
+	// MaxLength is the maximum slug length in bytes. Zero means
+	// DefaultMaxSlugLength; a negative value disables the limit.
+	MaxLength int
+}
+
+// latinDecomp maps lowercase precomposed Latin letters to the base letter
+// of their canonical decomposition (NFD), covering Latin-1 Supplement,
+// Latin Extended-A and -B and Latin Extended Additional, which holds the
+// Vietnamese letters. The standard library has no Unicode normalization,
+// and slugs drop the combining marks a decomposition leaves, so only the
+// base letter is kept.
+var latinDecomp = map[rune]rune{
+	'à': 'a', 'á': 'a', 'â': 'a', 'ã': 'a', 'ä': 'a', 'å': 'a', 'ç': 'c',
+	'è': 'e', 'é': 'e', 'ê': 'e', 'ë': 'e', 'ì': 'i', 'í': 'i', 'î': 'i',
+	'ï': 'i', 'ñ': 'n', 'ò': 'o', 'ó': 'o', 'ô': 'o', 'õ': 'o', 'ö': 'o',
+	'ù': 'u', 'ú': 'u', 'û': 'u', 'ü': 'u', 'ý': 'y', 'ÿ': 'y', 'ā': 'a',
+	'ă': 'a', 'ą': 'a', 'ć': 'c', 'ĉ': 'c', 'ċ': 'c', 'č': 'c', 'ď': 'd',
+	'ē': 'e', 'ĕ': 'e', 'ė': 'e', 'ę': 'e', 'ě': 'e', 'ĝ': 'g', 'ğ': 'g',
+	'ġ': 'g', 'ģ': 'g', 'ĥ': 'h', 'ĩ': 'i', 'ī': 'i', 'ĭ': 'i', 'į': 'i',
+	'ĵ': 'j', 'ķ': 'k', 'ĺ': 'l', 'ļ': 'l', 'ľ': 'l', 'ń': 'n', 'ņ': 'n',
+	'ň': 'n', 'ō': 'o', 'ŏ': 'o', 'ő': 'o', 'ŕ': 'r', 'ŗ': 'r', 'ř': 'r',
+	'ś': 's', 'ŝ': 's', 'ş': 's', 'š': 's', 'ţ': 't', 'ť': 't', 'ũ': 'u',
+	'ū': 'u', 'ŭ': 'u', 'ů': 'u', 'ű': 'u', 'ų': 'u', 'ŵ': 'w', 'ŷ': 'y',
+	'ź': 'z', 'ż': 'z', 'ž': 'z', 'ơ': 'o', 'ư': 'u', 'ǎ': 'a', 'ǐ': 'i',
+	'ǒ': 'o', 'ǔ': 'u', 'ǖ': 'u', 'ǘ': 'u', 'ǚ': 'u', 'ǜ': 'u', 'ǟ': 'a',
+	'ǡ': 'a', 'ǣ': 'æ', 'ǧ': 'g', 'ǩ': 'k', 'ǫ': 'o', 'ǭ': 'o', 'ǰ': 'j',
+	'ǵ': 'g', 'ǹ': 'n', 'ǻ': 'a', 'ǽ': 'æ', 'ǿ': 'ø', 'ȁ': 'a', 'ȃ': 'a',
+	'ȅ': 'e', 'ȇ': 'e', 'ȉ': 'i', 'ȋ': 'i', 'ȍ': 'o', 'ȏ': 'o', 'ȑ': 'r',
+	'ȓ': 'r', 'ȕ': 'u', 'ȗ': 'u', 'ș': 's', 'ț': 't', 'ȟ': 'h', 'ȧ': 'a',
+	'ȩ': 'e', 'ȫ': 'o', 'ȭ': 'o', 'ȯ': 'o', 'ȱ': 'o', 'ȳ': 'y', 'ḁ': 'a',
+	'ḃ': 'b', 'ḅ': 'b', 'ḇ': 'b', 'ḉ': 'c', 'ḋ': 'd', 'ḍ': 'd', 'ḏ': 'd',
+	'ḑ': 'd', 'ḓ': 'd', 'ḕ': 'e', 'ḗ': 'e', 'ḙ': 'e', 'ḛ': 'e', 'ḝ': 'e',
+	'ḟ': 'f', 'ḡ': 'g', 'ḣ': 'h', 'ḥ': 'h', 'ḧ': 'h', 'ḩ': 'h', 'ḫ': 'h',
+	'ḭ': 'i', 'ḯ': 'i', 'ḱ': 'k', 'ḳ': 'k', 'ḵ': 'k', 'ḷ': 'l', 'ḹ': 'l',
+	'ḻ': 'l', 'ḽ': 'l', 'ḿ': 'm', 'ṁ': 'm', 'ṃ': 'm', 'ṅ': 'n', 'ṇ': 'n',
+	'ṉ': 'n', 'ṋ': 'n', 'ṍ': 'o', 'ṏ': 'o', 'ṑ': 'o', 'ṓ': 'o', 'ṕ': 'p',
+	'ṗ': 'p', 'ṙ': 'r', 'ṛ': 'r', 'ṝ': 'r', 'ṟ': 'r', 'ṡ': 's', 'ṣ': 's',
+	'ṥ': 's', 'ṧ': 's', 'ṩ': 's', 'ṫ': 't', 'ṭ': 't', 'ṯ': 't', 'ṱ': 't',
+	'ṳ': 'u', 'ṵ': 'u', 'ṷ': 'u', 'ṹ': 'u', 'ṻ': 'u', 'ṽ': 'v', 'ṿ': 'v',
+	'ẁ': 'w', 'ẃ': 'w', 'ẅ': 'w', 'ẇ': 'w', 'ẉ': 'w', 'ẋ': 'x', 'ẍ': 'x',
+	'ẏ': 'y', 'ẑ': 'z', 'ẓ': 'z', 'ẕ': 'z', 'ẖ': 'h', 'ẗ': 't', 'ẘ': 'w',
+	'ẙ': 'y', 'ẛ': 'ſ', 'ạ': 'a', 'ả': 'a', 'ấ': 'a', 'ầ': 'a', 'ẩ': 'a',
+	'ẫ': 'a', 'ậ': 'a', 'ắ': 'a', 'ằ': 'a', 'ẳ': 'a', 'ẵ': 'a', 'ặ': 'a',
+	'ẹ': 'e', 'ẻ': 'e', 'ẽ': 'e', 'ế': 'e', 'ề': 'e', 'ể': 'e', 'ễ': 'e',
+	'ệ': 'e', 'ỉ': 'i', 'ị': 'i', 'ọ': 'o', 'ỏ': 'o', 'ố': 'o', 'ồ': 'o',
+	'ổ': 'o', 'ỗ': 'o', 'ộ': 'o', 'ớ': 'o', 'ờ': 'o', 'ở': 'o', 'ỡ': 'o',
+	'ợ': 'o', 'ụ': 'u', 'ủ': 'u', 'ứ': 'u', 'ừ': 'u', 'ử': 'u', 'ữ': 'u',
+	'ự': 'u', 'ỳ': 'y', 'ỵ': 'y', 'ỷ': 'y', 'ỹ': 'y',
+}
+
+// latinFold spells lowercase Latin letters that do not decompose, such as
+// ligatures and letters with a stroke, in ASCII.
+var latinFold = map[rune]string{
+	'ß': "ss", 'æ': "ae", 'ð': "d", 'ø': "o", 'þ': "th", 'đ': "d",
+	'ħ': "h", 'ı': "i", 'ĳ': "ij", 'ĸ': "k", 'ŀ': "l", 'ł': "l", 'ŋ': "n",
+	'œ': "oe", 'ŧ': "t", 'ſ': "s", 'ƀ': "b", 'ƈ': "c", 'ƒ': "f", 'ǆ': "dz",
+	'ǉ': "lj", 'ǌ': "nj", 'ǳ': "dz",
+}
+
+// foldLatin returns the ASCII spelling of the lowercase Latin letter r: the
+// base letter of its decomposition, spelled through latinFold if that is
+// not ASCII itself.
+func foldLatin(r rune) (string, bool) {
+	if base, ok := latinDecomp[r]; ok {
+		r = base
+		if r < utf8.RuneSelf {
+			return string(r), true
+		}
+	}
+	s, ok := latinFold[r]
+	return s, ok
+}
+
+// scriptTranslit maps lowercase Cyrillic and Greek letters to ASCII.
+var scriptTranslit = map[rune]string{
+	// Cyrillic (Russian, Ukrainian, Belarusian).
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "yo",
+	'ж': "zh", 'з': "z", 'и': "i", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u",
+	'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya", 'і': "i",
+	'ї': "yi", 'є': "ye", 'ґ': "g", 'ў': "u",
+	// Greek.
+	'α': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'ζ': "z", 'η': "i",
+	'θ': "th", 'ι': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x",
+	'ο': "o", 'π': "p", 'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y",
+	'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o", 'ά': "a", 'έ': "e", 'ή': "i",
+	'ί': "i", 'ό': "o", 'ύ': "y", 'ώ': "o", 'ϊ': "i", 'ϋ': "y", 'ΐ': "i",
+	'ΰ': "y",
+}
+
 func slugify(v string) string {
-	res := strings.TrimSpace(strings.ToLower(v))
-	res = reDisallowedSlugChars.ReplaceAllString(res, "-")
-	res = reMultiDash.ReplaceAllString(res, "-")
-	res = strings.Trim(res, "-")
-	return res
+	return Slugify(v, SlugOptions{})
+}
+
+// Slugify turns a title into a filename-safe slug: lowercase, with Latin
+// diacritics folded to ASCII ("Über Café" becomes "uber-cafe"), other
+// scripts handled according to opts.NonLatin, and every run of remaining
+// characters replaced by a single dash.
+func Slugify(v string, opts SlugOptions) string {
+	var b strings.Builder
+	dash := false
+	lastASCII := false
+
+	writeDash := func() {
+		if b.Len() > 0 {
+			dash = true
+		}
+	}
+	write := func(s string) {
+		if s == "" {
+			return
+		}
+		if dash {
+			b.WriteByte('-')
+			dash = false
+		}
+		b.WriteString(s)
+	}
+
+	for _, r := range strings.ToLower(v) {
+		switch {
+		case r < utf8.RuneSelf:
+			if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+				write(string(r))
+				lastASCII = true
+			} else {
+				writeDash()
+				lastASCII = false
+			}
+			continue
+		case unicode.Is(unicode.M, r):
+			// Combining marks from decomposed input ("e\u0301"): drop them
+			// after Latin letters, keep them with other scripts.
+			if !lastASCII && opts.NonLatin != SlugASCII && b.Len() > 0 && !dash {
+				b.WriteRune(r)
+			}
+			continue
+		}
+
+		lastASCII = false
+		if s, ok := foldLatin(r); ok {
+			write(s)
+			lastASCII = true
+			continue
+		}
+		if opts.NonLatin != SlugKeepLetters {
+			if s, ok := scriptTranslit[r]; ok {
+				write(s)
+				lastASCII = true
+				continue
+			}
+		}
+		if opts.NonLatin != SlugASCII && (unicode.IsLetter(r) || unicode.IsNumber(r)) {
+			write(string(r))
+			continue
+		}
+		writeDash()
+	}
+
+	return truncateSlug(b.String(), opts.MaxLength)
+}
+
+// truncateSlug shortens slug to at most max bytes, preferring to cut at a
+// dash so words stay whole, and never splitting a UTF-8 sequence.
+func truncateSlug(slug string, max int) string {
+	if max == 0 {
+		max = DefaultMaxSlugLength
+	}
+	if max < 0 || len(slug) <= max {
+		return slug
+	}
+
+	cut := max
+	for cut > 0 && !utf8.RuneStart(slug[cut]) {
+		cut--
+	}
+	if slug[cut] != '-' {
+		if i := strings.LastIndexByte(slug[:cut], '-'); i > max/2 {
+			cut = i
+		}
+	}
+	return strings.TrimRight(slug[:cut], "-")
 }
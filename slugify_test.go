@@ -1,6 +1,10 @@
 package gonotes
 
-import "testing"
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
 
 func TestSlugify(t *testing.T) {
 	tests := []struct {
@@ -18,7 +22,21 @@ func TestSlugify(t *testing.T) {
 		{"", ""},
 		{"a", "a"},
 		{"Hello   World", "hello-world"},
-		{"café résumé", "caf-r-sum"},
+		{"café résumé", "cafe-resume"},
+		{"Über Café Ångström", "uber-cafe-angstrom"},
+		{"Straße Æsir Œuvre", "strasse-aesir-oeuvre"},
+		{"Łódź Dvořák", "lodz-dvorak"},
+		{"cafe\u0301 decomposed", "cafe-decomposed"},
+		{"Tiếng Việt", "tieng-viet"},
+		{"Nguyễn Thị Minh Khai", "nguyen-thi-minh-khai"},
+		{"Đà Nẵng phở bò", "da-nang-pho-bo"},
+		{"Şişli İstanbul", "sisli-istanbul"},
+		{"Ærøskøbing ǿ", "aeroskobing-o"},
+		{"Ḑaḩḷa ẞ", "dahla-ss"},
+		{"Привет мир", "privet-mir"},
+		{"Ελληνικά", "ellinika"},
+		{"日本語のメモ", "日本語のメモ"},
+		{"Go と Rust", "go-と-rust"},
 	}
 
 	for _, tt := range tests {
@@ -30,3 +48,58 @@ func TestSlugify(t *testing.T) {
 		})
 	}
 }
+
+func TestSlugifyNonLatinModes(t *testing.T) {
+	tests := []struct {
+		input    string
+		nonLatin SlugNonLatin
+		want     string
+	}{
+		{"Привет мир", SlugKeepLetters, "привет-мир"},
+		{"Привет мир", SlugASCII, "privet-mir"},
+		{"Tiếng Việt", SlugASCII, "tieng-viet"},
+		{"Über Привет", SlugKeepLetters, "uber-привет"},
+		{"日本語 notes", SlugASCII, "notes"},
+		{"日本語 notes", SlugKeepLetters, "日本語-notes"},
+		{"हिन्दी", SlugKeepLetters, "हिन्दी"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got := Slugify(tt.input, SlugOptions{NonLatin: tt.nonLatin})
+			if got != tt.want {
+				t.Errorf("Slugify(%q, %v) = %q, want %q", tt.input, tt.nonLatin, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlugifyMaxLength(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		max   int
+		want  string
+	}{
+		{"cut at word boundary", "the quick brown fox jumps", 12, "the-quick"},
+		{"cut inside long word", "supercalifragilistic", 10, "supercalif"},
+		{"no trailing dash", "abcd efgh", 5, "abcd"},
+		{"unlimited", "the quick brown fox", -1, "the-quick-brown-fox"},
+		{"multibyte", "日本語日本語", 7, "日本"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Slugify(tt.input, SlugOptions{MaxLength: tt.max})
+			if got != tt.want {
+				t.Errorf("Slugify(%q, max %d) = %q, want %q", tt.input, tt.max, got, tt.want)
+			}
+		})
+	}
+
+	long := strings.Repeat("word ", 100)
+	got := slugify(long)
+	if len(got) > DefaultMaxSlugLength || !utf8.ValidString(got) || strings.HasSuffix(got, "-") {
+		t.Errorf("slugify(long) = %q (%d bytes), want at most %d bytes without trailing dash", got, len(got), DefaultMaxSlugLength)
+	}
+}
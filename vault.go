@@ -0,0 +1,46 @@
+package gonotes
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrNoVault is returned by FindVaultRoot when no vault encloses the
+// starting directory.
+var ErrNoVault = errors.New("no vault found")
+
+// FindVaultRoot returns the closest directory at or above dir that holds a
+// vault: one with a .gonotes.yaml file, a .gonotes state directory, or a
+// notes/by/id directory. It returns an error wrapping ErrNoVault when the
+// filesystem root is reached without finding one.
+func FindVaultRoot(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("find vault root: %w", err)
+	}
+
+	for cur := dir; ; {
+		if isVaultRoot(cur) {
+			return cur, nil
+		}
+		parent := filepath.Dir(cur)
+		if parent == cur {
+			return "", fmt.Errorf("find vault root: %s: %w", dir, ErrNoVault)
+		}
+		cur = parent
+	}
+}
+
+func isVaultRoot(dir string) bool {
+	if info, err := os.Stat(filepath.Join(dir, ConfigFilename)); err == nil && info.Mode().IsRegular() {
+		return true
+	}
+	for _, marker := range []string{stateDirName, filepath.Join("notes", "by", "id")} {
+		if info, err := os.Stat(filepath.Join(dir, marker)); err == nil && info.IsDir() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package gonotes
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFindVaultRoot(t *testing.T) {
+	tmp := t.TempDir()
+
+	byID := filepath.Join(tmp, "byid")
+	nested := filepath.Join(byID, "notes", "by", "tags", "go")
+	for _, dir := range []string{filepath.Join(byID, "notes", "by", "id"), nested} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	byConfig := filepath.Join(tmp, "byconfig")
+	writeTestNote(t, byConfig, ConfigFilename, "id_dir: notes/all\n")
+	configSub := filepath.Join(byConfig, "notes", "all")
+	if err := os.MkdirAll(configSub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	byState := filepath.Join(tmp, "bystate")
+	if err := os.MkdirAll(filepath.Join(byState, stateDirName), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		dir  string
+		want string
+	}{
+		{"root itself", byID, byID},
+		{"nested tag dir", nested, byID},
+		{"config file", configSub, byConfig},
+		{"state dir", byState, byState},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FindVaultRoot(tt.dir)
+			if err != nil {
+				t.Fatalf("FindVaultRoot() err = %q", err)
+			}
+			if got != tt.want {
+				t.Errorf("FindVaultRoot() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("no vault", func(t *testing.T) {
+		empty := filepath.Join(tmp, "empty")
+		if err := os.Mkdir(empty, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		// Unless a vault happens to enclose the temp dir, this fails.
+		got, err := FindVaultRoot(empty)
+		if err != nil && !errors.Is(err, ErrNoVault) {
+			t.Errorf("FindVaultRoot() err = %v, want ErrNoVault", err)
+		}
+		if err == nil && got == empty {
+			t.Errorf("FindVaultRoot() = %q, want ErrNoVault", got)
+		}
+	})
+}
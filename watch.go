@@ -0,0 +1,341 @@
+package gonotes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WatchOp is the kind of change a Watcher observed.
+type WatchOp string
+
+const (
+	WatchCreate WatchOp = "create"
+	WatchModify WatchOp = "modify"
+	WatchDelete WatchOp = "delete"
+	// WatchSync is a note brought in line on the first poll, which catches
+	// up with changes made while nothing was watching.
+	WatchSync WatchOp = "sync"
+)
+
+// WatchEvent reports a change to a note file and what the Watcher did in
+// response. Relink is nil for deletions and when Err is set.
+type WatchEvent struct {
+	Op          WatchOp
+	Filename    string
+	Relink      *RelinkResult
+	Removed     []string
+	BrokenLinks []BrokenLink
+	Err         error
+}
+
+func (e WatchEvent) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %s\n", e.Op, e.Filename)
+	if e.Err != nil {
+		fmt.Fprintf(&b, "  error: %v\n", e.Err)
+		return b.String()
+	}
+	for _, p := range e.Removed {
+		fmt.Fprintf(&b, "  unlink: %s\n", p)
+	}
+	if r := e.Relink; r != nil {
+		if r.OldName != r.NewName {
+			fmt.Fprintf(&b, "  rename: %s -> %s\n", r.OldName, r.NewName)
+		}
+		for _, p := range r.Removed {
+			fmt.Fprintf(&b, "  unlink: %s\n", p)
+		}
+		for _, l := range r.Added {
+			fmt.Fprintf(&b, "  link:  %s -> %s\n", l.Path, l.Target)
+		}
+	}
+	for _, bl := range e.BrokenLinks {
+		fmt.Fprintf(&b, "  broken link: %s -> %s\n", bl.SourceID, bl.TargetID)
+	}
+	return b.String()
+}
+
+type fileStamp struct {
+	modTime int64
+	size    int64
+}
+
+// Watcher polls the notes directory for changes and keeps each changed
+// note's filename and symlinks up to date.
+type Watcher struct {
+	baseDir string
+	cfg     *Config
+	state   map[string]fileStamp
+}
+
+func NewWatcher(baseDir string, cfg *Config) *Watcher {
+	return &Watcher{baseDir: baseDir, cfg: cfg.orDefault()}
+}
+
+// Poll compares the notes directory with the previous poll and handles every
+// created, modified and deleted note. The first call relinks every note
+// instead, giving notes without an ID one, and removes the symlinks of
+// notes deleted before watching started; it returns events only for notes
+// it changed or found broken links in. Per-note failures are reported in
+// the returned events; the error is only set when the directory itself
+// cannot be read.
+func (w *Watcher) Poll() ([]WatchEvent, error) {
+	idDir := w.cfg.idDir(w.baseDir)
+
+	current, err := w.snapshot(idDir)
+	if err != nil {
+		return nil, fmt.Errorf("watch: %w", err)
+	}
+
+	if w.state == nil {
+		events, err := w.sync(idDir, current)
+		if err != nil {
+			return nil, fmt.Errorf("watch: %w", err)
+		}
+		w.state = current
+		return events, nil
+	}
+
+	var events []WatchEvent
+	var names []string
+	for name := range current {
+		names = append(names, name)
+	}
+	for name := range w.state {
+		if _, ok := current[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	ids := make(map[string]struct{}, len(current))
+	for name := range current {
+		id, _ := IDFromFilename(name)
+		ids[id] = struct{}{}
+	}
+
+	targets := lazyLinkTargets(idDir, w.cfg)
+	for _, name := range names {
+		prev, existed := w.state[name]
+		cur, exists := current[name]
+
+		switch {
+		case existed && !exists:
+			ev := WatchEvent{Op: WatchDelete, Filename: name}
+			ev.Removed, ev.Err = removeNoteSymlinks(w.baseDir, nil, name)
+			events = append(events, ev)
+		case !existed:
+			events = append(events, w.relink(WatchCreate, name, ids, targets, current))
+		case prev != cur:
+			events = append(events, w.relink(WatchModify, name, ids, targets, current))
+		}
+	}
+
+	w.state = current
+	return events, nil
+}
+
+// sync renames the notes in current whose filename does not match their
+// title or lacks an ID, as a later poll would, and then brings every
+// symlink in line in one pass. Symlinks left behind by notes that no
+// longer exist are reported as deletions.
+func (w *Watcher) sync(idDir string, current map[string]fileStamp) ([]WatchEvent, error) {
+	files, errs, err := readNoteFiles(idDir, w.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []WatchEvent
+	for _, e := range errs {
+		events = append(events, WatchEvent{Op: WatchSync, Filename: e.Filename, Err: errors.New(e.Message)})
+	}
+
+	ids := make(map[string]struct{}, len(current))
+	for name := range current {
+		if id, ok := IDFromFilename(name); ok {
+			ids[id] = struct{}{}
+		}
+	}
+	targets := lazyLinkTargets(idDir, w.cfg)
+	notes := map[string]*Note{}
+	for i := range files {
+		nf := &files[i]
+		if id, ok := IDFromFilename(nf.Filename); ok && NoteFilename(id, nf.Slug) == nf.Filename {
+			notes[nf.Filename] = &nf.Note
+			continue
+		}
+		ev := w.relink(WatchSync, nf.Filename, ids, targets, current)
+		if ev.Err != nil {
+			// Still under its old name, where SyncSymlinks links it.
+			notes[nf.Filename] = &nf.Note
+		}
+		events = append(events, ev)
+	}
+
+	changes, err := SyncSymlinks(w.baseDir, w.cfg)
+	if err != nil {
+		return nil, err
+	}
+	byName := map[string]*WatchEvent{}
+	var order []string
+	event := func(name string) *WatchEvent {
+		ev, ok := byName[name]
+		if !ok {
+			ev = &WatchEvent{Op: WatchDelete, Filename: name}
+			if note, exists := notes[name]; exists {
+				ev.Op = WatchSync
+				ev.Relink = &RelinkResult{Note: note, OldName: name, NewName: name}
+			}
+			byName[name] = ev
+			order = append(order, name)
+		}
+		return ev
+	}
+	for _, l := range append(changes.Added, changes.Retargeted...) {
+		name := filepath.Base(l.Target)
+		if _, ok := notes[name]; !ok {
+			// A note created since it was read; the next poll reports it.
+			continue
+		}
+		ev := event(name)
+		ev.Relink.Added = append(ev.Relink.Added, l)
+	}
+	for _, p := range changes.Removed {
+		ev := event(filepath.Base(p))
+		if ev.Relink != nil {
+			ev.Relink.Removed = append(ev.Relink.Removed, p)
+		} else {
+			ev.Removed = append(ev.Removed, p)
+		}
+	}
+	sort.Strings(order)
+	for _, name := range order {
+		events = append(events, *byName[name])
+	}
+	return events, nil
+}
+
+// relink handles a created or modified note and updates current when the
+// note is renamed, so the rename is not seen as a change on the next poll.
+func (w *Watcher) relink(op WatchOp, name string, ids map[string]struct{}, targets func() linkTargets, current map[string]fileStamp) WatchEvent {
+	ev := WatchEvent{Op: op, Filename: name}
+
+	res, err := RelinkNote(w.baseDir, w.cfg, name)
+	if err != nil {
+		ev.Err = err
+		return ev
+	}
+	ev.Relink = res
+
+	if res.NewName != name {
+		current[res.NewName] = current[name]
+		delete(current, name)
+	}
+
+	filesDir := w.cfg.filesDir(w.baseDir)
+	for _, target := range res.Note.InternalLinks {
+		if linkIgnored(target, res.Note.IgnoreLinks) {
+			continue
+		}
+		link := ParseWikiLink(target)
+		targetID := res.Note.ID
+		if link.Target != "" {
+			targetID = linkTargetID(target)
+		}
+		resolved := linkResolves(target, ids, filesDir)
+		if !resolved {
+			if m := targets().names.lookup(link.Target); len(m) == 1 {
+				targetID, resolved = m[0], true
+			}
+		}
+		if resolved {
+			if link.Anchor == "" && link.Block == "" {
+				continue
+			}
+			// Headings and blocks are only checked in notes.
+			if a, isNote := targets().anchors[targetID]; !isNote || a.has(link) {
+				continue
+			}
+		}
+		ev.BrokenLinks = append(ev.BrokenLinks, BrokenLink{
+			SourceID: res.Note.ID,
+			TargetID: brokenLinkTarget(target),
+		})
+	}
+
+	return ev
+}
+
+// linkTargets holds what checking a link needs beyond the note IDs: the
+// titles and aliases of all notes, and their headings and block IDs.
+type linkTargets struct {
+	names   noteNames
+	anchors map[string]noteAnchors
+}
+
+// lazyLinkTargets returns a function that reads the notes in idDir when it
+// is first called, so a poll only reads every note when a link needs
+// resolving by name or has an anchor. Unreadable notes are skipped.
+func lazyLinkTargets(idDir string, cfg *Config) func() linkTargets {
+	var targets linkTargets
+	return func() linkTargets {
+		if targets.names == nil {
+			targets = linkTargets{names: noteNames{}, anchors: map[string]noteAnchors{}}
+			files, _, _ := readNoteFiles(idDir, cfg)
+			for i := range files {
+				if id := files[i].ID; id != "" {
+					targets.names.add(id, &files[i].Note)
+					targets.anchors[id] = parseAnchors(files[i].Body)
+				}
+			}
+		}
+		return targets
+	}
+}
+
+func (w *Watcher) snapshot(idDir string) (map[string]fileStamp, error) {
+	entries, err := noteEntries(idDir)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]fileStamp, len(entries))
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			// Removed between listing and stat; the next poll sees it gone.
+			continue
+		}
+		out[e.Name()] = fileStamp{
+			modTime: info.ModTime().UnixNano(),
+			size:    info.Size(),
+		}
+	}
+	return out, nil
+}
+
+// Run polls every interval until ctx is done, calling fn for each event.
+func (w *Watcher) Run(ctx context.Context, interval time.Duration, fn func(WatchEvent)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		events, err := w.Poll()
+		if err != nil {
+			return err
+		}
+		for _, ev := range events {
+			fn(ev)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package gonotes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestWatcherPoll(t *testing.T) {
+	baseDir := t.TempDir()
+	idDir := filepath.Join(baseDir, "notes", "by", "id")
+
+	writeTestNote(t, idDir, "20260328-1-existing.md", "---\ntitle: Existing\ntags: old\n---\n\n## Setup\n\nText. ^intro")
+
+	w := NewWatcher(baseDir, nil)
+	events, err := w.Poll()
+	if err != nil {
+		t.Fatalf("Poll() err = %q", err)
+	}
+	if len(events) != 1 || events[0].Op != WatchSync || events[0].Relink == nil || len(events[0].Relink.Added) != 1 {
+		t.Fatalf("first Poll() = %v, want 1 sync event adding a symlink", events)
+	}
+	if events, err = w.Poll(); err != nil || len(events) != 0 {
+		t.Fatalf("second Poll() = %v, %v, want no events", events, err)
+	}
+
+	writeTestNote(t, idDir, "20260328-2-new.md", `---
+title: New
+tags: go
+---
+
+See [[20260328-1]], [[existing]] and [[20260328-99]].
+Also [[20260328-1#Setup]], [[Existing#^intro]] and [[#Here]], but not
+[[20260328-1#Teardown]], [[existing#^outro]] or [[#Missing]].
+
+## Here`)
+
+	events, err = w.Poll()
+	if err != nil {
+		t.Fatalf("Poll() err = %q", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Poll() after create = %v, want 1 event", events)
+	}
+	ev := events[0]
+	if ev.Op != WatchCreate || ev.Filename != "20260328-2-new.md" || ev.Err != nil {
+		t.Errorf("create event = %+v", ev)
+	}
+	wantBroken := []BrokenLink{
+		{SourceID: "20260328-2", TargetID: "20260328-99"},
+		{SourceID: "20260328-2", TargetID: "20260328-1#Teardown"},
+		{SourceID: "20260328-2", TargetID: "existing#^outro"},
+		{SourceID: "20260328-2", TargetID: "#Missing"},
+	}
+	if diff := cmp.Diff(wantBroken, ev.BrokenLinks); diff != "" {
+		t.Errorf("broken links diff (-want, +got):\n%s", diff)
+	}
+
+	writeTestNote(t, idDir, "20260328-2-new.md", "---\ntitle: Renamed\ntags: go\n---\n")
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(idDir, "20260328-2-new.md"), future, future); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err = w.Poll()
+	if err != nil {
+		t.Fatalf("Poll() err = %q", err)
+	}
+	if len(events) != 1 || events[0].Op != WatchModify || events[0].Relink == nil {
+		t.Fatalf("Poll() after modify = %v, want 1 modify event", events)
+	}
+	if got := events[0].Relink.NewName; got != "20260328-2-renamed.md" {
+		t.Errorf("NewName = %q, want %q", got, "20260328-2-renamed.md")
+	}
+
+	events, err = w.Poll()
+	if err != nil {
+		t.Fatalf("Poll() err = %q", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("Poll() after own rename = %v, want no events", events)
+	}
+
+	if err := os.Remove(filepath.Join(idDir, "20260328-2-renamed.md")); err != nil {
+		t.Fatal(err)
+	}
+	events, err = w.Poll()
+	if err != nil {
+		t.Fatalf("Poll() err = %q", err)
+	}
+	if len(events) != 1 || events[0].Op != WatchDelete {
+		t.Fatalf("Poll() after delete = %v, want 1 delete event", events)
+	}
+	if diff := cmp.Diff([]string{"notes/by/tags/go/20260328-2-renamed.md"}, events[0].Removed); diff != "" {
+		t.Errorf("removed diff (-want, +got):\n%s", diff)
+	}
+
+	got, err := snapshotNoteSymlinks(baseDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"notes/by/tags/old/20260328-1-existing.md": "../../id/20260328-1-existing.md"}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("symlinks diff (-want, +got):\n%s", diff)
+	}
+}
+
+func TestWatcherFirstPollSyncs(t *testing.T) {
+	baseDir := t.TempDir()
+	idDir := filepath.Join(baseDir, "notes", "by", "id")
+
+	// Changed while nothing was watching: a note added without an ID, a
+	// note whose title changed and a note deleted after its symlinks were
+	// made.
+	writeTestNote(t, idDir, "20260328-1-gone.md", "---\ntitle: Gone\ntags: go\n---\n")
+	if err := RebuildSymlinks(baseDir, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(idDir, "20260328-1-gone.md")); err != nil {
+		t.Fatal(err)
+	}
+	writeTestNote(t, idDir, "20260328-2-old-title.md", "---\ntitle: New Title\n---\n")
+	writeTestNote(t, idDir, "scratch.md", "---\ntitle: Scratch\ndate: 2026-03-28 10:00:00\ntags: go\n---\n")
+
+	w := NewWatcher(baseDir, nil)
+	events, err := w.Poll()
+	if err != nil {
+		t.Fatalf("Poll() err = %q", err)
+	}
+	var got []string
+	for _, ev := range events {
+		if ev.Err != nil {
+			t.Errorf("event %s: %v", ev.Filename, ev.Err)
+		}
+		line := string(ev.Op) + " " + ev.Filename
+		if ev.Relink != nil && ev.Relink.NewName != ev.Filename {
+			line += " -> " + ev.Relink.NewName
+		}
+		got = append(got, line)
+	}
+	want := []string{
+		"sync 20260328-2-old-title.md -> 20260328-2-new-title.md",
+		"sync scratch.md -> 20260328-3-scratch.md",
+		"delete 20260328-1-gone.md",
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("first Poll() events diff (-want, +got):\n%s", diff)
+	}
+
+	links, err := snapshotNoteSymlinks(baseDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantLinks := map[string]string{
+		"notes/by/date/2026-03-28/20260328-3-scratch.md": "../../id/20260328-3-scratch.md",
+		"notes/by/tags/go/20260328-3-scratch.md":         "../../id/20260328-3-scratch.md",
+	}
+	if diff := cmp.Diff(wantLinks, links); diff != "" {
+		t.Errorf("symlinks diff (-want, +got):\n%s", diff)
+	}
+
+	if events, err := w.Poll(); err != nil || len(events) != 0 {
+		t.Errorf("second Poll() = %v, %v, want no events", events, err)
+	}
+}
+
+func TestWatcherFirstPollRenameCollision(t *testing.T) {
+	baseDir := t.TempDir()
+	idDir := filepath.Join(baseDir, "notes", "by", "id")
+
+	// Both notes want the name 20260328-1-new.md, so relinking the second
+	// one fails; its symlinks are still made under its current name.
+	writeTestNote(t, idDir, "20260328-1-new.md", "---\ntitle: New\ndate: 2026-03-28 10:00:00\n---\n")
+	writeTestNote(t, idDir, "20260328-1-old.md", "---\ntitle: New\ndate: 2026-03-28 10:00:00\n---\n")
+
+	events, err := NewWatcher(baseDir, nil).Poll()
+	if err != nil {
+		t.Fatalf("Poll() err = %q", err)
+	}
+	var failed bool
+	for _, ev := range events {
+		if ev.Filename == "20260328-1-old.md" && ev.Err != nil {
+			failed = true
+		}
+	}
+	if !failed {
+		t.Errorf("first Poll() = %v, want an error for 20260328-1-old.md", events)
+	}
+	if _, err := os.Lstat(filepath.Join(baseDir, "notes", "by", "date", "2026-03-28", "20260328-1-old.md")); err != nil {
+		t.Errorf("date symlink of the unrenamed note missing: %v", err)
+	}
+}